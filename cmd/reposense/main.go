@@ -4,14 +4,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"reposense/internal/config"
 	"reposense/pkg/analyzer"
 	"reposense/pkg/cache"
 	"reposense/pkg/changelog"
+	"reposense/pkg/filter"
 	"reposense/pkg/llm"
 	"reposense/pkg/reporter"
 	"reposense/pkg/scanner"
@@ -26,6 +31,7 @@ var (
 	disableLLM          bool
 	gitPullStrategy     string
 	gitAllowInteractive bool
+	noHooks             bool
 	enableCache         bool
 	forceRefresh        bool
 	// Analyzer flags
@@ -60,6 +66,45 @@ func main() {
 		Run:   runUpdate,
 	}
 	
+	var updateUndoCmd = &cobra.Command{
+		Use:   "undo [run-id]",
+		Short: "撤销一次批量更新",
+		Long:  "把指定更新中被拉取的仓库安全地重置到拉取前记录的分支和HEAD；不指定编号时列出最近的更新记录",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runUpdateUndo,
+	}
+	updateUndoCmd.Flags().Int("limit", 10, "列出的更新记录条数")
+	updateCmd.AddCommand(updateUndoCmd)
+	
+	// Fetch command
+	var fetchCmd = &cobra.Command{
+		Use:   "fetch [directory]",
+		Short: "批量获取远程更新",
+		Long:  "并发执行 git fetch --all --prune --tags，只更新远程跟踪分支和标签，不修改任何工作区",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runFetch,
+	}
+	
+	// Exec command
+	var execCmd = &cobra.Command{
+		Use:   "exec [directory] -- <command> [args...]",
+		Short: "在每个仓库中执行命令",
+		Long:  "扫描指定目录下的所有Git仓库，并发地在每个仓库目录中执行 -- 之后的命令，最后汇总各仓库的退出码",
+		Args: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 0 || dash == len(args) {
+				return fmt.Errorf("需要在 -- 之后指定要执行的命令")
+			}
+			if dash > 1 {
+				return fmt.Errorf("-- 之前最多只能指定一个目录")
+			}
+			return nil
+		},
+		Run: runExec,
+	}
+	execCmd.Flags().Bool("fail-fast", false, "有仓库执行失败后不再启动新的命令")
+	execCmd.Flags().Bool("stream", false, "实时输出，每行带仓库名前缀 (默认在结束后按仓库分组输出)")
+	
 	// Scan command
 	var scanCmd = &cobra.Command{
 		Use:   "scan [directory]",
@@ -151,6 +196,157 @@ func main() {
 		Run:   runCachePath,
 	}
 	
+	// Manifest command
+	var manifestCmd = &cobra.Command{
+		Use:   "manifest",
+		Short: "工作区清单管理",
+		Long:  "管理 reposense.yaml 工作区清单，所有接受 [directory] 的命令都可以通过 --manifest 改为读取清单",
+	}
+	
+	var manifestGenerateCmd = &cobra.Command{
+		Use:   "generate [directory]",
+		Short: "根据目录扫描结果生成清单",
+		Long:  "扫描指定目录下的所有Git仓库，生成包含路径和远程地址的 reposense.yaml",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runManifestGenerate,
+	}
+	
+	manifestGenerateCmd.Flags().StringP("output", "o", "", "清单输出路径 (默认: <directory>/reposense.yaml，- 表示标准输出)")
+	manifestGenerateCmd.Flags().Bool("force", false, "覆盖已存在的清单文件")
+	manifestCmd.AddCommand(manifestGenerateCmd)
+	
+	// Audit command
+	var auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "审计仓库",
+		Long:  "检查仓库中只存在于本地的工作，在删除或整理克隆前使用",
+	}
+	
+	var auditUnpushedCmd = &cobra.Command{
+		Use:   "unpushed [directory]",
+		Short: "列出只存在于本地的工作",
+		Long:  "列出每个仓库中未推送的提交、贮藏、未跟踪文件、仅本地的分支和标签，并给出能否安全删除的结论",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runAuditUnpushed,
+	}
+	
+	auditUnpushedCmd.Flags().Bool("fetch", false, "审计前先并发获取远程更新")
+	auditCmd.AddCommand(auditUnpushedCmd)
+	
+	// Tag command
+	var tagCmd = &cobra.Command{
+		Use:   "tag",
+		Short: "仓库标签管理",
+		Long:  "为仓库添加或删除标签，标签可在 --include/--exclude 中通过 tag:<名称> 过滤",
+	}
+	
+	var tagAddCmd = &cobra.Command{
+		Use:   "add <repository> <tag>...",
+		Short: "添加标签",
+		Args:  cobra.MinimumNArgs(2),
+		Run:   runTagAdd,
+	}
+	
+	var tagRemoveCmd = &cobra.Command{
+		Use:   "remove <repository> <tag>...",
+		Short: "删除标签",
+		Args:  cobra.MinimumNArgs(2),
+		Run:   runTagRemove,
+	}
+	
+	var tagListCmd = &cobra.Command{
+		Use:   "list [repository]",
+		Short: "列出标签",
+		Long:  "列出指定仓库的标签，如果不指定仓库则列出所有带标签的仓库",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runTagList,
+	}
+	
+	// Branch command
+	var branchCmd = &cobra.Command{
+		Use:   "branch",
+		Short: "跨仓库分支操作",
+		Long:  "在扫描到的所有仓库中同时创建、检出、推送或删除同名分支；先检查所有仓库，再并发执行，部分失败时可回滚",
+	}
+	branchCmd.PersistentFlags().String("on-failure", "rollback", "部分仓库失败时的处理方式 (rollback: 预检未通过则不修改任何仓库，执行失败则撤销已成功的仓库; continue: 保留成功的仓库)")
+	branchCmd.PersistentFlags().String("remote", "origin", "推送和删除远程分支时使用的远程")
+	
+	var branchCreateCmd = &cobra.Command{
+		Use:   "create <branch> [directory]",
+		Short: "在所有仓库中创建分支 (不切换)",
+		Args:  cobra.RangeArgs(1, 2),
+		Run:   runBranch,
+	}
+	branchCreateCmd.Flags().String("from", "", "新分支的起点 (默认为各仓库的 HEAD)")
+	
+	var branchCheckoutCmd = &cobra.Command{
+		Use:   "checkout <branch> [directory]",
+		Short: "在所有仓库中检出分支",
+		Long:  "检出本地分支；本地没有而远程跟踪分支存在时，创建跟踪该远程分支的本地分支。工作区有未提交变更的仓库无法通过预检",
+		Args:  cobra.RangeArgs(1, 2),
+		Run:   runBranch,
+	}
+	
+	var branchPushCmd = &cobra.Command{
+		Use:   "push <branch> [directory]",
+		Short: "在所有仓库中推送分支并设置上游",
+		Args:  cobra.RangeArgs(1, 2),
+		Run:   runBranch,
+	}
+	
+	var branchDeleteCmd = &cobra.Command{
+		Use:   "delete <branch> [directory]",
+		Short: "在所有仓库中删除分支",
+		Long:  "删除本地分支；检出中的分支、有未推送提交或未合并的分支无法通过预检，除非使用 --force",
+		Args:  cobra.RangeArgs(1, 2),
+		Run:   runBranch,
+	}
+	branchDeleteCmd.Flags().Bool("force", false, "删除未合并或有未推送提交的分支")
+	branchDeleteCmd.Flags().Bool("delete-remote", false, "同时删除远程分支")
+	branchCmd.AddCommand(branchCreateCmd, branchCheckoutCmd, branchPushCmd, branchDeleteCmd)
+	
+	// Snapshot command
+	var snapshotCmd = &cobra.Command{
+		Use:   "snapshot",
+		Short: "工作区快照",
+		Long:  "记录工作区中每个仓库所在的分支和提交，之后可以恢复到这一组合，用于跨服务复现问题或回到已知可用的状态",
+	}
+	
+	var snapshotSaveCmd = &cobra.Command{
+		Use:   "save <name> [directory]",
+		Short: "保存快照",
+		Long:  "扫描目录并记录每个仓库的分支和提交，保存到缓存数据库；使用 --file 时导出为 JSON 文件",
+		Args:  cobra.RangeArgs(1, 2),
+		Run:   runSnapshotSave,
+	}
+	snapshotSaveCmd.Flags().String("file", "", "导出到 JSON 文件而不是缓存数据库")
+	snapshotSaveCmd.Flags().Bool("force", false, "覆盖同名快照")
+	snapshotSaveCmd.Flags().Bool("allow-incomplete", false, "有仓库无法记录时仍然保存快照")
+	
+	var snapshotRestoreCmd = &cobra.Command{
+		Use:   "restore <name> [directory]",
+		Short: "恢复快照",
+		Long:  "在每个仓库中检出快照记录的提交，分支仍指向该提交时检出分支，否则以分离 HEAD 检出；拒绝修改有未提交变更的仓库，并报告与快照的差异。不指定目录时使用保存快照时的目录",
+		Args:  cobra.RangeArgs(0, 2),
+		Run:   runSnapshotRestore,
+	}
+	snapshotRestoreCmd.Flags().String("file", "", "从 JSON 文件读取快照 (此时不需要指定名称)")
+	
+	var snapshotListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "列出保存的快照",
+		Args:  cobra.NoArgs,
+		Run:   runSnapshotList,
+	}
+	
+	var snapshotDeleteCmd = &cobra.Command{
+		Use:   "delete <name>",
+		Short: "删除快照",
+		Args:  cobra.ExactArgs(1),
+		Run:   runSnapshotDelete,
+	}
+	snapshotCmd.AddCommand(snapshotSaveCmd, snapshotRestoreCmd, snapshotListCmd, snapshotDeleteCmd)
+	
 	// Analyze command
 	var analyzeCmd = &cobra.Command{
 		Use:   "analyze [directory]",
@@ -221,8 +417,14 @@ func main() {
 	rootCmd.PersistentFlags().BoolVarP(&cfg.Verbose, "verbose", "v", cfg.Verbose, "显示详细输出")
 	rootCmd.PersistentFlags().BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "模拟运行，不执行实际操作")
 	rootCmd.PersistentFlags().StringVarP((*string)(&cfg.OutputFormat), "format", "f", string(cfg.OutputFormat), "输出格式 (text|table|json)")
-	rootCmd.PersistentFlags().StringSliceVarP(&cfg.IncludePatterns, "include", "i", cfg.IncludePatterns, "包含模式 (可多次指定)")
-	rootCmd.PersistentFlags().StringSliceVarP(&cfg.ExcludePatterns, "exclude", "e", cfg.ExcludePatterns, "排除模式 (可多次指定)")
+	rootCmd.PersistentFlags().StringSliceVarP(&cfg.IncludePatterns, "include", "i", cfg.IncludePatterns, "包含过滤表达式，支持通配符、re:、remote:、branch:、lang:、tag: 及 AND/OR/NOT (可多次指定)")
+	rootCmd.PersistentFlags().StringSliceVarP(&cfg.ExcludePatterns, "exclude", "e", cfg.ExcludePatterns, "排除过滤表达式，语法同 --include (可多次指定)")
+	rootCmd.PersistentFlags().IntVar(&cfg.MaxDepth, "max-depth", cfg.MaxDepth, "最大扫描深度 (0表示不限制)")
+	rootCmd.PersistentFlags().BoolVar(&cfg.FollowSymlinks, "follow-symlinks", cfg.FollowSymlinks, "扫描时跟随符号链接目录")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.SkipDirs, "skip-dir", cfg.SkipDirs, "扫描时额外跳过的目录名 (可多次指定)")
+	rootCmd.PersistentFlags().BoolVar(&cfg.Submodules, "submodules", cfg.Submodules, "扫描时同时列出仓库中的子模块")
+	rootCmd.PersistentFlags().StringVar(&cfg.Manifest, "manifest", cfg.Manifest, "从清单文件 (reposense.yaml) 读取仓库列表，代替目录扫描")
+	rootCmd.PersistentFlags().StringSliceVar(&cfg.Groups, "group", cfg.Groups, "只使用清单中属于指定分组的仓库 (可多次指定)")
 	rootCmd.PersistentFlags().BoolVar(&cfg.SaveReport, "save-report", cfg.SaveReport, "保存报告到文件")
 	rootCmd.PersistentFlags().StringVar(&cfg.ReportFile, "report-file", cfg.ReportFile, "报告文件路径")
 	
@@ -245,7 +447,28 @@ func main() {
 	rootCmd.PersistentFlags().BoolVar(&forceRefresh, "force-refresh", false, "强制刷新缓存，重新生成所有描述")
 	
 	// List command specific flags
+	scanCmd.Flags().Bool("diff", false, "显示与上次扫描相比新增、删除和移动的仓库")
+	
+	updateCmd.Flags().StringVar(&cfg.DirtyPolicy, "dirty-policy", cfg.DirtyPolicy, "工作区有未提交变更时的处理方式 (skip|stash|fail)")
+	updateCmd.Flags().BoolVar(&cfg.AllBranches, "all-branches", cfg.AllBranches, "同时快进未检出且严格落后于上游的本地分支 (不切换分支，分叉的分支只报告)")
+	updateCmd.Flags().BoolVar(&noHooks, "no-hooks", false, "不执行配置中的更新后钩子")
+	for _, cmd := range []*cobra.Command{updateCmd, fetchCmd} {
+		cmd.Flags().IntVar(&cfg.Retries, "retries", cfg.Retries, "网络错误或超时后的重试次数 (0表示不重试)")
+		cmd.Flags().DurationVar(&cfg.RetryBackoff, "retry-backoff", cfg.RetryBackoff, "首次重试前的等待时间，之后逐次翻倍并加入随机抖动")
+	}
+	
+	statusCmd.Flags().Bool("fetch", false, "收集状态前先并发获取远程更新 (默认只使用本地已有的远程跟踪分支)")
+	statusCmd.Flags().Bool("branches", false, "显示每个本地分支的上游、领先/落后、最后提交时间及是否已合并到默认分支")
+	statusCmd.Flags().Bool("dirty", false, "只显示工作区有未提交变更的仓库")
+	statusCmd.Flags().Bool("ahead", false, "只显示领先上游 (有未推送提交) 的仓库")
+	statusCmd.Flags().Bool("behind", false, "只显示落后上游的仓库")
+	statusCmd.Flags().Bool("diverged", false, "只显示与上游分叉 (同时领先和落后) 的仓库")
+	statusCmd.Flags().Bool("no-upstream", false, "只显示当前分支没有上游的仓库")
+	statusCmd.Flags().Bool("detached", false, "只显示HEAD处于分离状态的仓库")
+	statusCmd.Flags().String("stale", "", "只显示最后提交早于指定时长的仓库 (如 90d、2w、36h)")
+	
 	listCmd.Flags().BoolVar(&cfg.SortByTime, "sort-by-time", cfg.SortByTime, "按更新时间排序")
+	listCmd.Flags().StringVar(&cfg.SortKey, "sort-key", cfg.SortKey, "按时间排序时使用的时间 (committer|author|branches)")
 	listCmd.Flags().BoolVarP(&cfg.Reverse, "reverse", "r", cfg.Reverse, "倒序显示")
 	
 	// Analyze command specific flags
@@ -270,6 +493,9 @@ func main() {
 	// Add sub-commands to cache
 	cacheCmd.AddCommand(cacheStatsCmd, cacheClearCmd, cacheRefreshCmd, cachePathCmd)
 	
+	// Add sub-commands to tag
+	tagCmd.AddCommand(tagAddCmd, tagRemoveCmd, tagListCmd)
+	
 	// Add sub-commands to metadata
 	metadataCmd.AddCommand(metadataShowCmd, metadataStatsCmd, metadataSearchCmd, metadataExportCmd)
 	
@@ -289,16 +515,563 @@ func main() {
 	changelogCmd.Flags().String("mode", "fast", "分析模式 (fast|deep|full)")
 	changelogCmd.Flags().String("language", "zh", "输出语言 (zh|en|ja)")
 
-	// Add commands
-	rootCmd.AddCommand(updateCmd, scanCmd, statusCmd, listCmd, analyzeCmd, metadataCmd, configCmd, cacheCmd, changelogCmd)
+	// Add commands
+	rootCmd.AddCommand(updateCmd, fetchCmd, execCmd, branchCmd, snapshotCmd, scanCmd, statusCmd, listCmd, analyzeCmd, metadataCmd, tagCmd, manifestCmd, auditCmd, configCmd, cacheCmd, changelogCmd)
+	
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runUpdate(cmd *cobra.Command, args []string) {
+	directory := getCurrentDirectory(args)
+	
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	
+	dirtyPolicy, err := updater.ParseDirtyPolicy(cfg.DirtyPolicy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	
+	hooks := cfg.Hooks
+	if noHooks || cfg.DryRun {
+		hooks = nil
+	}
+	if err := updater.ValidateHooks(hooks); err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	
+	// 扫描仓库并配置更新器
+	reporterInstance := reporter.NewReporter(cfg.OutputFormat, cfg.Verbose)
+	repositories, updaterInstance := scanAndBuildUpdater(directory, updater.UpdaterConfig{
+		GitPullStrategy: gitPullStrategy,
+		DirtyPolicy:     dirtyPolicy,
+		AllBranches:     cfg.AllBranches,
+		HostLimits:      cfg.HostLimits,
+		Hooks:           hooks,
+	})
+	
+	if len(repositories) == 0 {
+		fmt.Println("未发现任何Git仓库")
+		return
+	}
+	
+	// 记录拉取前的状态，供 update undo 使用
+	journal, closeJournal := startUpdateJournal(directory)
+	defer closeJournal()
+	if journal != nil {
+		updaterInstance.SetJournal(journal)
+	}
+	
+	// 初始化进度条
+	description := "更新仓库"
+	if cfg.DryRun {
+		description = "模拟更新"
+	}
+	reporterInstance.InitProgressBar(len(repositories), description)
+	
+	// 执行更新
+	fmt.Fprintf(infoOutput(), "🚀 开始更新，使用 %d 个工作协程\n", cfg.WorkerCount)
+	
+	results, err := updaterInstance.UpdateRepositories(repositories, func(result updater.UpdateResult) {
+		reporterInstance.UpdateProgress()
+	})
+	
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "更新过程出错: %v\n", err)
+		os.Exit(1)
+	}
+	
+	reporterInstance.FinishProgress()
+	
+	// 显示结果
+	reporterInstance.ReportUpdateResults(results)
+	if journal != nil {
+		if err := journal.Finish(); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+		}
+		if cfg.OutputFormat != reporter.FormatJSON {
+			fmt.Printf("📝 已记录为更新 #%d，可使用 reposense update undo %d 撤销\n", journal.RunID(), journal.RunID())
+		}
+	}
+	
+	// 保存报告
+	if cfg.SaveReport {
+		filename := cfg.ReportFile
+		if filename == "" {
+			filename = fmt.Sprintf("reposense-update-%s.json", time.Now().Format("20060102-150405"))
+		}
+		
+		if err := reporterInstance.SaveReport(filename, results); err != nil {
+			fmt.Fprintf(os.Stderr, "保存报告失败: %v\n", err)
+		} else {
+			fmt.Printf("📄 报告已保存到: %s\n", filename)
+		}
+	}
+}
+
+// startUpdateJournal opens the cache and starts recording an update run.
+// It returns nil in dry-run mode or when the cache is unavailable.
+func startUpdateJournal(directory string) (*cache.UpdateJournal, func()) {
+	if cfg.DryRun {
+		return nil, func() {}
+	}
+	
+	cacheManager, err := cache.NewManager(false, "", "", "", "", "", 0, true, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  无法打开缓存，本次更新不会记录，无法撤销: %v\n", err)
+		return nil, func() {}
+	}
+	
+	source := cfg.Manifest
+	if source == "" {
+		source = absoluteRepositoryPath(directory)
+	}
+	journal, err := cacheManager.GetCache().StartUpdateRun(source, gitPullStrategy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  本次更新不会记录，无法撤销: %v\n", err)
+		cacheManager.Close()
+		return nil, func() {}
+	}
+	return journal, func() { cacheManager.Close() }
+}
+
+func runUpdateUndo(cmd *cobra.Command, args []string) {
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	
+	cacheManager, err := cache.NewManager(false, "", "", "", "", "", 0, true, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "初始化缓存失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer cacheManager.Close()
+	
+	store := cacheManager.GetCache()
+	reporterInstance := reporter.NewReporter(cfg.OutputFormat, cfg.Verbose)
+	
+	if len(args) == 0 {
+		limit, _ := cmd.Flags().GetInt("limit")
+		runs, err := store.RecentUpdateRuns(limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		reporterInstance.ReportUpdateRuns(runs)
+		return
+	}
+	
+	runID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "无效的更新编号: %s\n", args[0])
+		os.Exit(1)
+	}
+	run, err := store.UpdateRun(runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	
+	updaterInstance := updater.NewUpdater(updater.UpdaterConfig{
+		Timeout:           cfg.Timeout,
+		DryRun:            cfg.DryRun,
+		GitNonInteractive: !gitAllowInteractive,
+	})
+	if cfg.Manifest != "" {
+		manifest, err := scanner.LoadManifest(cfg.Manifest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "加载清单失败: %v\n", err)
+			os.Exit(1)
+		}
+		updaterInstance.SetRepositoryOptions(manifest.Options())
+	}
+	stopOnInterrupt(updaterInstance.Stop)
+	
+	results := updaterInstance.Undo(run)
+	refused := false
+	for _, result := range results {
+		if result.Status == updater.StatusFailed {
+			refused = true
+		}
+		if result.Restored && !cfg.DryRun {
+			if err := store.MarkJournalUndone(result.Entry.ID); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			}
+		}
+	}
+	reporterInstance.ReportUndoResults(run, results)
+	
+	if refused {
+		os.Exit(1)
+	}
+}
+
+func runFetch(cmd *cobra.Command, args []string) {
+	directory := getCurrentDirectory(args)
+	
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	
+	reporterInstance := reporter.NewReporter(cfg.OutputFormat, cfg.Verbose)
+	repositories, updaterInstance := scanAndBuildUpdater(directory, updater.UpdaterConfig{HostLimits: cfg.HostLimits})
+	
+	if len(repositories) == 0 {
+		fmt.Println("未发现任何Git仓库")
+		return
+	}
+	
+	description := "获取更新"
+	if cfg.DryRun {
+		description = "模拟获取"
+	}
+	reporterInstance.InitProgressBar(len(repositories), description)
+	
+	fmt.Fprintf(infoOutput(), "🚀 开始获取远程更新，使用 %d 个工作协程\n", cfg.WorkerCount)
+	
+	results, err := updaterInstance.FetchRepositories(repositories, func(result updater.UpdateResult) {
+		reporterInstance.UpdateProgress()
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取过程出错: %v\n", err)
+		os.Exit(1)
+	}
+	
+	reporterInstance.FinishProgress()
+	reporterInstance.ReportUpdateResults(results)
+	
+	if cfg.SaveReport {
+		filename := cfg.ReportFile
+		if filename == "" {
+			filename = fmt.Sprintf("reposense-fetch-%s.json", time.Now().Format("20060102-150405"))
+		}
+		
+		if err := reporterInstance.SaveReport(filename, results); err != nil {
+			fmt.Fprintf(os.Stderr, "保存报告失败: %v\n", err)
+		} else {
+			fmt.Printf("📄 报告已保存到: %s\n", filename)
+		}
+	}
+}
+
+func runExec(cmd *cobra.Command, args []string) {
+	dash := cmd.ArgsLenAtDash()
+	command := args[dash:]
+	directory := getCurrentDirectory(args[:dash])
+	failFast, _ := cmd.Flags().GetBool("fail-fast")
+	stream, _ := cmd.Flags().GetBool("stream")
+	
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	
+	reporterInstance := reporter.NewReporter(cfg.OutputFormat, cfg.Verbose)
+	repositories, updaterInstance := scanAndBuildUpdater(directory, updater.UpdaterConfig{})
+	
+	if len(repositories) == 0 {
+		fmt.Println("未发现任何Git仓库")
+		return
+	}
+	
+	options := updater.ExecOptions{FailFast: failFast}
+	if stream {
+		// 实时输出与进度条会互相覆盖；JSON 模式下标准输出只留给报告
+		options.Stream = infoOutput()
+	} else {
+		reporterInstance.InitProgressBar(len(repositories), "执行命令")
+	}
+	
+	fmt.Fprintf(infoOutput(), "🚀 开始执行 %s，使用 %d 个工作协程\n", strings.Join(command, " "), cfg.WorkerCount)
+	
+	results, err := updaterInstance.ExecRepositories(repositories, command, options, func(result updater.UpdateResult) {
+		if !stream {
+			reporterInstance.UpdateProgress()
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "执行过程出错: %v\n", err)
+		os.Exit(1)
+	}
+	if !stream {
+		reporterInstance.FinishProgress()
+	}
+	
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Repository.Path < results[j].Repository.Path
+	})
+	reporterInstance.ReportExecResults(results, stream)
+	
+	if cfg.SaveReport {
+		filename := cfg.ReportFile
+		if filename == "" {
+			filename = fmt.Sprintf("reposense-exec-%s.json", time.Now().Format("20060102-150405"))
+		}
+		
+		if err := reporterInstance.SaveReport(filename, results); err != nil {
+			fmt.Fprintf(os.Stderr, "保存报告失败: %v\n", err)
+		} else {
+			fmt.Printf("📄 报告已保存到: %s\n", filename)
+		}
+	}
+	
+	for _, result := range results {
+		if result.Status == updater.StatusFailed {
+			os.Exit(1)
+		}
+	}
+}
+
+func runBranch(cmd *cobra.Command, args []string) {
+	directory := getCurrentDirectory(args[1:])
+	onFailure, _ := cmd.Flags().GetString("on-failure")
+	options := updater.BranchOptions{
+		Operation: updater.BranchOperation(cmd.Name()),
+		Name:      args[0],
+	}
+	options.Remote, _ = cmd.Flags().GetString("remote")
+	if cmd.Flags().Lookup("from") != nil {
+		options.StartPoint, _ = cmd.Flags().GetString("from")
+	}
+	if cmd.Flags().Lookup("force") != nil {
+		options.Force, _ = cmd.Flags().GetBool("force")
+		options.DeleteRemote, _ = cmd.Flags().GetBool("delete-remote")
+	}
+	
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	policy, err := updater.ParseFailurePolicy(onFailure)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	options.OnFailure = policy
+	
+	reporterInstance := reporter.NewReporter(cfg.OutputFormat, cfg.Verbose)
+	repositories, updaterInstance := scanAndBuildUpdater(directory, updater.UpdaterConfig{})
+	
+	if len(repositories) == 0 {
+		fmt.Println("未发现任何Git仓库")
+		return
+	}
+	
+	fmt.Fprintf(infoOutput(), "🌿 %s 分支 %s，使用 %d 个工作协程\n", options.Operation, options.Name, cfg.WorkerCount)
+	
+	results, err := updaterInstance.BranchRepositories(repositories, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Repository.Path < results[j].Repository.Path
+	})
+	reporterInstance.ReportBranchResults(options, results)
+	
+	if cfg.SaveReport {
+		filename := cfg.ReportFile
+		if filename == "" {
+			filename = fmt.Sprintf("reposense-branch-%s.json", time.Now().Format("20060102-150405"))
+		}
+		
+		if err := reporterInstance.SaveReport(filename, results); err != nil {
+			fmt.Fprintf(os.Stderr, "保存报告失败: %v\n", err)
+		} else {
+			fmt.Printf("📄 报告已保存到: %s\n", filename)
+		}
+	}
+	
+	for _, result := range results {
+		if result.Status == updater.StatusFailed {
+			os.Exit(1)
+		}
+	}
+}
+
+// openSnapshotStore opens the cache database that stores snapshots
+func openSnapshotStore() (*cache.Manager, *cache.Cache) {
+	cacheManager, err := cache.NewManager(false, "", "", "", "", "", 0, true, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "初始化缓存失败: %v\n", err)
+		os.Exit(1)
+	}
+	return cacheManager, cacheManager.GetCache()
+}
+
+func runSnapshotSave(cmd *cobra.Command, args []string) {
+	name := strings.TrimSpace(args[0])
+	directory, err := filepath.Abs(getCurrentDirectory(args[1:]))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "无法解析目录: %v\n", err)
+		os.Exit(1)
+	}
+	file, _ := cmd.Flags().GetString("file")
+	force, _ := cmd.Flags().GetBool("force")
+	allowIncomplete, _ := cmd.Flags().GetBool("allow-incomplete")
+	
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	if name == "" {
+		fmt.Fprintln(os.Stderr, "快照名称不能为空")
+		os.Exit(1)
+	}
+	
+	repositories, updaterInstance := scanAndBuildUpdater(directory, updater.UpdaterConfig{})
+	entries, results := updaterInstance.CaptureSnapshot(directory, repositories)
+	snapshot := &cache.Snapshot{
+		Name:      name,
+		Directory: directory,
+		CreatedAt: time.Now(),
+		Repos:     entries,
+	}
+	
+	// 缺少仓库的快照恢复时会把它们报告为差异，默认拒绝保存；
+	// 全部仓库都无法记录时同样以失败结束
+	failed := len(repositories) - len(results) // 中断后未处理的仓库
+	for _, result := range results {
+		if result.Status == updater.StatusFailed {
+			failed++
+			if !allowIncomplete {
+				fmt.Fprintf(os.Stderr, "✗ %s: %s\n", result.Repository.Name, result.Message)
+			}
+		}
+	}
+	if failed > 0 && !allowIncomplete {
+		fmt.Fprintf(os.Stderr, "快照未保存: %d 个仓库无法记录 (使用 --allow-incomplete 忽略这些仓库)\n", failed)
+		os.Exit(1)
+	}
+	
+	if len(entries) == 0 {
+		fmt.Println("没有可记录的仓库")
+		return
+	}
+	
+	if file != "" {
+		if _, err := os.Stat(file); err == nil && !force {
+			fmt.Fprintf(os.Stderr, "文件已存在: %s (使用 --force 覆盖)\n", file)
+			os.Exit(1)
+		}
+		if err := cache.WriteSnapshotFile(file, snapshot); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		cacheManager, store := openSnapshotStore()
+		defer cacheManager.Close()
+		if err := store.SaveSnapshot(snapshot, force); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+	
+	reporter.NewReporter(cfg.OutputFormat, cfg.Verbose).ReportSnapshotSaved(snapshot, results)
+	if file != "" {
+		fmt.Printf("📄 快照已导出到: %s\n", file)
+	}
+}
+
+func runSnapshotRestore(cmd *cobra.Command, args []string) {
+	file, _ := cmd.Flags().GetString("file")
+	
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	
+	var snapshot *cache.Snapshot
+	var err error
+	if file != "" {
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, "使用 --file 时只能指定目录")
+			os.Exit(1)
+		}
+		snapshot, err = cache.ReadSnapshotFile(file)
+	} else {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "需要指定快照名称或 --file")
+			os.Exit(1)
+		}
+		cacheManager, store := openSnapshotStore()
+		snapshot, err = store.Snapshot(args[0])
+		cacheManager.Close()
+		args = args[1:]
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	
+	// 默认恢复保存快照时的目录
+	directory := snapshot.Directory
+	if len(args) > 0 {
+		directory = args[0]
+	}
+	
+	repositories, updaterInstance := scanAndBuildUpdater(directory, updater.UpdaterConfig{})
+	results := updaterInstance.RestoreSnapshot(directory, snapshot, repositories)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Repository.Path < results[j].Repository.Path
+	})
+	reporterInstance := reporter.NewReporter(cfg.OutputFormat, cfg.Verbose)
+	reporterInstance.ReportRestoreResults(snapshot, results)
+	
+	if cfg.SaveReport {
+		filename := cfg.ReportFile
+		if filename == "" {
+			filename = fmt.Sprintf("reposense-restore-%s.json", time.Now().Format("20060102-150405"))
+		}
+		
+		if err := reporterInstance.SaveReport(filename, results); err != nil {
+			fmt.Fprintf(os.Stderr, "保存报告失败: %v\n", err)
+		} else {
+			fmt.Printf("📄 报告已保存到: %s\n", filename)
+		}
+	}
+	
+	for _, result := range results {
+		if result.Status == updater.StatusFailed {
+			os.Exit(1)
+		}
+	}
+}
+
+func runSnapshotList(cmd *cobra.Command, args []string) {
+	cacheManager, store := openSnapshotStore()
+	defer cacheManager.Close()
+	
+	snapshots, err := store.ListSnapshots()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	reporter.NewReporter(cfg.OutputFormat, cfg.Verbose).ReportSnapshots(snapshots)
+}
+
+func runSnapshotDelete(cmd *cobra.Command, args []string) {
+	cacheManager, store := openSnapshotStore()
+	defer cacheManager.Close()
 	
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+	if err := store.DeleteSnapshot(args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("✅ 已删除快照 %s\n", args[0])
 }
 
-func runUpdate(cmd *cobra.Command, args []string) {
+func runScan(cmd *cobra.Command, args []string) {
 	directory := getCurrentDirectory(args)
 	
 	if err := cfg.Validate(); err != nil {
@@ -308,13 +1081,15 @@ func runUpdate(cmd *cobra.Command, args []string) {
 	
 	// 初始化组件
 	scannerInstance := scanner.NewScanner()
+	scannerInstance.SetScanOptions(scanOptions())
+	defer attachMetadataSource(scannerInstance)()
 	reporterInstance := reporter.NewReporter(cfg.OutputFormat, cfg.Verbose)
 	
 	if cfg.Verbose {
 		scannerInstance.SetLogLevel(logrus.DebugLevel)
 	}
 	
-	fmt.Printf("🔍 正在扫描目录: %s\n", directory)
+	announceScan(directory)
 	
 	// 扫描仓库
 	repositories, err := scannerInstance.ScanDirectoryWithFilter(directory, cfg.IncludePatterns, cfg.ExcludePatterns)
@@ -323,59 +1098,27 @@ func runUpdate(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 	
-	if len(repositories) == 0 {
-		fmt.Println("未发现任何Git仓库")
-		return
-	}
-	
-	fmt.Printf("📦 发现 %d 个Git仓库\n", len(repositories))
-	
-	// 配置更新器
-	updaterConfig := updater.UpdaterConfig{
-		WorkerCount:       cfg.WorkerCount,
-		Timeout:           cfg.Timeout,
-		DryRun:            cfg.DryRun,
-		GitPullStrategy:   gitPullStrategy,
-		GitNonInteractive: !gitAllowInteractive, // 反转：不允许交互 = 启用非交互模式
-	}
-	
-	updaterInstance := updater.NewUpdater(updaterConfig)
-	if cfg.Verbose {
-		updaterInstance.SetLogLevel(logrus.DebugLevel)
-	}
-	
-	// 初始化进度条
-	description := "更新仓库"
-	if cfg.DryRun {
-		description = "模拟更新"
-	}
-	reporterInstance.InitProgressBar(len(repositories), description)
-	
-	// 执行更新
-	fmt.Printf("🚀 开始更新，使用 %d 个工作协程\n", cfg.WorkerCount)
-	
-	results, err := updaterInstance.UpdateRepositories(repositories, func(result updater.UpdateResult) {
-		reporterInstance.UpdateProgress()
-	})
-	
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "更新过程出错: %v\n", err)
-		os.Exit(1)
-	}
-	
-	reporterInstance.FinishProgress()
+	// 记录仓库清单，识别新增、删除和移动的仓库
+	showDiff, _ := cmd.Flags().GetBool("diff")
+	diff := syncInventory(repositories, showDiff)
 	
 	// 显示结果
-	reporterInstance.ReportUpdateResults(results)
+	var reportData interface{} = repositories
+	if showDiff {
+		reporterInstance.ReportInventoryDiff(diff)
+		reportData = diff
+	} else {
+		reporterInstance.ReportScanResults(repositories)
+	}
 	
 	// 保存报告
 	if cfg.SaveReport {
 		filename := cfg.ReportFile
 		if filename == "" {
-			filename = fmt.Sprintf("reposense-update-%s.json", time.Now().Format("20060102-150405"))
+			filename = fmt.Sprintf("reposense-scan-%s.json", time.Now().Format("20060102-150405"))
 		}
 		
-		if err := reporterInstance.SaveReport(filename, results); err != nil {
+		if err := reporterInstance.SaveReport(filename, reportData); err != nil {
 			fmt.Fprintf(os.Stderr, "保存报告失败: %v\n", err)
 		} else {
 			fmt.Printf("📄 报告已保存到: %s\n", filename)
@@ -383,7 +1126,39 @@ func runUpdate(cmd *cobra.Command, args []string) {
 	}
 }
 
-func runScan(cmd *cobra.Command, args []string) {
+// syncInventory records the scanned repositories in the cache inventory.
+// Failures are fatal only when the caller needs the diff.
+func syncInventory(repositories []scanner.Repository, required bool) *scanner.InventoryDiff {
+	cacheManager, err := cache.NewManager(false, "", "", "", "", "", 0, true, false)
+	if err != nil {
+		if required {
+			fmt.Fprintf(os.Stderr, "初始化缓存失败: %v\n", err)
+			os.Exit(1)
+		}
+		return nil
+	}
+	defer cacheManager.Close()
+	
+	cachedScanner := scanner.NewCachedScanner(cacheManager)
+	if cfg.Verbose {
+		cachedScanner.SetLogLevel(logrus.DebugLevel)
+	} else {
+		cachedScanner.SetLogLevel(logrus.WarnLevel)
+	}
+	cachedScanner.SetWorkerCount(cfg.WorkerCount)
+	
+	diff, err := cachedScanner.SyncInventory(repositories)
+	if err != nil {
+		if required {
+			fmt.Fprintf(os.Stderr, "更新仓库清单失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "警告: 更新仓库清单失败: %v\n", err)
+	}
+	return diff
+}
+
+func runStatus(cmd *cobra.Command, args []string) {
 	directory := getCurrentDirectory(args)
 	
 	if err := cfg.Validate(); err != nil {
@@ -391,15 +1166,25 @@ func runScan(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 	
+	query, err := statusQuery(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "参数错误: %v\n", err)
+		os.Exit(1)
+	}
+	
 	// 初始化组件
 	scannerInstance := scanner.NewScanner()
+	scannerInstance.SetScanOptions(scanOptions())
+	defer attachMetadataSource(scannerInstance)()
 	reporterInstance := reporter.NewReporter(cfg.OutputFormat, cfg.Verbose)
+	statusCollector := scanner.NewStatusCollector(cfg.Timeout)
 	
 	if cfg.Verbose {
 		scannerInstance.SetLogLevel(logrus.DebugLevel)
+		statusCollector.SetLogLevel(logrus.DebugLevel)
 	}
 	
-	fmt.Printf("🔍 正在扫描目录: %s\n", directory)
+	announceScan(directory)
 	
 	// 扫描仓库
 	repositories, err := scannerInstance.ScanDirectoryWithFilter(directory, cfg.IncludePatterns, cfg.ExcludePatterns)
@@ -408,25 +1193,81 @@ func runScan(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 	
+	if len(repositories) == 0 {
+		fmt.Println("未发现任何Git仓库")
+		return
+	}
+	
+	fmt.Fprintf(infoOutput(), "📦 发现 %d 个Git仓库，正在收集状态信息...\n", len(repositories))
+	
+	// 收集状态，Ctrl-C 时停止
+	fetch, _ := cmd.Flags().GetBool("fetch")
+	statusCollector.SetFetch(fetch)
+	branches, _ := cmd.Flags().GetBool("branches")
+	statusCollector.SetBranches(branches)
+	// 只有详细输出会显示忽略的文件数
+	statusCollector.SetIgnored(cfg.Verbose)
+	statusCollector.SetWorkerCount(cfg.WorkerCount)
+	stopOnInterrupt(statusCollector.Stop)
+	
+	reporterInstance.InitProgressBar(len(repositories), "收集状态")
+	statuses := statusCollector.CollectBatchStatus(repositories, func(status scanner.RepositoryStatus) {
+		reporterInstance.UpdateProgress()
+	})
+	reporterInstance.FinishProgress()
+	
+	// 按查询条件筛选需要关注的仓库
+	statuses = query.Filter(statuses)
+	if !query.Empty() {
+		fmt.Fprintf(os.Stderr, "🔎 %d 个仓库符合查询条件\n", len(statuses))
+	}
+	
 	// 显示结果
-	reporterInstance.ReportScanResults(repositories)
+	reporterInstance.ReportStatusResults(statuses)
 	
 	// 保存报告
 	if cfg.SaveReport {
 		filename := cfg.ReportFile
 		if filename == "" {
-			filename = fmt.Sprintf("reposense-scan-%s.json", time.Now().Format("20060102-150405"))
+			filename = fmt.Sprintf("reposense-status-%s.json", time.Now().Format("20060102-150405"))
 		}
 		
-		if err := reporterInstance.SaveReport(filename, repositories); err != nil {
+		if err := reporterInstance.SaveReport(filename, statuses); err != nil {
 			fmt.Fprintf(os.Stderr, "保存报告失败: %v\n", err)
 		} else {
 			fmt.Printf("📄 报告已保存到: %s\n", filename)
 		}
 	}
+	
+	// 有仓库符合查询条件时以非零退出码结束，便于脚本判断
+	if !query.Empty() && len(statuses) > 0 {
+		os.Exit(exitStatusMatched)
+	}
 }
 
-func runStatus(cmd *cobra.Command, args []string) {
+// exitStatusMatched is the exit code of status when a query flag matched at least one repository
+const exitStatusMatched = 2
+
+// statusQuery builds the status query from the command's query flags
+func statusQuery(cmd *cobra.Command) (scanner.StatusQuery, error) {
+	var query scanner.StatusQuery
+	query.Dirty, _ = cmd.Flags().GetBool("dirty")
+	query.Ahead, _ = cmd.Flags().GetBool("ahead")
+	query.Behind, _ = cmd.Flags().GetBool("behind")
+	query.Diverged, _ = cmd.Flags().GetBool("diverged")
+	query.NoUpstream, _ = cmd.Flags().GetBool("no-upstream")
+	query.Detached, _ = cmd.Flags().GetBool("detached")
+	
+	stale, _ := cmd.Flags().GetString("stale")
+	age, err := scanner.ParseAge(stale)
+	if err != nil {
+		return query, fmt.Errorf("--stale: %w", err)
+	}
+	query.Stale = age
+	return query, nil
+}
+
+func runAuditUnpushed(cmd *cobra.Command, args []string) {
 	directory := getCurrentDirectory(args)
 	
 	if err := cfg.Validate(); err != nil {
@@ -434,8 +1275,9 @@ func runStatus(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 	
-	// 初始化组件
 	scannerInstance := scanner.NewScanner()
+	scannerInstance.SetScanOptions(scanOptions())
+	defer attachMetadataSource(scannerInstance)()
 	reporterInstance := reporter.NewReporter(cfg.OutputFormat, cfg.Verbose)
 	statusCollector := scanner.NewStatusCollector(cfg.Timeout)
 	
@@ -444,9 +1286,8 @@ func runStatus(cmd *cobra.Command, args []string) {
 		statusCollector.SetLogLevel(logrus.DebugLevel)
 	}
 	
-	fmt.Printf("🔍 正在扫描目录: %s\n", directory)
+	announceScan(directory)
 	
-	// 扫描仓库
 	repositories, err := scannerInstance.ScanDirectoryWithFilter(directory, cfg.IncludePatterns, cfg.ExcludePatterns)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "扫描失败: %v\n", err)
@@ -458,19 +1299,26 @@ func runStatus(cmd *cobra.Command, args []string) {
 		return
 	}
 	
-	fmt.Printf("📦 发现 %d 个Git仓库，正在收集状态信息...\n", len(repositories))
+	fmt.Fprintf(infoOutput(), "📦 发现 %d 个Git仓库，正在检查未推送的工作...\n", len(repositories))
 	
-	// 收集状态
-	statuses := statusCollector.CollectBatchStatus(repositories)
+	fetch, _ := cmd.Flags().GetBool("fetch")
+	statusCollector.SetFetch(fetch)
+	statusCollector.SetAudit(true)
+	statusCollector.SetWorkerCount(cfg.WorkerCount)
+	stopOnInterrupt(statusCollector.Stop)
 	
-	// 显示结果
-	reporterInstance.ReportStatusResults(statuses)
+	reporterInstance.InitProgressBar(len(repositories), "审计仓库")
+	statuses := statusCollector.CollectBatchStatus(repositories, func(status scanner.RepositoryStatus) {
+		reporterInstance.UpdateProgress()
+	})
+	reporterInstance.FinishProgress()
+	
+	reporterInstance.ReportAuditResults(statuses)
 	
-	// 保存报告
 	if cfg.SaveReport {
 		filename := cfg.ReportFile
 		if filename == "" {
-			filename = fmt.Sprintf("reposense-status-%s.json", time.Now().Format("20060102-150405"))
+			filename = fmt.Sprintf("reposense-audit-%s.json", time.Now().Format("20060102-150405"))
 		}
 		
 		if err := reporterInstance.SaveReport(filename, statuses); err != nil {
@@ -479,6 +1327,13 @@ func runStatus(cmd *cobra.Command, args []string) {
 			fmt.Printf("📄 报告已保存到: %s\n", filename)
 		}
 	}
+	
+	// 有不安全的仓库时与 status 查询一致，以退出码 2 结束
+	for _, status := range statuses {
+		if !status.AuditSafe() {
+			os.Exit(exitStatusMatched)
+		}
+	}
 }
 
 func runList(cmd *cobra.Command, args []string) {
@@ -489,6 +1344,12 @@ func runList(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 	
+	sortKey, err := scanner.ParseSortKey(cfg.SortKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置错误: %v\n", err)
+		os.Exit(1)
+	}
+	
 	// 检查是否从环境变量读取API密钥
 	if cfg.LLMAPIKey == "" && cfg.EnableLLM {
 		if key := os.Getenv("OPENAI_API_KEY"); key != "" && cfg.LLMProvider == "openai" {
@@ -522,6 +1383,8 @@ func runList(cmd *cobra.Command, args []string) {
 	
 	// 初始化缓存扫描器
 	cachedScanner := scanner.NewCachedScanner(cacheManager)
+	cachedScanner.SetScanOptions(scanOptions())
+	cachedScanner.SetWorkerCount(cfg.WorkerCount)
 	reporterInstance := reporter.NewReporter(cfg.OutputFormat, cfg.Verbose)
 	
 	if cfg.Verbose {
@@ -551,7 +1414,7 @@ func runList(cmd *cobra.Command, args []string) {
 			cfg.LLMProvider, cfg.LLMModel, cfg.LLMLanguage, cacheStatus)
 	}
 	
-	fmt.Printf("🔍 正在扫描目录: %s\n", directory)
+	announceScan(directory)
 	
 	// 扫描仓库并获取描述（使用缓存）
 	repositories, err := cachedScanner.ScanDirectoryWithDescription(
@@ -583,7 +1446,7 @@ func runList(cmd *cobra.Command, args []string) {
 	}
 	
 	// 显示结果
-	reporterInstance.ReportListResults(repositories, cfg.SortByTime, cfg.Reverse)
+	reporterInstance.ReportListResults(repositories, cfg.SortByTime, sortKey, cfg.Reverse)
 	
 	// 保存报告
 	if cfg.SaveReport {
@@ -615,12 +1478,139 @@ func getCurrentDirectory(args []string) string {
 	return wd
 }
 
+// scanOptions builds the directory traversal options from the configuration
+func scanOptions() scanner.ScanOptions {
+	options := scanner.DefaultScanOptions()
+	options.MaxDepth = cfg.MaxDepth
+	options.FollowSymlinks = cfg.FollowSymlinks
+	options.SkipDirs = cfg.SkipDirs
+	options.Submodules = cfg.Submodules
+	options.Manifest = cfg.Manifest
+	options.Groups = cfg.Groups
+	return options
+}
+
+// scanAndBuildUpdater scans directory with the configured filters and creates
+// the updater that processes the repositories. The settings shared by all
+// commands and the manifest's per-repository options are filled into config;
+// commands that reach the remotes set HostLimits themselves.
+func scanAndBuildUpdater(directory string, config updater.UpdaterConfig) ([]scanner.Repository, *updater.Updater) {
+	scannerInstance := scanner.NewScanner()
+	scannerInstance.SetScanOptions(scanOptions())
+	if cfg.Verbose {
+		scannerInstance.SetLogLevel(logrus.DebugLevel)
+	}
+	
+	announceScan(directory)
+	
+	// 元数据缓存只在扫描时用于 lang:/tag: 过滤，退出前无需保持打开
+	closeMetadata := attachMetadataSource(scannerInstance)
+	repositories, err := scannerInstance.ScanDirectoryWithFilter(directory, cfg.IncludePatterns, cfg.ExcludePatterns)
+	closeMetadata()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "扫描失败: %v\n", err)
+		os.Exit(1)
+	}
+	if len(repositories) > 0 {
+		fmt.Fprintf(infoOutput(), "📦 发现 %d 个Git仓库\n", len(repositories))
+	}
+	
+	config.WorkerCount = cfg.WorkerCount
+	config.Timeout = cfg.Timeout
+	config.DryRun = cfg.DryRun
+	config.GitNonInteractive = !gitAllowInteractive // 反转：不允许交互 = 启用非交互模式
+	config.Retries = cfg.Retries
+	config.RetryBackoff = cfg.RetryBackoff
+	
+	updaterInstance := updater.NewUpdater(config)
+	if cfg.Verbose {
+		updaterInstance.SetLogLevel(logrus.DebugLevel)
+	}
+	if cfg.Manifest != "" {
+		manifest, err := scanner.LoadManifest(cfg.Manifest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "加载清单失败: %v\n", err)
+			os.Exit(1)
+		}
+		updaterInstance.SetRepositoryOptions(manifest.Options())
+	}
+	stopOnInterrupt(updaterInstance.Stop)
+	return repositories, updaterInstance
+}
+
+// stopOnInterrupt calls stop when the process receives Ctrl-C or SIGTERM
+func stopOnInterrupt(stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Fprintln(os.Stderr, "\n⚠️  收到中断信号，正在停止...")
+		stop()
+		signal.Stop(signals)
+	}()
+}
+
+// announceScan prints where repositories are discovered from
+func announceScan(directory string) {
+	if cfg.Manifest != "" {
+		fmt.Fprintf(infoOutput(), "📄 正在读取清单: %s\n", cfg.Manifest)
+		return
+	}
+	fmt.Fprintf(infoOutput(), "🔍 正在扫描目录: %s\n", directory)
+}
+
+// infoOutput returns where progress messages are printed; JSON output keeps stdout for the report
+func infoOutput() *os.File {
+	if cfg.OutputFormat == reporter.FormatJSON {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// openMetadataSource opens the metadata cache when the filters use lang: or tag:.
+// It returns a nil source when no cache is needed, plus a function that closes it.
+func openMetadataSource() (scanner.MetadataSource, func()) {
+	patterns := append(append([]string{}, cfg.IncludePatterns...), cfg.ExcludePatterns...)
+	if !filter.UsesAttribute(patterns, filter.AttrLang, filter.AttrTag) {
+		return nil, func() {}
+	}
+
+	cacheManager, err := cache.NewManager(false, "", "", "", "", "", 0, true, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "警告: 打开缓存失败，lang:/tag: 过滤不会匹配任何仓库: %v\n", err)
+		return nil, func() {}
+	}
+	return cacheManager.GetCache().GetMetadataCache(), func() { cacheManager.Close() }
+}
+
+// attachMetadataSource wires the metadata cache into the scanner if the filters need it
+func attachMetadataSource(s *scanner.Scanner) func() {
+	metadata, closeMetadata := openMetadataSource()
+	if metadata != nil {
+		s.SetMetadataSource(metadata)
+	}
+	return closeMetadata
+}
+
 func runConfigShow(cmd *cobra.Command, args []string) {
 	fmt.Printf("配置文件路径: %s\n", config.GetConfigPath())
 	fmt.Println("\n当前配置:")
 	fmt.Printf("  工作协程数: %d\n", cfg.WorkerCount)
 	fmt.Printf("  超时时间: %v\n", cfg.Timeout)
 	fmt.Printf("  输出格式: %s\n", cfg.OutputFormat)
+	fmt.Printf("  最大扫描深度: %d\n", cfg.MaxDepth)
+	hosts := make([]string, 0, len(cfg.HostLimits))
+	for host := range cfg.HostLimits {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		limit := cfg.HostLimits[host]
+		fmt.Printf("  主机限制 %s: 并发 %d, 间隔 %v\n", host, limit.MaxConcurrent, limit.MinInterval)
+	}
+	for _, hook := range cfg.Hooks {
+		fmt.Printf("  更新后钩子 %s: %s (路径 %s)\n", hook.Name, hook.Command, strings.Join(hook.Paths, ", "))
+	}
 	fmt.Printf("  启用LLM: %v\n", cfg.EnableLLM)
 	if cfg.EnableLLM {
 		fmt.Printf("  LLM提供商: %s\n", cfg.LLMProvider)
@@ -736,6 +1726,138 @@ func runCachePath(cmd *cobra.Command, args []string) {
 	fmt.Println(cacheManager.GetDatabasePath())
 }
 
+func runManifestGenerate(cmd *cobra.Command, args []string) {
+	directory := getCurrentDirectory(args)
+	output, _ := cmd.Flags().GetString("output")
+	force, _ := cmd.Flags().GetBool("force")
+	
+	if output == "" {
+		output = filepath.Join(directory, scanner.ManifestFileName)
+	}
+	
+	// 相对路径以清单所在目录为基准
+	baseDir := filepath.Dir(output)
+	if output == "-" {
+		baseDir = directory
+	}
+	
+	scannerInstance := scanner.NewScanner()
+	options := scanOptions()
+	options.Manifest = ""
+	scannerInstance.SetScanOptions(options)
+	if cfg.Verbose {
+		scannerInstance.SetLogLevel(logrus.DebugLevel)
+	} else {
+		scannerInstance.SetLogLevel(logrus.WarnLevel)
+	}
+	
+	manifest, err := scannerInstance.GenerateManifest(directory, baseDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "生成清单失败: %v\n", err)
+		os.Exit(1)
+	}
+	
+	if output == "-" {
+		data, err := manifest.Marshal()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "生成清单失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(string(data))
+		return
+	}
+	
+	if _, err := os.Stat(output); err == nil && !force {
+		fmt.Fprintf(os.Stderr, "清单文件已存在: %s (使用 --force 覆盖)\n", output)
+		os.Exit(1)
+	}
+	
+	if err := manifest.Save(output); err != nil {
+		fmt.Fprintf(os.Stderr, "保存清单失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 已生成清单: %s (%d 个仓库)\n", output, len(manifest.Repositories))
+}
+
+func runTagAdd(cmd *cobra.Command, args []string) {
+	repoPath := absoluteRepositoryPath(args[0])
+	
+	cacheManager, err := cache.NewManager(false, "", "", "", "", "", 0, true, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "初始化缓存失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer cacheManager.Close()
+	
+	if err := cacheManager.GetCache().GetMetadataCache().AddTags(repoPath, args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "添加标签失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 已为 %s 添加标签: %s\n", repoPath, strings.Join(args[1:], ", "))
+}
+
+func runTagRemove(cmd *cobra.Command, args []string) {
+	repoPath := absoluteRepositoryPath(args[0])
+	
+	cacheManager, err := cache.NewManager(false, "", "", "", "", "", 0, true, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "初始化缓存失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer cacheManager.Close()
+	
+	if err := cacheManager.GetCache().GetMetadataCache().RemoveTags(repoPath, args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "删除标签失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 已从 %s 删除标签: %s\n", repoPath, strings.Join(args[1:], ", "))
+}
+
+func runTagList(cmd *cobra.Command, args []string) {
+	cacheManager, err := cache.NewManager(false, "", "", "", "", "", 0, true, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "初始化缓存失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer cacheManager.Close()
+	
+	metadataCache := cacheManager.GetCache().GetMetadataCache()
+	if len(args) > 0 {
+		repoPath := absoluteRepositoryPath(args[0])
+		fmt.Printf("%s: %s\n", repoPath, strings.Join(metadataCache.Tags(repoPath), ", "))
+		return
+	}
+	
+	allTags, err := metadataCache.AllTags()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取标签失败: %v\n", err)
+		os.Exit(1)
+	}
+	if len(allTags) == 0 {
+		fmt.Println("暂无带标签的仓库")
+		return
+	}
+	
+	paths := make([]string, 0, len(allTags))
+	for path := range allTags {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Printf("%s: %s\n", path, strings.Join(allTags[path], ", "))
+	}
+}
+
+// absoluteRepositoryPath resolves a repository argument to the absolute path used as cache key
+func absoluteRepositoryPath(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "无法解析路径 %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	return absPath
+}
+
 func runAnalyze(cmd *cobra.Command, args []string) {
 	directory := getCurrentDirectory(args)
 	
@@ -810,8 +1932,10 @@ func runAnalyze(cmd *cobra.Command, args []string) {
 	}
 	
 	// 扫描仓库
-	fmt.Printf("🔍 正在扫描目录: %s\n", directory)
+	announceScan(directory)
 	scannerInstance := scanner.NewScanner()
+	scannerInstance.SetScanOptions(scanOptions())
+	scannerInstance.SetMetadataSource(cacheManager.GetCache().GetMetadataCache())
 	if cfg.Verbose {
 		scannerInstance.SetLogLevel(logrus.DebugLevel)
 	}
@@ -842,10 +1966,18 @@ func runAnalyze(cmd *cobra.Command, args []string) {
 	for i, repo := range repositories {
 		fmt.Printf("[%d/%d] 正在分析: %s\n", i+1, totalRepos, repo.Name)
 		
+		// 裸仓库没有工作区可供分析
+		if !repo.Kind.HasWorkTree() {
+			fmt.Printf("  - 跳过裸仓库\n")
+			continue
+		}
+		
+		repoConfig := repositoryAnalysisConfig(analysisConfig, repo)
+		
 		// 检查缓存
 		var metadata *analyzer.ProjectMetadata
 		if !forceRefresh {
-			structureHash, err := analyzer.GenerateStructureHash(repo.Path, analysisConfig.IgnorePatterns)
+			structureHash, err := analyzer.GenerateStructureHash(repo.Path, repoConfig.IgnorePatterns)
 			if err == nil {
 				if cachedMetadata, found := metadataCache.GetCachedMetadata(repo.Path, structureHash); found {
 					metadata = cachedMetadata
@@ -856,7 +1988,7 @@ func runAnalyze(cmd *cobra.Command, args []string) {
 		
 		// 如果没有缓存，执行分析
 		if metadata == nil {
-			analyzedMetadata, err := metadataService.AnalyzeRepository(repo.Path, analysisConfig)
+			analyzedMetadata, err := metadataService.AnalyzeRepository(repo.Path, repoConfig)
 			if err != nil {
 				fmt.Printf("  ✗ 分析失败: %v\n", err)
 				continue
@@ -890,8 +2022,12 @@ func runAnalyze(cmd *cobra.Command, args []string) {
 		// 收集所有分析结果
 		var allMetadata []map[string]interface{}
 		for _, repo := range repositories {
+			if !repo.Kind.HasWorkTree() {
+				continue
+			}
+			
 			// 获取每个仓库的元数据
-			structureHash, _ := analyzer.GenerateStructureHash(repo.Path, analysisConfig.IgnorePatterns)
+			structureHash, _ := analyzer.GenerateStructureHash(repo.Path, repositoryAnalysisConfig(analysisConfig, repo).IgnorePatterns)
 			if metadata, found := metadataCache.GetCachedMetadata(repo.Path, structureHash); found {
 				metadataService := analyzer.NewMetadataService()
 				report := metadataService.GetAnalysisReport(metadata)
@@ -922,6 +2058,18 @@ func runAnalyze(cmd *cobra.Command, args []string) {
 	}
 }
 
+// repositoryAnalysisConfig excludes a repository's submodules, which are analyzed on their own
+func repositoryAnalysisConfig(base *analyzer.AnalysisConfig, repo scanner.Repository) *analyzer.AnalysisConfig {
+	submodules := scanner.SubmodulePaths(repo.Path)
+	if len(submodules) == 0 {
+		return base
+	}
+	
+	repoConfig := *base
+	repoConfig.IgnorePatterns = append(append([]string{}, base.IgnorePatterns...), submodules...)
+	return &repoConfig
+}
+
 func runMetadataShow(cmd *cobra.Command, args []string) {
 	var repoPath string
 	if len(args) > 0 {
@@ -1297,6 +2445,9 @@ func runChangelog(cmd *cobra.Command, args []string) {
 		}
 	}
 	
+	metadata, closeMetadata := openMetadataSource()
+	defer closeMetadata()
+	
 	// 构建分析选项
 	opts := changelog.ChangelogOptions{
 		Directory:       directory,
@@ -1311,6 +2462,8 @@ func runChangelog(cmd *cobra.Command, args []string) {
 		LLMTimeout:     cfg.LLMTimeout,
 		IncludePatterns: cfg.IncludePatterns,
 		ExcludePatterns: cfg.ExcludePatterns,
+		ScanOptions:    scanOptions(),
+		Metadata:       metadata,
 		OutputFormat:   cfg.OutputFormat,
 		SaveReport:     cfg.SaveReport,
 		ReportFile:     cfg.ReportFile,
@@ -1320,7 +2473,7 @@ func runChangelog(cmd *cobra.Command, args []string) {
 	}
 	
 	// 显示分析信息
-	fmt.Printf("🔍 正在扫描目录: %s\n", directory)
+	announceScan(directory)
 	fmt.Printf("📅 时间范围: %s 至 %s\n", 
 		timeRange.Since.Format("2006-01-02"), timeRange.Until.Format("2006-01-02"))
 	fmt.Printf("⚙️  分析模式: %s\n", mode)
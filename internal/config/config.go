@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"reposense/pkg/reporter"
+	"reposense/pkg/updater"
 )
 
 // Config holds the application configuration
@@ -23,9 +24,26 @@ type Config struct {
 	IncludePatterns []string `json:"include_patterns"`
 	ExcludePatterns []string `json:"exclude_patterns"`
 	
+	// Scanning options
+	MaxDepth       int      `json:"max_depth"`
+	FollowSymlinks bool     `json:"follow_symlinks"`
+	SkipDirs       []string `json:"skip_dirs"`
+	Submodules     bool     `json:"submodules"`
+	Manifest       string   `json:"manifest"`
+	Groups         []string `json:"groups"`
+	
 	// Sorting options
-	SortByTime bool `json:"sort_by_time"`
-	Reverse    bool `json:"reverse"`
+	SortByTime bool   `json:"sort_by_time"`
+	SortKey    string `json:"sort_key"`
+	Reverse    bool   `json:"reverse"`
+	
+	// Update options
+	DirtyPolicy  string        `json:"dirty_policy"`  // skip, stash, fail
+	AllBranches  bool          `json:"all_branches"`  // 同时快进未检出的本地分支
+	Retries      int           `json:"retries"`       // 网络错误或超时后的重试次数
+	RetryBackoff time.Duration `json:"retry_backoff"` // 首次重试前的等待时间
+	HostLimits   map[string]updater.HostLimit `json:"host_limits"` // 按远程主机限制并发，"*" 适用于其他主机
+	Hooks        []updater.PostUpdateHook     `json:"hooks"`       // 拉取改动了指定路径时执行的命令
 	
 	// LLM options
 	EnableLLM     bool   `json:"enable_llm"`
@@ -52,8 +70,18 @@ func DefaultConfig() *Config {
 		OutputFormat:    reporter.FormatTable,
 		IncludePatterns: []string{},
 		ExcludePatterns: []string{},
+		MaxDepth:        0,
+		FollowSymlinks:  false,
+		SkipDirs:        []string{},
+		Submodules:      false,
+		Manifest:        "",
+		Groups:          []string{},
 		SortByTime:      false,
+		SortKey:         "committer",
 		Reverse:         false,
+		DirtyPolicy:     "skip",
+		Retries:         2,
+		RetryBackoff:    2 * time.Second,
 		EnableLLM:       true,
 		LLMProvider:     "gemini",
 		LLMModel:        "gemini-2.5-flash",
@@ -83,8 +111,9 @@ func LoadConfig() *Config {
 	configPath := GetConfigPath()
 	if data, err := os.ReadFile(configPath); err == nil {
 		var fileConfig Config
-		if err := json.Unmarshal(data, &fileConfig); err == nil {
-			mergeConfig(cfg, &fileConfig)
+		var keys map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fileConfig); err == nil && json.Unmarshal(data, &keys) == nil {
+			mergeConfig(cfg, &fileConfig, keys)
 		}
 	}
 	
@@ -112,8 +141,14 @@ func (c *Config) SaveConfig() error {
 	return nil
 }
 
-// mergeConfig merges file config into default config, only overriding non-zero values
-func mergeConfig(dst, src *Config) {
+// mergeConfig merges file config into default config, only overriding non-zero values.
+// Options whose zero value is meaningful are taken whenever their key is present in the file.
+func mergeConfig(dst, src *Config, keys map[string]json.RawMessage) {
+	present := func(key string) bool {
+		_, ok := keys[key]
+		return ok
+	}
+	
 	if src.WorkerCount != 0 {
 		dst.WorkerCount = src.WorkerCount
 	}
@@ -135,9 +170,49 @@ func mergeConfig(dst, src *Config) {
 	if len(src.ExcludePatterns) > 0 {
 		dst.ExcludePatterns = src.ExcludePatterns
 	}
+	if src.MaxDepth != 0 {
+		dst.MaxDepth = src.MaxDepth
+	}
+	if src.FollowSymlinks {
+		dst.FollowSymlinks = src.FollowSymlinks
+	}
+	if len(src.SkipDirs) > 0 {
+		dst.SkipDirs = src.SkipDirs
+	}
+	if src.Submodules {
+		dst.Submodules = src.Submodules
+	}
+	if src.Manifest != "" {
+		dst.Manifest = src.Manifest
+	}
+	if len(src.Groups) > 0 {
+		dst.Groups = src.Groups
+	}
 	if src.SortByTime {
 		dst.SortByTime = src.SortByTime
 	}
+	if src.SortKey != "" {
+		dst.SortKey = src.SortKey
+	}
+	if src.AllBranches {
+		dst.AllBranches = src.AllBranches
+	}
+	if src.DirtyPolicy != "" {
+		dst.DirtyPolicy = src.DirtyPolicy
+	}
+	// 0 表示不重试 / 立即重试，不能按零值忽略
+	if present("retries") {
+		dst.Retries = src.Retries
+	}
+	if present("retry_backoff") {
+		dst.RetryBackoff = src.RetryBackoff
+	}
+	if len(src.HostLimits) > 0 {
+		dst.HostLimits = src.HostLimits
+	}
+	if len(src.Hooks) > 0 {
+		dst.Hooks = src.Hooks
+	}
 	if src.Reverse {
 		dst.Reverse = src.Reverse
 	}
@@ -190,6 +265,14 @@ func (c *Config) Validate() error {
 		c.Timeout = 30 * time.Second
 	}
 	
+	if c.MaxDepth < 0 {
+		c.MaxDepth = 0
+	}
+	
+	if c.Retries < 0 {
+		c.Retries = 0
+	}
+	
 	// 验证输出格式
 	switch c.OutputFormat {
 	case reporter.FormatTable, reporter.FormatJSON, reporter.FormatText:
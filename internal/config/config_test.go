@@ -0,0 +1,40 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMergeConfigExplicitZero(t *testing.T) {
+	tests := []struct {
+		file        string
+		wantRetries int
+		wantBackoff time.Duration
+		wantWorkers int
+	}{
+		{`{}`, 2, 2 * time.Second, 10},
+		{`{"retries": 0, "retry_backoff": 0}`, 0, 0, 10},
+		{`{"retries": 5, "worker_count": 0}`, 5, 2 * time.Second, 10},
+		{`{"worker_count": 4}`, 2, 2 * time.Second, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			var src Config
+			var keys map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.file), &src); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.file), &keys); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := DefaultConfig()
+			mergeConfig(cfg, &src, keys)
+			if cfg.Retries != tt.wantRetries || cfg.RetryBackoff != tt.wantBackoff || cfg.WorkerCount != tt.wantWorkers {
+				t.Errorf("retries = %d, backoff = %s, workers = %d, want %d, %s, %d",
+					cfg.Retries, cfg.RetryBackoff, cfg.WorkerCount, tt.wantRetries, tt.wantBackoff, tt.wantWorkers)
+			}
+		})
+	}
+}
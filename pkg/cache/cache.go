@@ -157,6 +157,20 @@ func (c *Cache) migrateDatabase() error {
 		c.logger.Info("数据库迁移完成")
 	}
 
+	// 更新日志记录拉取中断后留下的未完成操作
+	var hasOperation int
+	err = c.db.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('update_journal') WHERE name = 'operation'
+	`).Scan(&hasOperation)
+	if err != nil {
+		return fmt.Errorf("检查表结构失败: %w", err)
+	}
+	if hasOperation == 0 {
+		if _, err := c.db.Exec("ALTER TABLE update_journal ADD COLUMN operation TEXT"); err != nil {
+			return fmt.Errorf("执行迁移失败 [update_journal.operation]: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -265,6 +279,8 @@ func (c *Cache) ClearCache() error {
 		"repository_languages", 
 		"repository_tags", 
 		"repositories",
+		"repository_inventory",
+		"repository_commit_dates",
 	}
 	for _, table := range tables {
 		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s", table)); err != nil {
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"reposense/pkg/git"
+)
+
+// GetCommitDates returns cached commit dates if they were computed for head
+func (c *Cache) GetCommitDates(repoPath, head string) (git.CommitDates, bool) {
+	var committer, author, latest string
+	err := c.db.QueryRow(`
+		SELECT committer_date, author_date, latest_branch_date
+		FROM repository_commit_dates
+		WHERE path = ? AND head = ?
+	`, repoPath, head).Scan(&committer, &author, &latest)
+	if err != nil {
+		return git.CommitDates{}, false
+	}
+
+	dates := git.CommitDates{Head: head}
+	dates.CommitterDate, _ = time.Parse(time.RFC3339, committer)
+	dates.AuthorDate, _ = time.Parse(time.RFC3339, author)
+	dates.LatestBranchDate, _ = time.Parse(time.RFC3339, latest)
+	return dates, true
+}
+
+// SaveCommitDates caches commit dates keyed by the HEAD they were computed for
+func (c *Cache) SaveCommitDates(repoPath string, dates git.CommitDates) error {
+	_, err := c.db.Exec(`
+		INSERT OR REPLACE INTO repository_commit_dates
+		(path, head, committer_date, author_date, latest_branch_date, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`, repoPath, dates.Head,
+		dates.CommitterDate.Format(time.RFC3339),
+		dates.AuthorDate.Format(time.RFC3339),
+		dates.LatestBranchDate.Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("保存提交时间失败: %w", err)
+	}
+	return nil
+}
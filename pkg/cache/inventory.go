@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"reposense/pkg/filter"
+)
+
+// InventoryEntry is a repository recorded by a previous scan
+type InventoryEntry struct {
+	Path       string    `json:"path"`
+	Name       string    `json:"name"`
+	Kind       string    `json:"kind,omitempty"`
+	RootCommit string    `json:"root_commit,omitempty"`
+	RemoteURL  string    `json:"remote_url,omitempty"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// Identity returns the key used to recognise a repository after it moved,
+// or "" when the repository has no commits to identify it by
+func (e InventoryEntry) Identity() string {
+	if e.RootCommit == "" {
+		return ""
+	}
+	return e.RootCommit + " " + filter.NormalizeRemote(e.RemoteURL)
+}
+
+// InventoryMove records a repository that was relocated
+type InventoryMove struct {
+	From InventoryEntry `json:"from"`
+	To   InventoryEntry `json:"to"`
+}
+
+// LoadInventory returns all repositories recorded by previous scans
+func (c *Cache) LoadInventory() ([]InventoryEntry, error) {
+	rows, err := c.db.Query(`
+		SELECT path, name, kind, root_commit, remote_url, first_seen, last_seen
+		FROM repository_inventory
+		ORDER BY path
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("读取仓库清单失败: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []InventoryEntry
+	for rows.Next() {
+		var entry InventoryEntry
+		var kind, rootCommit, remoteURL sql.NullString
+		if err := rows.Scan(&entry.Path, &entry.Name, &kind, &rootCommit, &remoteURL, &entry.FirstSeen, &entry.LastSeen); err != nil {
+			return nil, fmt.Errorf("读取仓库清单失败: %w", err)
+		}
+		entry.Kind = kind.String
+		entry.RootCommit = rootCommit.String
+		entry.RemoteURL = remoteURL.String
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// SaveInventory records a scan: seen entries are upserted, removed entries are
+// dropped and moved repositories take their cached descriptions and metadata along
+func (c *Cache) SaveInventory(seen, removed []InventoryEntry, moved []InventoryMove) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, move := range moved {
+		if err := c.moveRepository(tx, move.From.Path, move.To.Path, move.To.Name); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM repository_inventory WHERE path = ?", move.From.Path); err != nil {
+			return fmt.Errorf("更新仓库清单失败: %w", err)
+		}
+	}
+
+	for _, entry := range removed {
+		if _, err := tx.Exec("DELETE FROM repository_inventory WHERE path = ?", entry.Path); err != nil {
+			return fmt.Errorf("更新仓库清单失败: %w", err)
+		}
+	}
+
+	for _, entry := range seen {
+		_, err := tx.Exec(`
+			INSERT INTO repository_inventory (path, name, kind, root_commit, remote_url, identity, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(path) DO UPDATE SET
+				name = excluded.name,
+				kind = excluded.kind,
+				root_commit = excluded.root_commit,
+				remote_url = excluded.remote_url,
+				identity = excluded.identity,
+				last_seen = CURRENT_TIMESTAMP
+		`, entry.Path, entry.Name, entry.Kind, entry.RootCommit, entry.RemoteURL, entry.Identity(), firstSeen(entry))
+		if err != nil {
+			return fmt.Errorf("更新仓库清单失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// moveRepository re-keys the cached rows of a repository to its new path.
+// Tags added under the new path before the move was detected are kept.
+func (c *Cache) moveRepository(tx *sql.Tx, from, to, name string) error {
+	if _, err := tx.Exec("DELETE FROM repository_commit_dates WHERE path = ?", to); err != nil {
+		return fmt.Errorf("迁移仓库缓存失败: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE repository_commit_dates SET path = ? WHERE path = ?", to, from); err != nil {
+		return fmt.Errorf("迁移仓库缓存失败: %w", err)
+	}
+
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM repositories WHERE path = ?", from).Scan(&exists); err != nil {
+		return fmt.Errorf("查询仓库缓存失败: %w", err)
+	}
+	if exists == 0 {
+		return nil
+	}
+
+	// 新路径上可能已有后来生成的缓存，以迁移过来的为准，但保留其中的标签
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO repository_tags (repository_id, tag)
+		SELECT (SELECT id FROM repositories WHERE path = ?), rt.tag
+		FROM repository_tags rt
+		JOIN repositories r ON r.id = rt.repository_id
+		WHERE r.path = ?
+	`, from, to); err != nil {
+		return fmt.Errorf("迁移仓库标签失败: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM repositories WHERE path = ?", to); err != nil {
+		return fmt.Errorf("迁移仓库缓存失败: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE repositories SET path = ?, name = ?, updated_at = CURRENT_TIMESTAMP WHERE path = ?", to, name, from); err != nil {
+		return fmt.Errorf("迁移仓库缓存失败: %w", err)
+	}
+
+	c.logger.Debugf("已迁移仓库缓存: %s -> %s", from, to)
+	return nil
+}
+
+// firstSeen returns the entry's first-seen time, defaulting to now
+func firstSeen(entry InventoryEntry) time.Time {
+	if entry.FirstSeen.IsZero() {
+		return time.Now()
+	}
+	return entry.FirstSeen
+}
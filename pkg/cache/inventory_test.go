@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveInventoryMovesTags(t *testing.T) {
+	c, err := NewCache(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("NewCache() error: %v", err)
+	}
+	defer c.Close()
+	mc := c.GetMetadataCache()
+
+	from := InventoryEntry{Path: "/old/api", Name: "api", RootCommit: "abc"}
+	to := InventoryEntry{Path: "/new/api", Name: "api", RootCommit: "abc"}
+	if err := c.SaveInventory([]InventoryEntry{from}, nil, nil); err != nil {
+		t.Fatalf("SaveInventory() error: %v", err)
+	}
+	if err := mc.AddTags(from.Path, []string{"backend", "go"}); err != nil {
+		t.Fatalf("AddTags() error: %v", err)
+	}
+	// 移动被检测到之前，在新路径上添加的标签
+	if err := mc.AddTags(to.Path, []string{"prod"}); err != nil {
+		t.Fatalf("AddTags() error: %v", err)
+	}
+
+	if err := c.SaveInventory([]InventoryEntry{to}, nil, []InventoryMove{{From: from, To: to}}); err != nil {
+		t.Fatalf("SaveInventory() error: %v", err)
+	}
+
+	if got, want := mc.Tags(to.Path), []string{"backend", "go", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags(%s) = %q, want %q", to.Path, got, want)
+	}
+	if got := mc.Tags(from.Path); len(got) != 0 {
+		t.Errorf("Tags(%s) = %q, want none", from.Path, got)
+	}
+
+	entries, err := c.LoadInventory()
+	if err != nil {
+		t.Fatalf("LoadInventory() error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != to.Path {
+		t.Errorf("LoadInventory() = %+v, want only %s", entries, to.Path)
+	}
+}
@@ -0,0 +1,260 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// JournalEntry is the state of one repository recorded by an update run
+type JournalEntry struct {
+	ID         int64        `json:"id"`
+	Path       string       `json:"path"`
+	Name       string       `json:"name"`
+	Branch     string       `json:"branch,omitempty"`
+	HeadBefore string       `json:"head_before"`
+	HeadAfter  string       `json:"head_after,omitempty"`
+	Operation  string       `json:"operation,omitempty"` // 拉取中断后留下的未完成操作 (rebase、merge)
+	Status     string       `json:"status,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	UndoneAt   time.Time    `json:"undone_at,omitempty"`
+	Refs       []JournalRef `json:"refs,omitempty"` // --all-branches 快进的其他分支
+}
+
+// JournalRef is a ref an update run fast-forwarded without checking it out
+type JournalRef struct {
+	Ref string `json:"ref"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// Changed reports whether the run moved the repository's HEAD or other refs, or left an operation unfinished
+func (e JournalEntry) Changed() bool {
+	return e.HeadChanged() || len(e.Refs) > 0
+}
+
+// HeadChanged reports whether the run moved the checked out branch or left an operation unfinished
+func (e JournalEntry) HeadChanged() bool {
+	return e.Operation != "" || (e.HeadAfter != "" && e.HeadAfter != e.HeadBefore)
+}
+
+// Undone reports whether the entry was already restored
+func (e JournalEntry) Undone() bool {
+	return !e.UndoneAt.IsZero()
+}
+
+// UpdateRun is a recorded update run with the state of each repository it pulled
+type UpdateRun struct {
+	ID         int64          `json:"id"`
+	Directory  string         `json:"directory"`
+	Strategy   string         `json:"strategy"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at,omitempty"`
+	Entries    []JournalEntry `json:"entries"`
+}
+
+// UpdateJournal records the repositories of one update run. It is safe for concurrent use.
+type UpdateJournal struct {
+	cache *Cache
+	runID int64
+	mu    sync.Mutex // SQLite 同一时间只允许一个写入
+}
+
+// StartUpdateRun creates a new update run and returns the journal to record it in
+func (c *Cache) StartUpdateRun(directory, strategy string) (*UpdateJournal, error) {
+	res, err := c.db.Exec(`
+		INSERT INTO update_runs (directory, strategy, started_at) VALUES (?, ?, ?)
+	`, directory, strategy, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("创建更新记录失败: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("创建更新记录失败: %w", err)
+	}
+	return &UpdateJournal{cache: c, runID: id}, nil
+}
+
+// RunID returns the id of the run being recorded
+func (j *UpdateJournal) RunID() int64 {
+	return j.runID
+}
+
+// RecordBefore records the branch and HEAD of a repository before it is pulled
+func (j *UpdateJournal) RecordBefore(repoPath, name, branch, head string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, err := j.cache.db.Exec(`
+		INSERT OR REPLACE INTO update_journal (run_id, path, name, branch, head_before)
+		VALUES (?, ?, ?, ?, ?)
+	`, j.runID, repoPath, name, branch, head)
+	if err != nil {
+		return fmt.Errorf("写入更新日志失败: %w", err)
+	}
+	return nil
+}
+
+// RecordResult records the HEAD and outcome of a repository after it was pulled.
+// operation is the rebase or merge the pull left unfinished, or "".
+func (j *UpdateJournal) RecordResult(repoPath, head, operation, status, message string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, err := j.cache.db.Exec(`
+		UPDATE update_journal SET head_after = ?, operation = ?, status = ?, message = ?
+		WHERE run_id = ? AND path = ?
+	`, head, operation, status, message, j.runID, repoPath)
+	if err != nil {
+		return fmt.Errorf("写入更新日志失败: %w", err)
+	}
+	return nil
+}
+
+// RecordRef records a ref of a journaled repository that was moved from old to new
+func (j *UpdateJournal) RecordRef(repoPath, ref, old, new string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, err := j.cache.db.Exec(`
+		INSERT OR REPLACE INTO update_journal_refs (entry_id, ref, old_value, new_value)
+		SELECT id, ?, ?, ? FROM update_journal WHERE run_id = ? AND path = ?
+	`, ref, old, new, j.runID, repoPath)
+	if err != nil {
+		return fmt.Errorf("写入更新日志失败: %w", err)
+	}
+	return nil
+}
+
+// Finish marks the run as finished
+func (j *UpdateJournal) Finish() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.cache.db.Exec("UPDATE update_runs SET finished_at = ? WHERE id = ?", time.Now(), j.runID); err != nil {
+		return fmt.Errorf("写入更新记录失败: %w", err)
+	}
+	return nil
+}
+
+// RecentUpdateRuns returns the latest update runs with their entries, newest first
+func (c *Cache) RecentUpdateRuns(limit int) ([]UpdateRun, error) {
+	rows, err := c.db.Query(`
+		SELECT id FROM update_runs ORDER BY id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("读取更新记录失败: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("读取更新记录失败: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取更新记录失败: %w", err)
+	}
+
+	runs := make([]UpdateRun, 0, len(ids))
+	for _, id := range ids {
+		run, err := c.UpdateRun(id)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
+
+// UpdateRun returns a recorded update run with its entries
+func (c *Cache) UpdateRun(id int64) (*UpdateRun, error) {
+	run := &UpdateRun{ID: id}
+	var directory, strategy sql.NullString
+	var finished sql.NullTime
+	err := c.db.QueryRow(`
+		SELECT directory, strategy, started_at, finished_at FROM update_runs WHERE id = ?
+	`, id).Scan(&directory, &strategy, &run.StartedAt, &finished)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("更新记录不存在: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取更新记录失败: %w", err)
+	}
+	run.Directory = directory.String
+	run.Strategy = strategy.String
+	run.FinishedAt = finished.Time
+
+	rows, err := c.db.Query(`
+		SELECT id, path, name, branch, head_before, head_after, operation, status, message, undone_at
+		FROM update_journal
+		WHERE run_id = ?
+		ORDER BY name, path
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("读取更新日志失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry JournalEntry
+		var branch, headAfter, operation, status, message sql.NullString
+		var undone sql.NullTime
+		if err := rows.Scan(&entry.ID, &entry.Path, &entry.Name, &branch, &entry.HeadBefore, &headAfter, &operation, &status, &message, &undone); err != nil {
+			return nil, fmt.Errorf("读取更新日志失败: %w", err)
+		}
+		entry.Branch = branch.String
+		entry.HeadAfter = headAfter.String
+		entry.Operation = operation.String
+		entry.Status = status.String
+		entry.Message = message.String
+		entry.UndoneAt = undone.Time
+		run.Entries = append(run.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取更新日志失败: %w", err)
+	}
+	rows.Close()
+
+	for i := range run.Entries {
+		refs, err := c.journalRefs(run.Entries[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		run.Entries[i].Refs = refs
+	}
+	return run, nil
+}
+
+// journalRefs returns the refs recorded for a journal entry
+func (c *Cache) journalRefs(entryID int64) ([]JournalRef, error) {
+	rows, err := c.db.Query(`
+		SELECT ref, old_value, new_value FROM update_journal_refs WHERE entry_id = ? ORDER BY ref
+	`, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("读取更新日志失败: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []JournalRef
+	for rows.Next() {
+		var ref JournalRef
+		if err := rows.Scan(&ref.Ref, &ref.Old, &ref.New); err != nil {
+			return nil, fmt.Errorf("读取更新日志失败: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// MarkJournalUndone records that a journal entry was restored
+func (c *Cache) MarkJournalUndone(entryID int64) error {
+	if _, err := c.db.Exec("UPDATE update_journal SET undone_at = ? WHERE id = ?", time.Now(), entryID); err != nil {
+		return fmt.Errorf("写入更新日志失败: %w", err)
+	}
+	return nil
+}
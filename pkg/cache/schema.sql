@@ -118,10 +118,95 @@ CREATE TABLE IF NOT EXISTS repository_dependencies (
     FOREIGN KEY (repository_id) REFERENCES repositories (id) ON DELETE CASCADE
 );
 
+-- 仓库清单：记录上次扫描到的仓库，用于识别新增、删除和移动
+CREATE TABLE IF NOT EXISTS repository_inventory (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    path TEXT NOT NULL UNIQUE,                 -- 仓库绝对路径
+    name TEXT NOT NULL,                        -- 仓库名称
+    kind TEXT,                                 -- 仓库类型：normal, worktree, submodule, bare
+    root_commit TEXT,                          -- 根提交哈希
+    remote_url TEXT,                           -- origin 远程地址
+    identity TEXT,                             -- 根提交 + 归一化远程地址，用于识别移动
+    first_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_seen DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 提交时间缓存：按 HEAD 哈希失效，用于 list --sort-by-time
+CREATE TABLE IF NOT EXISTS repository_commit_dates (
+    path TEXT PRIMARY KEY,                     -- 仓库绝对路径
+    head TEXT NOT NULL,                        -- 计算时的 HEAD 哈希
+    committer_date TEXT,                       -- HEAD 提交时间 (RFC3339)
+    author_date TEXT,                          -- HEAD 作者时间 (RFC3339)
+    latest_branch_date TEXT,                   -- 本地分支最新提交时间 (RFC3339)
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 更新记录：每次 update 运行一条，用于 update undo
+CREATE TABLE IF NOT EXISTS update_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    directory TEXT,                            -- 更新的目录或清单
+    strategy TEXT,                             -- 拉取策略：ff-only, merge, rebase
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME
+);
+
+-- 更新日志：拉取前记录每个仓库的分支和 HEAD
+CREATE TABLE IF NOT EXISTS update_journal (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    path TEXT NOT NULL,                        -- 仓库绝对路径
+    name TEXT NOT NULL,                        -- 仓库名称
+    branch TEXT,                               -- 拉取时所在的分支
+    head_before TEXT NOT NULL,                 -- 拉取前的 HEAD
+    head_after TEXT,                           -- 拉取后的 HEAD
+    operation TEXT,                            -- 拉取中断后留下的未完成操作：rebase, merge
+    status TEXT,                               -- 更新结果：success, skipped, failed
+    message TEXT,
+    undone_at DATETIME,                        -- 撤销时间
+    FOREIGN KEY (run_id) REFERENCES update_runs (id) ON DELETE CASCADE,
+    UNIQUE (run_id, path)
+);
+
+-- 更新日志：--all-branches 快进的其他本地分支
+CREATE TABLE IF NOT EXISTS update_journal_refs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entry_id INTEGER NOT NULL,
+    ref TEXT NOT NULL,                         -- 完整引用名，如 refs/heads/develop
+    old_value TEXT NOT NULL,                   -- 快进前的提交
+    new_value TEXT NOT NULL,                   -- 快进后的提交
+    FOREIGN KEY (entry_id) REFERENCES update_journal (id) ON DELETE CASCADE,
+    UNIQUE (entry_id, ref)
+);
+
+-- 工作区快照：保存时每个仓库所在的分支和提交，用于 snapshot restore
+CREATE TABLE IF NOT EXISTS snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    directory TEXT,                            -- 保存快照时的根目录
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS snapshot_repos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    snapshot_id INTEGER NOT NULL,
+    path TEXT NOT NULL,                        -- 相对于快照根目录的路径
+    name TEXT NOT NULL,                        -- 仓库名称
+    branch TEXT,                               -- 所在的分支，分离 HEAD 时为空
+    commit_hash TEXT NOT NULL,                 -- HEAD 提交
+    remote_url TEXT,                           -- origin 远程地址
+    dirty BOOLEAN DEFAULT 0,                   -- 保存时工作区有未提交的变更
+    FOREIGN KEY (snapshot_id) REFERENCES snapshots (id) ON DELETE CASCADE,
+    UNIQUE (snapshot_id, path)
+);
+
 -- 索引优化
 CREATE INDEX IF NOT EXISTS idx_repositories_path ON repositories (path);
 CREATE INDEX IF NOT EXISTS idx_repositories_readme_hash ON repositories (readme_hash);
 CREATE INDEX IF NOT EXISTS idx_repositories_updated_at ON repositories (updated_at);
+CREATE INDEX IF NOT EXISTS idx_repository_inventory_identity ON repository_inventory (identity);
+CREATE INDEX IF NOT EXISTS idx_update_journal_run_id ON update_journal (run_id);
+CREATE INDEX IF NOT EXISTS idx_update_journal_refs_entry_id ON update_journal_refs (entry_id);
+CREATE INDEX IF NOT EXISTS idx_snapshot_repos_snapshot_id ON snapshot_repos (snapshot_id);
 CREATE INDEX IF NOT EXISTS idx_repository_tags_repo_id ON repository_tags (repository_id);
 CREATE INDEX IF NOT EXISTS idx_repository_languages_repo_id ON repository_languages (repository_id);
 CREATE INDEX IF NOT EXISTS idx_repository_languages_language ON repository_languages (language);
@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SnapshotRepo is the recorded state of one repository in a workspace snapshot
+type SnapshotRepo struct {
+	Path      string `json:"path"` // 相对于快照根目录，使用 "/" 分隔
+	Name      string `json:"name"`
+	Branch    string `json:"branch,omitempty"` // 分离 HEAD 时为空
+	Commit    string `json:"commit"`
+	RemoteURL string `json:"remote_url,omitempty"`
+	Dirty     bool   `json:"dirty,omitempty"` // 保存时工作区有未提交的变更，快照无法完全复现
+}
+
+// Snapshot records the branch and commit of every repository in a workspace
+type Snapshot struct {
+	Name      string         `json:"name"`
+	Directory string         `json:"directory"`
+	CreatedAt time.Time      `json:"created_at"`
+	Repos     []SnapshotRepo `json:"repos"`
+}
+
+// SnapshotInfo summarizes a stored snapshot
+type SnapshotInfo struct {
+	Name      string    `json:"name"`
+	Directory string    `json:"directory"`
+	CreatedAt time.Time `json:"created_at"`
+	RepoCount int       `json:"repo_count"`
+}
+
+// SaveSnapshot stores a snapshot. An existing snapshot with the same name is
+// replaced only when replace is set.
+func (c *Cache) SaveSnapshot(snapshot *Snapshot, replace bool) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var existing int64
+	err = tx.QueryRow("SELECT id FROM snapshots WHERE name = ?", snapshot.Name).Scan(&existing)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return fmt.Errorf("读取快照失败: %w", err)
+	case !replace:
+		return fmt.Errorf("快照已存在: %s", snapshot.Name)
+	default:
+		if _, err := tx.Exec("DELETE FROM snapshots WHERE id = ?", existing); err != nil {
+			return fmt.Errorf("删除旧快照失败: %w", err)
+		}
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO snapshots (name, directory, created_at) VALUES (?, ?, ?)
+	`, snapshot.Name, snapshot.Directory, snapshot.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("保存快照失败: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("保存快照失败: %w", err)
+	}
+
+	for _, repo := range snapshot.Repos {
+		_, err := tx.Exec(`
+			INSERT INTO snapshot_repos (snapshot_id, path, name, branch, commit_hash, remote_url, dirty)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, id, repo.Path, repo.Name, repo.Branch, repo.Commit, repo.RemoteURL, repo.Dirty)
+		if err != nil {
+			return fmt.Errorf("保存快照失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// Snapshot returns a stored snapshot with its repositories
+func (c *Cache) Snapshot(name string) (*Snapshot, error) {
+	snapshot := &Snapshot{Name: name}
+	var id int64
+	var directory sql.NullString
+	err := c.db.QueryRow(`
+		SELECT id, directory, created_at FROM snapshots WHERE name = ?
+	`, name).Scan(&id, &directory, &snapshot.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("快照不存在: %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取快照失败: %w", err)
+	}
+	snapshot.Directory = directory.String
+
+	rows, err := c.db.Query(`
+		SELECT path, name, branch, commit_hash, remote_url, dirty
+		FROM snapshot_repos
+		WHERE snapshot_id = ?
+		ORDER BY path
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("读取快照失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var repo SnapshotRepo
+		var branch, remoteURL sql.NullString
+		if err := rows.Scan(&repo.Path, &repo.Name, &branch, &repo.Commit, &remoteURL, &repo.Dirty); err != nil {
+			return nil, fmt.Errorf("读取快照失败: %w", err)
+		}
+		repo.Branch = branch.String
+		repo.RemoteURL = remoteURL.String
+		snapshot.Repos = append(snapshot.Repos, repo)
+	}
+	return snapshot, rows.Err()
+}
+
+// ListSnapshots returns the stored snapshots, newest first
+func (c *Cache) ListSnapshots() ([]SnapshotInfo, error) {
+	rows, err := c.db.Query(`
+		SELECT s.name, s.directory, s.created_at, COUNT(r.id)
+		FROM snapshots s LEFT JOIN snapshot_repos r ON r.snapshot_id = s.id
+		GROUP BY s.id
+		ORDER BY s.created_at DESC, s.id DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("读取快照失败: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []SnapshotInfo
+	for rows.Next() {
+		var info SnapshotInfo
+		var directory sql.NullString
+		if err := rows.Scan(&info.Name, &directory, &info.CreatedAt, &info.RepoCount); err != nil {
+			return nil, fmt.Errorf("读取快照失败: %w", err)
+		}
+		info.Directory = directory.String
+		snapshots = append(snapshots, info)
+	}
+	return snapshots, rows.Err()
+}
+
+// DeleteSnapshot removes a stored snapshot
+func (c *Cache) DeleteSnapshot(name string) error {
+	res, err := c.db.Exec("DELETE FROM snapshots WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("删除快照失败: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("快照不存在: %s", name)
+	}
+	return nil
+}
+
+// WriteSnapshotFile exports a snapshot as JSON so that it can be shared or kept in version control
+func WriteSnapshotFile(filename string, snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("写入快照文件失败: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshotFile loads a snapshot exported by WriteSnapshotFile
+func ReadSnapshotFile(filename string) (*Snapshot, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("读取快照文件失败: %w", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("解析快照文件失败: %w", err)
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+)
+
+// MainLanguage returns the cached main language of a repository, or "" if it was never analyzed
+func (mc *MetadataCache) MainLanguage(repoPath string) string {
+	var language sql.NullString
+	err := mc.cache.db.QueryRow(`
+		SELECT rm.main_language
+		FROM repository_metadata rm
+		JOIN repositories r ON r.id = rm.repository_id
+		WHERE r.path = ?
+	`, repoPath).Scan(&language)
+	if err != nil {
+		return ""
+	}
+	return language.String
+}
+
+// Tags returns the tags recorded for a repository
+func (mc *MetadataCache) Tags(repoPath string) []string {
+	rows, err := mc.cache.db.Query(`
+		SELECT rt.tag
+		FROM repository_tags rt
+		JOIN repositories r ON r.id = rt.repository_id
+		WHERE r.path = ?
+		ORDER BY rt.tag
+	`, repoPath)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// AddTags attaches tags to a repository, creating its record if needed
+func (mc *MetadataCache) AddTags(repoPath string, tags []string) error {
+	tx, err := mc.cache.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	repoID, err := mc.getOrCreateRepository(tx, repoPath, filepath.Base(repoPath))
+	if err != nil {
+		return fmt.Errorf("获取仓库记录失败: %w", err)
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO repository_tags (repository_id, tag) VALUES (?, ?)", repoID, tag); err != nil {
+			return fmt.Errorf("保存标签失败: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RemoveTags detaches tags from a repository
+func (mc *MetadataCache) RemoveTags(repoPath string, tags []string) error {
+	for _, tag := range tags {
+		_, err := mc.cache.db.Exec(`
+			DELETE FROM repository_tags
+			WHERE tag = ? AND repository_id IN (SELECT id FROM repositories WHERE path = ?)
+		`, tag, repoPath)
+		if err != nil {
+			return fmt.Errorf("删除标签失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// AllTags returns the tags of every tagged repository keyed by path
+func (mc *MetadataCache) AllTags() (map[string][]string, error) {
+	rows, err := mc.cache.db.Query(`
+		SELECT r.path, rt.tag
+		FROM repository_tags rt
+		JOIN repositories r ON r.id = rt.repository_id
+		ORDER BY r.path, rt.tag
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询标签失败: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string][]string)
+	for rows.Next() {
+		var path, tag string
+		if err := rows.Scan(&path, &tag); err != nil {
+			return nil, fmt.Errorf("读取标签失败: %w", err)
+		}
+		result[path] = append(result[path], tag)
+	}
+	return result, rows.Err()
+}
@@ -1,13 +1,14 @@
 package changelog
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"reposense/pkg/git"
 	"reposense/pkg/llm"
 	"reposense/pkg/reporter"
 	"reposense/pkg/scanner"
@@ -18,6 +19,7 @@ import (
 // ChangelogAnalyzer 变更日志分析器
 type ChangelogAnalyzer struct {
 	scanner    *scanner.Scanner
+	runner     git.Runner
 	llmService *llm.DescriptionService
 	reporter   *reporter.Reporter
 	workers    int
@@ -27,8 +29,17 @@ type ChangelogAnalyzer struct {
 
 // NewChangelogAnalyzer 创建新的分析器实例
 func NewChangelogAnalyzer(opts ChangelogOptions) *ChangelogAnalyzer {
+	return NewChangelogAnalyzerWithRunner(opts, git.NewExecRunner())
+}
+
+// NewChangelogAnalyzerWithRunner 创建通过指定runner执行git命令的分析器实例
+func NewChangelogAnalyzerWithRunner(opts ChangelogOptions, runner git.Runner) *ChangelogAnalyzer {
 	// 初始化扫描器
 	scannerInstance := scanner.NewScanner()
+	scannerInstance.SetScanOptions(opts.ScanOptions)
+	if opts.Metadata != nil {
+		scannerInstance.SetMetadataSource(opts.Metadata)
+	}
 
 	// 初始化LLM服务（如果启用）
 	var llmService *llm.DescriptionService
@@ -55,6 +66,7 @@ func NewChangelogAnalyzer(opts ChangelogOptions) *ChangelogAnalyzer {
 
 	return &ChangelogAnalyzer{
 		scanner:    scannerInstance,
+		runner:     runner,
 		llmService: llmService,
 		reporter:   reporterInstance,
 		workers:    opts.WorkerCount,
@@ -137,17 +149,27 @@ func (a *ChangelogAnalyzer) filterUpdatedRepos(repos []scanner.Repository, timeR
 // hasUpdatesInRange 检查仓库在指定时间范围内是否有更新
 func (a *ChangelogAnalyzer) hasUpdatesInRange(repoPath string, timeRange TimeRange) bool {
 	// 使用git log检查指定时间范围内的提交
-	cmd := exec.Command("git", "log", "--oneline", "--since="+timeRange.Since.Format("2006-01-02T15:04:05"), "--until="+timeRange.Until.Format("2006-01-02T15:04:05"))
-	cmd.Dir = repoPath
+	ctx, cancel := a.commandContext()
+	defer cancel()
 
-	output, err := cmd.Output()
+	output, err := git.Output(ctx, a.runner, repoPath, "log", "--oneline", "-1",
+		"--since="+timeRange.Since.Format("2006-01-02T15:04:05"),
+		"--until="+timeRange.Until.Format("2006-01-02T15:04:05"))
 	if err != nil {
 		a.logger.Debugf("检查仓库更新失败 %s: %v", repoPath, err)
 		return false
 	}
 
 	// 如果有输出，说明有提交
-	return len(strings.TrimSpace(string(output))) > 0
+	return len(output) > 0
+}
+
+// commandContext 返回单个git命令使用的上下文（应用超时设置）
+func (a *ChangelogAnalyzer) commandContext() (context.Context, context.CancelFunc) {
+	if a.timeout > 0 {
+		return context.WithTimeout(context.Background(), a.timeout)
+	}
+	return context.WithCancel(context.Background())
 }
 
 // analyzeReposParallel 并发分析多个仓库
@@ -226,15 +248,15 @@ func (a *ChangelogAnalyzer) getCommitsInRange(repoPath string, timeRange TimeRan
 		"--until=" + timeRange.Until.Format("2006-01-02T15:04:05"),
 	}
 
-	cmd := exec.Command("git", args...)
-	cmd.Dir = repoPath
+	ctx, cancel := a.commandContext()
+	defer cancel()
 
-	output, err := cmd.Output()
+	result, err := a.runner.Run(ctx, repoPath, args...)
 	if err != nil {
 		return nil, fmt.Errorf("执行git log失败: %w", err)
 	}
 
-	return a.parseGitLog(string(output))
+	return a.parseGitLog(result.Stdout)
 }
 
 // parseGitLog 解析git log输出
@@ -301,13 +323,13 @@ func (a *ChangelogAnalyzer) generateStats(repoPath string, commits []Commit, tim
 	}
 
 	// 获取文件变更统计
-	cmd := exec.Command("git", "diff", "--stat",
-		"--since="+timeRange.Since.Format("2006-01-02T15:04:05"),
-		"--until="+timeRange.Until.Format("2006-01-02T15:04:05"))
-	cmd.Dir = repoPath
+	ctx, cancel := a.commandContext()
+	defer cancel()
 
-	if output, err := cmd.Output(); err == nil {
-		stats.FilesChanged, stats.Insertions, stats.Deletions = a.parseGitDiffStat(string(output))
+	if output, err := git.Output(ctx, a.runner, repoPath, "diff", "--stat",
+		"--since="+timeRange.Since.Format("2006-01-02T15:04:05"),
+		"--until="+timeRange.Until.Format("2006-01-02T15:04:05")); err == nil {
+		stats.FilesChanged, stats.Insertions, stats.Deletions = a.parseGitDiffStat(output)
 	}
 
 	// 识别重大变更
@@ -36,7 +36,7 @@ func reportChangelogText(report *ChangelogReport, verbose bool) {
 		return
 	}
 
-	fmt.Println("## 📊 更新概览\n")
+	fmt.Print("## 📊 更新概览\n\n")
 
 	for i, entry := range report.Entries {
 		fmt.Printf("### %d. %s\n", i+1, entry.Repository.Name)
@@ -92,7 +92,7 @@ func reportChangelogText(report *ChangelogReport, verbose bool) {
 			}
 		}
 
-		fmt.Println("\n---\n")
+		fmt.Print("\n---\n\n")
 	}
 
 	// 显示总体统计
@@ -93,6 +93,8 @@ type ChangelogOptions struct {
 	LLMTimeout      time.Duration
 	IncludePatterns []string
 	ExcludePatterns []string
+	ScanOptions     scanner.ScanOptions
+	Metadata        scanner.MetadataSource // lang: 和 tag: 过滤使用的缓存元数据，可为空
 	OutputFormat    reporter.ReportFormat
 	SaveReport      bool
 	ReportFile      string
@@ -0,0 +1,135 @@
+// Package filter implements the repository filter expressions accepted by
+// --include and --exclude.
+//
+// An expression combines terms with AND, OR, NOT (or &&, ||, !) and
+// parentheses; adjacent terms are joined with AND. Supported terms:
+//
+//	backend            repository name contains "backend"
+//	api-*              glob against the repository name
+//	work/*/svc         glob against the trailing components of the path
+//	path:**/go/**      any repository below a directory named "go"
+//	/home/me/src/**    glob anchored at the absolute path (~/ is expanded)
+//	re:^go-.*$         regular expression against the name or the full path
+//	name:, path:       glob against the whole name or the full path
+//	remote:github.com/myorg/*   glob against normalized remote URLs
+//	branch:main        glob against the checked-out branch
+//	lang:Go            main language from cached metadata
+//	tag:backend        tags recorded for the repository
+//	kind:worktree      repository kind (normal, worktree, submodule, bare)
+//
+// Glob and attribute matches are case-insensitive.
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Candidate is the repository information a filter is evaluated against
+type Candidate struct {
+	Name string
+	Path string
+	Kind string
+}
+
+// Resolver supplies attribute values (remote, branch, lang, tag) for a candidate
+type Resolver interface {
+	Resolve(c Candidate, attribute string) []string
+}
+
+// Attribute names that require a Resolver
+const (
+	AttrRemote = "remote"
+	AttrBranch = "branch"
+	AttrLang   = "lang"
+	AttrTag    = "tag"
+)
+
+// Filter holds compiled include and exclude expressions
+type Filter struct {
+	include []Expr
+	exclude []Expr
+}
+
+// New compiles include and exclude expressions. A candidate passes when it
+// matches any include expression (or there are none) and no exclude expression.
+func New(includePatterns, excludePatterns []string) (*Filter, error) {
+	f := &Filter{}
+	for _, pattern := range includePatterns {
+		expr, err := Parse(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的包含模式 %q: %w", pattern, err)
+		}
+		f.include = append(f.include, expr)
+	}
+	for _, pattern := range excludePatterns {
+		expr, err := Parse(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的排除模式 %q: %w", pattern, err)
+		}
+		f.exclude = append(f.exclude, expr)
+	}
+	return f, nil
+}
+
+// Empty reports whether the filter accepts everything
+func (f *Filter) Empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// Match evaluates the filter; r may be nil when no attribute terms are used
+func (f *Filter) Match(c Candidate, r Resolver) bool {
+	ctx := &evalContext{candidate: c, resolver: r}
+
+	for _, expr := range f.exclude {
+		if expr.eval(ctx) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+
+	for _, expr := range f.include {
+		if expr.eval(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesAttribute reports whether any of the patterns references one of the attributes
+func UsesAttribute(patterns []string, attributes ...string) bool {
+	for _, pattern := range patterns {
+		for _, attribute := range attributes {
+			if strings.Contains(pattern, attribute+":") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// evalContext caches resolved attributes while evaluating one candidate
+type evalContext struct {
+	candidate Candidate
+	resolver  Resolver
+	resolved  map[string][]string
+}
+
+// attribute resolves and memoizes an attribute for the current candidate
+func (ctx *evalContext) attribute(name string) []string {
+	if values, ok := ctx.resolved[name]; ok {
+		return values
+	}
+	var values []string
+	if ctx.resolver != nil {
+		values = ctx.resolver.Resolve(ctx.candidate, name)
+	}
+	if ctx.resolved == nil {
+		ctx.resolved = make(map[string][]string)
+	}
+	ctx.resolved[name] = values
+	return values
+}
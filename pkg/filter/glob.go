@@ -0,0 +1,119 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// GlobRegex translates a gitignore-style glob into a regular expression body.
+// "*" and "?" do not cross "/", "**/" matches zero or more directories and a
+// trailing "/**" matches everything below.
+func GlobRegex(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				atStart := i == 0 || glob[i-1] == '/'
+				atEnd := i+2 == len(glob)
+				if atStart && atEnd {
+					// "**" 或 "a/**" 匹配其下所有内容
+					sb.WriteString(".*")
+					i++
+					continue
+				}
+				if atStart && glob[i+2] == '/' {
+					// "**/" 匹配零个或多个目录
+					sb.WriteString("(?:.*/)?")
+					i += 2
+					continue
+				}
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				i += writeLiteral(&sb, glob[i:]) - 1
+			}
+		default:
+			i += writeLiteral(&sb, glob[i:]) - 1
+		}
+	}
+	return sb.String()
+}
+
+// writeLiteral writes the first character of s as a quoted literal and
+// returns its length in bytes, so that multi-byte characters stay intact
+func writeLiteral(sb *strings.Builder, s string) int {
+	_, size := utf8.DecodeRuneInString(s)
+	sb.WriteString(regexp.QuoteMeta(s[:size]))
+	return size
+}
+
+// compileGlob compiles a case-insensitive, fully anchored glob
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)^" + GlobRegex(glob) + "$")
+}
+
+// NormalizeRemote reduces a remote URL to "host/path" without scheme,
+// credentials or a ".git" suffix, so that SSH and HTTPS remotes compare equal
+func NormalizeRemote(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return ""
+	}
+
+	if idx := strings.Index(remote, "://"); idx >= 0 {
+		remote = remote[idx+3:]
+		if at := strings.Index(remote, "@"); at >= 0 && at < strings.Index(remote+"/", "/") {
+			remote = remote[at+1:]
+		}
+		// 去掉端口号
+		if slash := strings.Index(remote, "/"); slash >= 0 {
+			host := remote[:slash]
+			if colon := strings.Index(host, ":"); colon >= 0 {
+				host = host[:colon]
+			}
+			remote = host + remote[slash:]
+		}
+	} else if colon := strings.Index(remote, ":"); colon >= 0 && !strings.Contains(remote[:colon], "/") {
+		// scp 风格: git@github.com:org/repo.git
+		host := remote[:colon]
+		if at := strings.Index(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		remote = host + "/" + remote[colon+1:]
+	}
+
+	remote = strings.TrimSuffix(remote, "/")
+	remote = strings.TrimSuffix(remote, ".git")
+	return strings.ToLower(remote)
+}
+
+// RemoteHost returns the host part of a remote URL, or "" for local paths
+func RemoteHost(remote string) string {
+	normalized := NormalizeRemote(remote)
+	if normalized == "" || strings.HasPrefix(normalized, "/") || strings.HasPrefix(normalized, ".") {
+		return ""
+	}
+	if slash := strings.Index(normalized, "/"); slash >= 0 {
+		return normalized[:slash]
+	}
+	return normalized
+}
@@ -0,0 +1,336 @@
+package filter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Expr is a compiled filter expression
+type Expr interface {
+	eval(ctx *evalContext) bool
+}
+
+type andExpr struct{ left, right Expr }
+type orExpr struct{ left, right Expr }
+type notExpr struct{ expr Expr }
+
+func (e andExpr) eval(ctx *evalContext) bool { return e.left.eval(ctx) && e.right.eval(ctx) }
+func (e orExpr) eval(ctx *evalContext) bool  { return e.left.eval(ctx) || e.right.eval(ctx) }
+func (e notExpr) eval(ctx *evalContext) bool { return !e.expr.eval(ctx) }
+
+// nameTerm matches the repository name. Directories in the path are only
+// matched by path terms, so that "go" does not select everything under ~/go/.
+type nameTerm struct{ re *regexp.Regexp }
+
+func (t nameTerm) eval(ctx *evalContext) bool {
+	return t.re.MatchString(ctx.candidate.Name)
+}
+
+// pathTerm matches the slash-separated repository path
+type pathTerm struct{ re *regexp.Regexp }
+
+func (t pathTerm) eval(ctx *evalContext) bool {
+	return t.re.MatchString(filepath.ToSlash(ctx.candidate.Path))
+}
+
+// regexTerm matches the name or the full path with a user-supplied regex
+type regexTerm struct{ re *regexp.Regexp }
+
+func (t regexTerm) eval(ctx *evalContext) bool {
+	return t.re.MatchString(ctx.candidate.Name) || t.re.MatchString(filepath.ToSlash(ctx.candidate.Path))
+}
+
+// attrTerm matches a resolved attribute
+type attrTerm struct {
+	attribute string
+	re        *regexp.Regexp
+}
+
+func (t attrTerm) eval(ctx *evalContext) bool {
+	var values []string
+	switch t.attribute {
+	case "name":
+		values = []string{ctx.candidate.Name}
+	case "kind":
+		kind := ctx.candidate.Kind
+		if kind == "" {
+			kind = "normal"
+		}
+		values = []string{kind}
+	case AttrRemote:
+		for _, remote := range ctx.attribute(AttrRemote) {
+			values = append(values, NormalizeRemote(remote))
+		}
+	default:
+		values = ctx.attribute(t.attribute)
+	}
+
+	for _, value := range values {
+		if t.re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse compiles a single filter expression
+func Parse(input string) (Expr, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("表达式为空")
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("多余的内容: %s", p.tokens[p.pos].text)
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits an expression into words, operators and parentheses.
+// Parentheses inside a word (e.g. a regex) are kept as long as they balance.
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")"})
+			i++
+		case c == '!' && !strings.HasPrefix(input[i:], "!="):
+			tokens = append(tokens, token{tokNot, "!"})
+			i++
+		case strings.HasPrefix(input[i:], "&&"):
+			tokens = append(tokens, token{tokAnd, "&&"})
+			i += 2
+		case strings.HasPrefix(input[i:], "||"):
+			tokens = append(tokens, token{tokOr, "||"})
+			i += 2
+		case c == '"':
+			end := strings.IndexByte(input[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("引号未闭合")
+			}
+			tokens = append(tokens, token{tokWord, input[i+1 : i+1+end]})
+			i += end + 2
+		default:
+			start, depth := i, 0
+			for i < len(input) {
+				ch := input[i]
+				if ch == ' ' || ch == '\t' {
+					break
+				}
+				if ch == '(' {
+					depth++
+				} else if ch == ')' {
+					if depth == 0 {
+						break
+					}
+					depth--
+				}
+				i++
+			}
+			word := input[start:i]
+			switch word {
+			case "AND":
+				tokens = append(tokens, token{tokAnd, word})
+			case "OR":
+				tokens = append(tokens, token{tokOr, word})
+			case "NOT":
+				tokens = append(tokens, token{tokNot, word})
+			default:
+				tokens = append(tokens, token{tokWord, word})
+			}
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+// parseOr: and ("OR" and)*
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok != nil && tok.kind == tokOr; tok = p.peek() {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd: unary (["AND"] unary)*
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok != nil && tok.kind != tokOr && tok.kind != tokRParen; tok = p.peek() {
+		if tok.kind == tokAnd {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+// parseUnary: "NOT" unary | "(" or ")" | term
+func (p *parser) parseUnary() (Expr, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, fmt.Errorf("表达式不完整")
+	}
+
+	switch tok.kind {
+	case tokNot:
+		p.pos++
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	case tokLParen:
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next := p.peek(); next == nil || next.kind != tokRParen {
+			return nil, fmt.Errorf("缺少右括号")
+		}
+		p.pos++
+		return expr, nil
+	case tokWord:
+		p.pos++
+		return parseTerm(tok.text)
+	default:
+		return nil, fmt.Errorf("意外的符号: %s", tok.text)
+	}
+}
+
+// parseTerm compiles a single word into a term
+func parseTerm(word string) (Expr, error) {
+	if key, value, found := strings.Cut(word, ":"); found && isAttribute(key) {
+		if value == "" {
+			return nil, fmt.Errorf("%s: 缺少匹配值", key)
+		}
+		switch key {
+		case "re":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("无效的正则表达式 %q: %w", value, err)
+			}
+			return regexTerm{re}, nil
+		case "path":
+			return compilePathTerm(value)
+		case AttrRemote:
+			if strings.Contains(value, "://") || strings.Contains(value, "@") {
+				value = NormalizeRemote(value)
+			}
+		}
+		re, err := compileGlob(value)
+		if err != nil {
+			return nil, fmt.Errorf("无效的模式 %q: %w", value, err)
+		}
+		return attrTerm{attribute: key, re: re}, nil
+	}
+
+	if strings.HasPrefix(word, "/") || strings.HasPrefix(word, "~/") || strings.Contains(word, "/") {
+		return compilePathTerm(word)
+	}
+
+	// 不含通配符的裸词按子串匹配仓库名，需要精确匹配时使用 name:
+	glob := word
+	if !strings.ContainsAny(word, "*?[") {
+		glob = "*" + word + "*"
+	}
+	re, err := compileGlob(glob)
+	if err != nil {
+		return nil, fmt.Errorf("无效的模式 %q: %w", word, err)
+	}
+	return nameTerm{re}, nil
+}
+
+// compilePathTerm compiles an absolute ("/", "~/") or trailing-component path glob
+func compilePathTerm(glob string) (Expr, error) {
+	if strings.HasPrefix(glob, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			glob = filepath.ToSlash(home) + glob[1:]
+		}
+	}
+	glob = strings.TrimSuffix(glob, "/")
+
+	var pattern string
+	if strings.HasPrefix(glob, "/") {
+		pattern = "(?i)^" + GlobRegex(glob) + "$"
+	} else {
+		pattern = "(?i)(?:^|/)" + GlobRegex(glob) + "$"
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的路径模式 %q: %w", glob, err)
+	}
+	return pathTerm{re}, nil
+}
+
+// isAttribute reports whether key is a recognised "key:" prefix
+func isAttribute(key string) bool {
+	switch key {
+	case "re", "name", "path", "kind", AttrRemote, AttrBranch, AttrLang, AttrTag:
+		return true
+	}
+	return false
+}
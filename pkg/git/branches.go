@@ -0,0 +1,125 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BranchInfo describes a local branch and its relation to its upstream
+type BranchInfo struct {
+	Name           string    `json:"name"`
+	Commit         string    `json:"commit"`
+	Current        bool      `json:"current"`
+	Upstream       string    `json:"upstream,omitempty"`
+	UpstreamGone   bool      `json:"upstream_gone,omitempty"` // 上游分支已在远程删除
+	Ahead          int       `json:"ahead"`
+	Behind         int       `json:"behind"`
+	LastCommitDate time.Time `json:"last_commit_date"`
+	Merged         bool      `json:"merged"`             // 已合并到默认分支
+	Worktree       string    `json:"worktree,omitempty"` // 检出该分支的工作树路径
+}
+
+// HasUnpushedWork reports whether the branch holds commits that exist nowhere
+// else: it is ahead of its upstream, or it has no usable upstream and is not merged
+func (b BranchInfo) HasUnpushedWork() bool {
+	if b.Ahead > 0 {
+		return true
+	}
+	return (b.Upstream == "" || b.UpstreamGone) && !b.Merged
+}
+
+// ListBranches returns all local branches with upstream tracking information.
+// Merged is computed against defaultBranch and left false when it is empty.
+func ListBranches(ctx context.Context, r Runner, dir, defaultBranch string) ([]BranchInfo, error) {
+	out, err := Output(ctx, r, dir, "for-each-ref",
+		"--format=%(refname:short)%00%(objectname)%00%(HEAD)%00%(upstream:short)%00%(upstream:track,nobracket)%00%(committerdate:iso-strict)%00%(worktreepath)",
+		"refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []BranchInfo
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, "\x00")
+		if len(parts) != 7 {
+			return nil, fmt.Errorf("无法解析分支信息: %q", line)
+		}
+
+		branch := BranchInfo{
+			Name:     parts[0],
+			Commit:   parts[1],
+			Current:  parts[2] == "*",
+			Upstream: parts[3],
+			Worktree: parts[6],
+		}
+		branch.parseTrack(parts[4])
+		branch.LastCommitDate, _ = time.Parse(time.RFC3339, parts[5])
+		branches = append(branches, branch)
+	}
+
+	if defaultBranch == "" {
+		return branches, nil
+	}
+
+	merged, err := Output(ctx, r, dir, "for-each-ref", "--merged", defaultBranch, "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	mergedSet := make(map[string]bool)
+	for _, name := range strings.Fields(merged) {
+		mergedSet[name] = true
+	}
+	for i := range branches {
+		branches[i].Merged = mergedSet[branches[i].Name]
+	}
+	return branches, nil
+}
+
+// parseTrack reads %(upstream:track,nobracket), e.g. "ahead 1, behind 2" or "gone"
+func (b *BranchInfo) parseTrack(track string) {
+	if track == "gone" {
+		b.UpstreamGone = true
+		return
+	}
+	for _, part := range strings.Split(track, ",") {
+		fields := strings.Fields(part)
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := ParseCount(fields[1])
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "ahead":
+			b.Ahead = n
+		case "behind":
+			b.Behind = n
+		}
+	}
+}
+
+// DefaultBranch returns the branch other branches are merged into: the remote
+// HEAD of origin (e.g. "origin/main") if known, otherwise main or master on origin
+// or locally. It returns "" when none of them exists.
+func DefaultBranch(ctx context.Context, r Runner, dir string) string {
+	if ref, err := Output(ctx, r, dir, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil && ref != "" {
+		return ref
+	}
+	for _, name := range []string{"origin/main", "origin/master"} {
+		if RefExists(ctx, r, dir, "refs/remotes/"+name) {
+			return name
+		}
+	}
+	for _, name := range []string{"main", "master"} {
+		if RefExists(ctx, r, dir, "refs/heads/"+name) {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package git
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrorClass is a machine-readable category for a failed git command
+type ErrorClass string
+
+const (
+	ErrorAuth           ErrorClass = "auth"             // 认证失败或无权限
+	ErrorHostKey        ErrorClass = "host_key"         // SSH 主机密钥验证失败
+	ErrorNetwork        ErrorClass = "network"          // 无法连接远程仓库
+	ErrorTimeout        ErrorClass = "timeout"          // 命令超时
+	ErrorNonFastForward ErrorClass = "non_fast_forward" // 无法快进
+	ErrorNoUpstream     ErrorClass = "no_upstream"      // 没有可拉取的上游
+	ErrorConflict       ErrorClass = "conflict"         // 与本地变更冲突
+	ErrorUnknown        ErrorClass = "unknown"
+)
+
+// Transient reports whether the failure may go away when the command is retried
+func (c ErrorClass) Transient() bool {
+	return c == ErrorNetwork || c == ErrorTimeout
+}
+
+// errorPatterns maps git and ssh messages to error classes, checked in order
+var errorPatterns = []struct {
+	class    ErrorClass
+	patterns []string
+}{
+	// 主机密钥错误之后也会输出 "Could not read from remote repository"，需先判断
+	{ErrorHostKey, []string{"host key verification failed", "remote host identification has changed", "no matching host key"}},
+	{ErrorAuth, []string{"permission denied", "authentication failed", "could not read username", "could not read password", "terminal prompts disabled", "access denied", "repository not found", "returned error: 401", "returned error: 403"}},
+	{ErrorTimeout, []string{"timed out", "timeout"}},
+	{ErrorNetwork, []string{"could not resolve host", "failed to connect", "couldn't connect to server", "temporary failure in name resolution", "connection refused", "connection reset", "network is unreachable", "no route to host", "the remote end hung up unexpectedly", "early eof", "rpc failed", "unable to access", "tls connection", "ssl_error", "gnutls"}},
+	{ErrorNoUpstream, []string{"there is no tracking information", "no such ref was fetched", "couldn't find remote ref"}},
+	{ErrorNonFastForward, []string{"non-fast-forward", "not possible to fast-forward", "divergent branches", "refusing to merge unrelated histories"}},
+	{ErrorConflict, []string{"conflict", "would be overwritten", "automatic merge failed", "could not apply", "unmerged"}},
+	// 其他无法读取远程仓库的情况多为无权限
+	{ErrorAuth, []string{"could not read from remote repository"}},
+}
+
+// ClassifyError categorizes a failed git command from its error and combined output
+func ClassifyError(err error, output string) ErrorClass {
+	if errors.Is(err, ErrTimeout) {
+		return ErrorTimeout
+	}
+	if errors.Is(err, ErrCanceled) || errors.Is(err, ErrGitNotFound) {
+		return ErrorUnknown
+	}
+
+	output = strings.ToLower(output)
+	for _, entry := range errorPatterns {
+		for _, pattern := range entry.patterns {
+			if strings.Contains(output, pattern) {
+				return entry.class
+			}
+		}
+	}
+	return ErrorUnknown
+}
@@ -0,0 +1,60 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	failed := errors.New("exit status 1")
+	tests := []struct {
+		name   string
+		err    error
+		output string
+		want   ErrorClass
+	}{
+		{"timeout error", &CommandError{Err: ErrTimeout}, "", ErrorTimeout},
+		{"canceled", &CommandError{Err: ErrCanceled}, "fatal: could not resolve host", ErrorUnknown},
+		{"git missing", fmt.Errorf("wrapped: %w", ErrGitNotFound), "", ErrorUnknown},
+		{"host key before auth", failed, "Host key verification failed.\nfatal: Could not read from remote repository.", ErrorHostKey},
+		{"ssh permission", failed, "git@github.com: Permission denied (publickey).\nfatal: Could not read from remote repository.", ErrorAuth},
+		{"https prompt disabled", failed, "fatal: could not read Username for 'https://github.com': terminal prompts disabled", ErrorAuth},
+		{"http 403", failed, "fatal: unable to access 'https://x/': The requested URL returned error: 403", ErrorAuth},
+		{"ssh timeout", failed, "ssh: connect to host example.com port 22: Connection timed out", ErrorTimeout},
+		{"dns", failed, "ssh: Could not resolve hostname example.com: Temporary failure in name resolution", ErrorNetwork},
+		{"unable to access", failed, "fatal: unable to access 'https://x/': Failed to connect to x port 443", ErrorNetwork},
+		{"hung up", failed, "fatal: the remote end hung up unexpectedly", ErrorNetwork},
+		{"no upstream", failed, "There is no tracking information for the current branch.", ErrorNoUpstream},
+		{"ff-only", failed, "fatal: Not possible to fast-forward, aborting.", ErrorNonFastForward},
+		{"divergent", failed, "hint: You have divergent branches and need to specify how to reconcile them.", ErrorNonFastForward},
+		{"rejected push", failed, " ! [rejected]        main -> main (non-fast-forward)", ErrorNonFastForward},
+		{"overwritten", failed, "error: Your local changes to the following files would be overwritten by merge:", ErrorConflict},
+		{"merge conflict", failed, "CONFLICT (content): Merge conflict in a.txt\nAutomatic merge failed; fix conflicts and then commit the result.", ErrorConflict},
+		{"other remote failure", failed, "fatal: Could not read from remote repository.", ErrorAuth},
+		{"unknown", failed, "fatal: something unexpected", ErrorUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err, tt.output); got != tt.want {
+				t.Errorf("ClassifyError() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorClassTransient(t *testing.T) {
+	for class, want := range map[ErrorClass]bool{
+		ErrorNetwork:        true,
+		ErrorTimeout:        true,
+		ErrorAuth:           false,
+		ErrorHostKey:        false,
+		ErrorNonFastForward: false,
+		ErrorConflict:       false,
+		ErrorUnknown:        false,
+	} {
+		if got := class.Transient(); got != want {
+			t.Errorf("%s.Transient() = %v, want %v", class, got, want)
+		}
+	}
+}
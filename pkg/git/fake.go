@@ -0,0 +1,114 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// FakeResponse is the canned output for a command registered on a FakeRunner
+type FakeResponse struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Err      error
+}
+
+// FakeCall records a command executed through a FakeRunner
+type FakeCall struct {
+	Dir  string
+	Args []string
+}
+
+// FakeRunner is an in-memory Runner for exercising git-driven logic without
+// real repositories. Responses are matched on directory and exact arguments;
+// a response registered with an empty directory matches any directory.
+type FakeRunner struct {
+	mu        sync.Mutex
+	responses map[string]FakeResponse
+	calls     []FakeCall
+
+	// Handler, if set, is consulted before the registered responses
+	Handler func(dir string, args []string) (FakeResponse, bool)
+}
+
+// NewFakeRunner creates an empty FakeRunner
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{
+		responses: make(map[string]FakeResponse),
+	}
+}
+
+// Set registers the response for a command run in dir
+func (f *FakeRunner) Set(dir string, response FakeResponse, args ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[fakeKey(dir, args)] = response
+}
+
+// SetOutput registers a successful command with the given stdout
+func (f *FakeRunner) SetOutput(dir, stdout string, args ...string) {
+	f.Set(dir, FakeResponse{Stdout: stdout}, args...)
+}
+
+// Calls returns a copy of the commands executed so far
+func (f *FakeRunner) Calls() []FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([]FakeCall, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
+
+// Run implements Runner
+func (f *FakeRunner) Run(ctx context.Context, dir string, args ...string) (*Result, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, FakeCall{Dir: dir, Args: append([]string(nil), args...)})
+	handler := f.Handler
+	response, ok := f.responses[fakeKey(dir, args)]
+	if !ok {
+		response, ok = f.responses[fakeKey("", args)]
+	}
+	f.mu.Unlock()
+
+	if handler != nil {
+		if handled, found := handler(dir, args); found {
+			response, ok = handled, true
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, &CommandError{Dir: dir, Args: args, Err: ErrCanceled}
+	}
+
+	if !ok {
+		response = FakeResponse{
+			Stderr:   "fake: 未注册的命令 git " + strings.Join(args, " "),
+			ExitCode: 1,
+		}
+	}
+
+	result := &Result{
+		Args:     args,
+		Dir:      dir,
+		Stdout:   response.Stdout,
+		Stderr:   response.Stderr,
+		ExitCode: response.ExitCode,
+	}
+
+	if response.Err != nil || response.ExitCode != 0 {
+		return result, &CommandError{
+			Dir:      dir,
+			Args:     args,
+			ExitCode: response.ExitCode,
+			Stderr:   response.Stderr,
+			Err:      response.Err,
+		}
+	}
+
+	return result, nil
+}
+
+func fakeKey(dir string, args []string) string {
+	return dir + "\x00" + strings.Join(args, "\x00")
+}
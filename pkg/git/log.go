@@ -0,0 +1,84 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Commit is a commit summary as shown in a log
+type Commit struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Subject string `json:"subject"`
+}
+
+// IncomingCommits returns the commits reachable from newHead but not from
+// oldHead, newest first. Commits that only moved during a rebase are left out.
+// At most limit commits are returned; total is the full count.
+func IncomingCommits(ctx context.Context, r Runner, dir, oldHead, newHead string, limit int) (commits []Commit, total int, err error) {
+	revRange := oldHead + "..." + newHead
+	out, err := Output(ctx, r, dir, "rev-list", "--count", "--right-only", "--cherry-pick", revRange)
+	if err != nil {
+		return nil, 0, err
+	}
+	if total, err = ParseCount(out); err != nil {
+		return nil, 0, err
+	}
+
+	out, err = Output(ctx, r, dir, "log", "--right-only", "--cherry-pick", fmt.Sprintf("--max-count=%d", limit), "--format=%H%x00%an%x00%s", revRange)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, "\x00", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		commits = append(commits, Commit{Hash: parts[0], Author: parts[1], Subject: parts[2]})
+	}
+	return commits, total, nil
+}
+
+// DiffStat summarizes the files changed between two commits
+type DiffStat struct {
+	FilesChanged int      `json:"files_changed"`
+	Insertions   int      `json:"insertions"`
+	Deletions    int      `json:"deletions"`
+	Paths        []string `json:"-"`
+}
+
+// Diff returns the files changed between from and to. Renames are counted
+// as a deletion and an addition so that every path is reported.
+func Diff(ctx context.Context, r Runner, dir, from, to string) (DiffStat, error) {
+	// -z 输出原始路径，否则非 ASCII 路径会被加上引号并转义
+	result, err := r.Run(ctx, dir, "diff", "--numstat", "--no-renames", "-z", from, to)
+	if err != nil {
+		return DiffStat{}, err
+	}
+	return ParseNumstat(result.Stdout)
+}
+
+// ParseNumstat parses the output of git diff --numstat --no-renames -z, in
+// which every record is "added\tdeleted\tpath" terminated by a NUL
+func ParseNumstat(output string) (DiffStat, error) {
+	var stat DiffStat
+	for _, record := range strings.Split(output, "\x00") {
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\t", 3)
+		if len(fields) != 3 || fields[2] == "" {
+			return DiffStat{}, fmt.Errorf("无法解析diff输出: %q", record)
+		}
+		// 二进制文件的行数为 "-"
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		stat.FilesChanged++
+		stat.Insertions += added
+		stat.Deletions += deleted
+		stat.Paths = append(stat.Paths, fields[2])
+	}
+	return stat, nil
+}
@@ -0,0 +1,47 @@
+package git
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseNumstat(t *testing.T) {
+	// 路径中的制表符、换行和非 ASCII 字符在 -z 输出中保持原样
+	output := "3\t1\tcmd/main.go\x00-\t-\tassets/logo.png\x000\t2\t文档/说明.md\x001\t0\tname\twith\ttabs\nand newline\x00"
+	stat, err := ParseNumstat(output)
+	if err != nil {
+		t.Fatalf("ParseNumstat() error: %v", err)
+	}
+	if stat.FilesChanged != 4 || stat.Insertions != 4 || stat.Deletions != 3 {
+		t.Errorf("ParseNumstat() = %+v, want 4 files, 4 insertions, 3 deletions", stat)
+	}
+	want := []string{"cmd/main.go", "assets/logo.png", "文档/说明.md", "name\twith\ttabs\nand newline"}
+	if len(stat.Paths) != len(want) {
+		t.Fatalf("Paths = %q, want %q", stat.Paths, want)
+	}
+	for i := range want {
+		if stat.Paths[i] != want[i] {
+			t.Errorf("Paths[%d] = %q, want %q", i, stat.Paths[i], want[i])
+		}
+	}
+
+	if stat, err := ParseNumstat(""); err != nil || stat.FilesChanged != 0 {
+		t.Errorf("ParseNumstat(\"\") = %+v, %v, want empty", stat, err)
+	}
+	if _, err := ParseNumstat("garbage\x00"); err == nil {
+		t.Error("ParseNumstat(garbage) succeeded, want error")
+	}
+}
+
+func TestDiffUsesNulSeparatedOutput(t *testing.T) {
+	fake := NewFakeRunner()
+	fake.SetOutput("", "1\t0\tsrc/新.go\x00", "diff", "--numstat", "--no-renames", "-z", "aaa", "bbb")
+
+	stat, err := Diff(context.Background(), fake, "/repo", "aaa", "bbb")
+	if err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	if len(stat.Paths) != 1 || stat.Paths[0] != "src/新.go" {
+		t.Errorf("Diff() paths = %q, want [src/新.go]", stat.Paths)
+	}
+}
@@ -0,0 +1,220 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout matches git's ISO-like date format (%ci/%ai)
+const DateLayout = "2006-01-02 15:04:05 -0700"
+
+// CommitInfo summarizes a single commit
+type CommitInfo struct {
+	Hash    string    `json:"hash"`
+	Subject string    `json:"subject"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+}
+
+// CurrentBranch returns the checked-out branch name, or "" for a detached HEAD
+func CurrentBranch(ctx context.Context, r Runner, dir string) (string, error) {
+	return Output(ctx, r, dir, "branch", "--show-current")
+}
+
+// HeadCommit returns the full hash of HEAD
+func HeadCommit(ctx context.Context, r Runner, dir string) (string, error) {
+	return Output(ctx, r, dir, "rev-parse", "HEAD")
+}
+
+// RootCommit returns the root commit of HEAD's history. When there are several
+// roots (merged unrelated histories) the lexically smallest hash is returned so
+// the result is stable.
+func RootCommit(ctx context.Context, r Runner, dir string) (string, error) {
+	out, err := Output(ctx, r, dir, "rev-list", "--max-parents=0", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	roots := strings.Fields(out)
+	if len(roots) == 0 {
+		return "", nil
+	}
+	sort.Strings(roots)
+	return roots[0], nil
+}
+
+// RemoteURL returns the URL of the named remote
+func RemoteURL(ctx context.Context, r Runner, dir, remote string) (string, error) {
+	return Output(ctx, r, dir, "remote", "get-url", remote)
+}
+
+// RemoteBranch asks the named remote where its branch points, returning ""
+// when the remote has no such branch. Unlike remote-tracking refs this is
+// always current, so it contacts the remote.
+func RemoteBranch(ctx context.Context, r Runner, dir, remote, branch string) (string, error) {
+	out, err := Output(ctx, r, dir, "ls-remote", "--heads", remote, "refs/heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	hash, _, _ := strings.Cut(out, "\t")
+	return hash, nil
+}
+
+// Upstream returns the short name of the branch HEAD tracks, such as
+// "upstream/main", or "" when no upstream is configured or HEAD is detached
+func Upstream(ctx context.Context, r Runner, dir string) (string, error) {
+	out, err := Output(ctx, r, dir, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		// 超时或取消需要上报，其余错误表示没有配置上游
+		if errors.Is(err, ErrTimeout) || errors.Is(err, ErrCanceled) || errors.Is(err, ErrGitNotFound) {
+			return "", err
+		}
+		return "", nil
+	}
+	return out, nil
+}
+
+// LastFetchTime returns when the repository was last fetched, judged by the
+// modification time of FETCH_HEAD; the zero time means it was never fetched
+func LastFetchTime(ctx context.Context, r Runner, dir string) (time.Time, error) {
+	path, err := Output(ctx, r, dir, "rev-parse", "--git-path", "FETCH_HEAD")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// ListRefs returns the object each ref under the given prefixes points at, keyed by full ref name
+func ListRefs(ctx context.Context, r Runner, dir string, prefixes ...string) (map[string]string, error) {
+	args := append([]string{"for-each-ref", "--format=%(objectname) %(refname)"}, prefixes...)
+	out, err := Output(ctx, r, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if hash, name, found := strings.Cut(line, " "); found {
+			refs[name] = hash
+		}
+	}
+	return refs, nil
+}
+
+// RefExists reports whether ref resolves to an object
+func RefExists(ctx context.Context, r Runner, dir, ref string) bool {
+	_, err := r.Run(ctx, dir, "rev-parse", "--verify", "--quiet", ref)
+	return err == nil
+}
+
+// CountCommits returns the number of commits in a revision range such as "a..b"
+func CountCommits(ctx context.Context, r Runner, dir, revRange string) (int, error) {
+	out, err := Output(ctx, r, dir, "rev-list", "--count", revRange)
+	if err != nil {
+		return 0, err
+	}
+	return ParseCount(out)
+}
+
+// AheadBehind returns how many commits local is ahead of and behind upstream
+func AheadBehind(ctx context.Context, r Runner, dir, local, upstream string) (int, int, error) {
+	out, err := Output(ctx, r, dir, "rev-list", "--left-right", "--count", local+"..."+upstream)
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("无法解析rev-list输出: %q", out)
+	}
+	ahead, err := ParseCount(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	behind, err := ParseCount(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return ahead, behind, nil
+}
+
+// LastCommit returns hash, subject, author and committer date of rev
+func LastCommit(ctx context.Context, r Runner, dir, rev string) (CommitInfo, error) {
+	out, err := Output(ctx, r, dir, "log", "-1", "--pretty=format:%H%x00%s%x00%an%x00%ci", rev, "--")
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	parts := strings.Split(out, "\x00")
+	if len(parts) != 4 {
+		return CommitInfo{}, fmt.Errorf("无法解析git log输出: %q", out)
+	}
+	info := CommitInfo{
+		Hash:    parts[0],
+		Subject: parts[1],
+		Author:  parts[2],
+	}
+	if date, err := time.Parse(DateLayout, parts[3]); err == nil {
+		info.Date = date
+	}
+	return info, nil
+}
+
+// CommitDates holds the dates used to order repositories by activity
+type CommitDates struct {
+	Head             string    `json:"head"`
+	CommitterDate    time.Time `json:"committer_date"`     // HEAD 的提交时间
+	AuthorDate       time.Time `json:"author_date"`        // HEAD 的作者时间
+	LatestBranchDate time.Time `json:"latest_branch_date"` // 所有本地分支中最新的提交时间
+}
+
+// ReadCommitDates reads the HEAD commit dates and the newest local branch commit date
+func ReadCommitDates(ctx context.Context, r Runner, dir string) (CommitDates, error) {
+	out, err := Output(ctx, r, dir, "log", "-1", "--format=%H%x00%cI%x00%aI", "HEAD", "--")
+	if err != nil {
+		return CommitDates{}, err
+	}
+	parts := strings.Split(out, "\x00")
+	if len(parts) != 3 {
+		return CommitDates{}, fmt.Errorf("无法解析git log输出: %q", out)
+	}
+
+	dates := CommitDates{Head: parts[0]}
+	dates.CommitterDate, _ = time.Parse(time.RFC3339, parts[1])
+	dates.AuthorDate, _ = time.Parse(time.RFC3339, parts[2])
+	dates.LatestBranchDate = dates.CommitterDate
+
+	// 分离 HEAD 或其他分支上可能有更新的提交
+	branchOut, err := Output(ctx, r, dir, "for-each-ref", "--sort=-committerdate", "--count=1",
+		"--format=%(committerdate:iso-strict)", "refs/heads")
+	if err == nil && branchOut != "" {
+		if latest, err := time.Parse(time.RFC3339, branchOut); err == nil && latest.After(dates.LatestBranchDate) {
+			dates.LatestBranchDate = latest
+		}
+	}
+	return dates, nil
+}
+
+// ParseCount parses a non-negative integer printed by git
+func ParseCount(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number: %s", s)
+	}
+	return n, nil
+}
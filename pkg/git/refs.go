@@ -0,0 +1,64 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadHead resolves HEAD to a commit hash by reading the git directory directly,
+// which is much cheaper than running git. commonDir is the shared git directory
+// of a linked worktree and may be empty.
+func ReadHead(gitDir, commonDir string) (string, error) {
+	if commonDir == "" {
+		commonDir = gitDir
+	}
+
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(data))
+
+	// 符号引用最多跟随几层，防止循环
+	for i := 0; i < 5; i++ {
+		ref, ok := strings.CutPrefix(value, "ref: ")
+		if !ok {
+			return value, nil
+		}
+		value, err = readRef(gitDir, commonDir, strings.TrimSpace(ref))
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("引用层级过深: %s", value)
+}
+
+// readRef reads a loose ref, falling back to packed-refs
+func readRef(gitDir, commonDir, ref string) (string, error) {
+	for _, dir := range []string{gitDir, commonDir} {
+		if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+
+	file, err := os.Open(filepath.Join(commonDir, "packed-refs"))
+	if err != nil {
+		return "", fmt.Errorf("无法解析引用 %s: %w", ref, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "^") {
+			continue
+		}
+		if hash, name, found := strings.Cut(line, " "); found && name == ref {
+			return hash, nil
+		}
+	}
+	return "", fmt.Errorf("引用不存在: %s", ref)
+}
@@ -0,0 +1,203 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrGitNotFound is returned when the git executable cannot be located
+	ErrGitNotFound = errors.New("未找到git可执行文件")
+	// ErrTimeout is returned when a git command exceeds its deadline
+	ErrTimeout = errors.New("git命令执行超时")
+	// ErrCanceled is returned when a git command is canceled by its context
+	ErrCanceled = errors.New("git命令已取消")
+)
+
+// Result holds the captured output of a git command
+type Result struct {
+	Args     []string      `json:"args"`
+	Dir      string        `json:"dir"`
+	Stdout   string        `json:"stdout"`
+	Stderr   string        `json:"stderr"`
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Output returns stdout followed by stderr, similar to CombinedOutput
+func (r *Result) Output() string {
+	if r == nil {
+		return ""
+	}
+	if r.Stderr == "" {
+		return r.Stdout
+	}
+	if r.Stdout == "" {
+		return r.Stderr
+	}
+	return r.Stdout + "\n" + r.Stderr
+}
+
+// CommandError describes a git command that did not complete successfully
+type CommandError struct {
+	Dir      string
+	Args     []string
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("git %s", strings.Join(e.Args, " "))
+	if e.ExitCode > 0 {
+		msg += fmt.Sprintf(" 退出码 %d", e.ExitCode)
+	}
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	} else if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Runner executes git commands. Implementations must be safe for concurrent use.
+//
+// Run always returns a non-nil Result when the command was started, even if it
+// exited with a non-zero status, so callers can inspect the captured output.
+type Runner interface {
+	Run(ctx context.Context, dir string, args ...string) (*Result, error)
+}
+
+// ExecRunner runs git as a subprocess
+type ExecRunner struct {
+	// Binary is the git executable, defaults to "git"
+	Binary string
+	// Timeout bounds every command; zero means only the caller's context applies
+	Timeout time.Duration
+	// NonInteractive disables credential and SSH prompts
+	NonInteractive bool
+	// SSHConnectTimeout is passed to ssh in non-interactive mode
+	SSHConnectTimeout time.Duration
+	// Env holds additional KEY=VALUE entries appended to the environment
+	Env []string
+}
+
+// NewExecRunner creates a non-interactive ExecRunner
+func NewExecRunner() *ExecRunner {
+	return &ExecRunner{
+		Binary:            "git",
+		NonInteractive:    true,
+		SSHConnectTimeout: 10 * time.Second,
+	}
+}
+
+// Run executes git with the given arguments in dir
+func (r *ExecRunner) Run(ctx context.Context, dir string, args ...string) (*Result, error) {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	binary := r.Binary
+	if binary == "" {
+		binary = "git"
+	}
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Dir = dir
+	cmd.Env = r.environ()
+	// git 被终止后，ssh 等子进程可能仍占用输出管道，不再等待它们
+	cmd.WaitDelay = time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	result := &Result{
+		Args:     args,
+		Dir:      dir,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(start),
+	}
+
+	if err == nil {
+		return result, nil
+	}
+
+	cmdErr := &CommandError{
+		Dir:    dir,
+		Args:   args,
+		Stderr: result.Stderr,
+		Err:    err,
+	}
+
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		cmdErr.Err = ErrGitNotFound
+		return nil, cmdErr
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		cmdErr.Err = ErrTimeout
+	case errors.Is(ctx.Err(), context.Canceled):
+		cmdErr.Err = ErrCanceled
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		cmdErr.ExitCode = exitErr.ExitCode()
+		result.ExitCode = cmdErr.ExitCode
+	}
+
+	return result, cmdErr
+}
+
+// environ builds the environment for a git subprocess
+func (r *ExecRunner) environ() []string {
+	env := os.Environ()
+	if r.NonInteractive {
+		connectTimeout := r.SSHConnectTimeout
+		if connectTimeout <= 0 {
+			connectTimeout = 10 * time.Second
+		}
+		env = append(env,
+			"GIT_TERMINAL_PROMPT=0", // 禁用终端提示
+			"GIT_ASKPASS=echo",      // 禁用密码提示
+			"SSH_ASKPASS=echo",      // 禁用SSH密码提示
+			fmt.Sprintf("GIT_SSH_COMMAND=ssh -o BatchMode=yes -o ConnectTimeout=%d -o StrictHostKeyChecking=no", int(connectTimeout.Seconds())),
+		)
+	}
+	return append(env, r.Env...)
+}
+
+// Output runs a git command and returns its trimmed stdout
+func Output(ctx context.Context, r Runner, dir string, args ...string) (string, error) {
+	result, err := r.Run(ctx, dir, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+// ExitCode extracts the exit status from an error returned by a Runner, or -1
+func ExitCode(err error) int {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.ExitCode
+	}
+	return -1
+}
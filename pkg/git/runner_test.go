@@ -0,0 +1,242 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestResultOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *Result
+		want   string
+	}{
+		{"nil", nil, ""},
+		{"stdout only", &Result{Stdout: "out"}, "out"},
+		{"stderr only", &Result{Stderr: "err"}, "err"},
+		{"both", &Result{Stdout: "out", Stderr: "err"}, "out\nerr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.Output(); got != tt.want {
+				t.Errorf("Output() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *CommandError
+		want string
+	}{
+		{
+			name: "stderr",
+			err:  &CommandError{Args: []string{"pull"}, ExitCode: 1, Stderr: "fatal: boom\n"},
+			want: "git pull 退出码 1: fatal: boom",
+		},
+		{
+			name: "wrapped error without stderr",
+			err:  &CommandError{Args: []string{"fetch", "--all"}, Err: ErrTimeout},
+			want: "git fetch --all: " + ErrTimeout.Error(),
+		},
+		{
+			name: "exit code only",
+			err:  &CommandError{Args: []string{"status"}, ExitCode: 128},
+			want: "git status 退出码 128",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	err := error(&CommandError{Args: []string{"fetch"}, ExitCode: 2, Err: ErrTimeout})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("errors.Is(err, ErrTimeout) = false, want true")
+	}
+	if got := ExitCode(err); got != 2 {
+		t.Errorf("ExitCode() = %d, want 2", got)
+	}
+	if got := ExitCode(errors.New("other")); got != -1 {
+		t.Errorf("ExitCode(non-command error) = %d, want -1", got)
+	}
+}
+
+func TestFakeRunnerResponses(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeRunner()
+	fake.SetOutput("", "main\n", "branch", "--show-current")
+	fake.SetOutput("/repo/b", "dev\n", "branch", "--show-current")
+	fake.Set("/repo/c", FakeResponse{Stderr: "fatal: not a git repository", ExitCode: 128}, "branch", "--show-current")
+
+	// 指定目录的响应优先，其次是空目录注册的通用响应
+	for dir, want := range map[string]string{"/repo/a": "main", "/repo/b": "dev"} {
+		got, err := CurrentBranch(ctx, fake, dir)
+		if err != nil {
+			t.Fatalf("CurrentBranch(%s) error: %v", dir, err)
+		}
+		if got != want {
+			t.Errorf("CurrentBranch(%s) = %q, want %q", dir, got, want)
+		}
+	}
+
+	result, err := fake.Run(ctx, "/repo/c", "branch", "--show-current")
+	if err == nil {
+		t.Fatal("expected error for non-zero exit code")
+	}
+	if result == nil || result.ExitCode != 128 {
+		t.Errorf("result = %+v, want exit code 128", result)
+	}
+	if got := ExitCode(err); got != 128 {
+		t.Errorf("ExitCode() = %d, want 128", got)
+	}
+
+	// 未注册的命令按失败处理
+	if _, err := fake.Run(ctx, "/repo/a", "log"); ExitCode(err) != 1 {
+		t.Errorf("unregistered command: err = %v, want exit code 1", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 4 {
+		t.Fatalf("len(Calls()) = %d, want 4", len(calls))
+	}
+	if calls[1].Dir != "/repo/b" || len(calls[1].Args) != 2 || calls[1].Args[0] != "branch" {
+		t.Errorf("Calls()[1] = %+v", calls[1])
+	}
+}
+
+func TestFakeRunnerHandler(t *testing.T) {
+	fake := NewFakeRunner()
+	fake.SetOutput("", "registered", "rev-parse", "HEAD")
+	fake.Handler = func(dir string, args []string) (FakeResponse, bool) {
+		if dir == "/handled" {
+			return FakeResponse{Stdout: "handled"}, true
+		}
+		return FakeResponse{}, false
+	}
+
+	for dir, want := range map[string]string{"/handled": "handled", "/other": "registered"} {
+		got, err := HeadCommit(context.Background(), fake, dir)
+		if err != nil {
+			t.Fatalf("HeadCommit(%s) error: %v", dir, err)
+		}
+		if got != want {
+			t.Errorf("HeadCommit(%s) = %q, want %q", dir, got, want)
+		}
+	}
+}
+
+func TestFakeRunnerCanceled(t *testing.T) {
+	fake := NewFakeRunner()
+	fake.SetOutput("", "abc", "rev-parse", "HEAD")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fake.Run(ctx, "/repo", "rev-parse", "HEAD"); !errors.Is(err, ErrCanceled) {
+		t.Errorf("err = %v, want ErrCanceled", err)
+	}
+}
+
+func TestQueryHelpers(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeRunner()
+	fake.SetOutput("", "3\t5\n", "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	fake.SetOutput("", "ffff\naaaa\n", "rev-list", "--max-parents=0", "HEAD")
+	fake.SetOutput("", "1111\trefs/heads/main\n", "ls-remote", "--heads", "origin", "refs/heads/main")
+	fake.SetOutput("", "", "ls-remote", "--heads", "origin", "refs/heads/gone")
+	fake.SetOutput("", "1111 refs/heads/main\n2222 refs/tags/v1\n", "for-each-ref", "--format=%(objectname) %(refname)", "refs/heads", "refs/tags")
+
+	ahead, behind, err := AheadBehind(ctx, fake, "/repo", "HEAD", "@{upstream}")
+	if err != nil || ahead != 3 || behind != 5 {
+		t.Errorf("AheadBehind() = %d, %d, %v, want 3, 5, nil", ahead, behind, err)
+	}
+
+	if root, err := RootCommit(ctx, fake, "/repo"); err != nil || root != "aaaa" {
+		t.Errorf("RootCommit() = %q, %v, want the smallest root %q", root, err, "aaaa")
+	}
+
+	if hash, err := RemoteBranch(ctx, fake, "/repo", "origin", "main"); err != nil || hash != "1111" {
+		t.Errorf("RemoteBranch(main) = %q, %v, want 1111", hash, err)
+	}
+	if hash, err := RemoteBranch(ctx, fake, "/repo", "origin", "gone"); err != nil || hash != "" {
+		t.Errorf("RemoteBranch(gone) = %q, %v, want empty", hash, err)
+	}
+
+	refs, err := ListRefs(ctx, fake, "/repo", "refs/heads", "refs/tags")
+	if err != nil {
+		t.Fatalf("ListRefs() error: %v", err)
+	}
+	if len(refs) != 2 || refs["refs/heads/main"] != "1111" || refs["refs/tags/v1"] != "2222" {
+		t.Errorf("ListRefs() = %v", refs)
+	}
+}
+
+func TestUpstream(t *testing.T) {
+	ctx := context.Background()
+	args := []string{"rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}"}
+	fake := NewFakeRunner()
+	fake.SetOutput("/tracking", "origin/main\n", args...)
+	fake.Set("/untracked", FakeResponse{Stderr: "fatal: no upstream configured", ExitCode: 128}, args...)
+	fake.Set("/slow", FakeResponse{Err: ErrTimeout}, args...)
+
+	if got, err := Upstream(ctx, fake, "/tracking"); err != nil || got != "origin/main" {
+		t.Errorf("Upstream(tracking) = %q, %v", got, err)
+	}
+	// 没有上游不是错误
+	if got, err := Upstream(ctx, fake, "/untracked"); err != nil || got != "" {
+		t.Errorf("Upstream(untracked) = %q, %v, want empty and no error", got, err)
+	}
+	if _, err := Upstream(ctx, fake, "/slow"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Upstream(slow) error = %v, want ErrTimeout", err)
+	}
+}
+
+func TestExecRunnerErrors(t *testing.T) {
+	runner := &ExecRunner{Binary: "reposense-no-such-git"}
+	result, err := runner.Run(context.Background(), t.TempDir(), "status")
+	if !errors.Is(err, ErrGitNotFound) {
+		t.Errorf("missing binary: err = %v, want ErrGitNotFound", err)
+	}
+	if result != nil {
+		t.Errorf("missing binary: result = %+v, want nil", result)
+	}
+
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+	runner = &ExecRunner{Binary: "sleep", Timeout: 50 * time.Millisecond}
+	if _, err := runner.Run(context.Background(), t.TempDir(), "5"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("slow command: err = %v, want ErrTimeout", err)
+	}
+}
+
+func TestExecRunnerGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	runner := NewExecRunner()
+
+	if _, err := runner.Run(ctx, dir, "init", "--quiet"); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	result, err := runner.Run(ctx, dir, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err == nil {
+		t.Fatal("rev-parse HEAD in an empty repository should fail")
+	}
+	if result == nil || result.ExitCode == 0 || ExitCode(err) != result.ExitCode {
+		t.Errorf("result = %+v, err = %v, want matching non-zero exit codes", result, err)
+	}
+	if RefExists(ctx, runner, dir, "HEAD") {
+		t.Error("RefExists(HEAD) = true in an empty repository")
+	}
+}
@@ -0,0 +1,141 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HeadState describes what HEAD points at
+type HeadState string
+
+const (
+	HeadBranch   HeadState = "branch"   // 位于分支上
+	HeadDetached HeadState = "detached" // 分离 HEAD，直接指向某个提交
+	HeadUnborn   HeadState = "unborn"   // 分支尚无任何提交
+)
+
+// HeadInfo is the state of HEAD in a repository
+type HeadInfo struct {
+	State  HeadState `json:"state"`
+	Branch string    `json:"branch,omitempty"` // 分支名，未出生的分支也有名称
+	Commit string    `json:"commit,omitempty"`
+}
+
+// ShortCommit returns the abbreviated commit hash
+func (h HeadInfo) ShortCommit() string {
+	return ShortHash(h.Commit)
+}
+
+// ShortHash abbreviates a commit hash to 7 characters
+func ShortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// Operation is a multi-step git command that has been started but not finished
+type Operation string
+
+const (
+	OpNone       Operation = ""
+	OpRebase     Operation = "rebase"
+	OpAm         Operation = "am"
+	OpMerge      Operation = "merge"
+	OpCherryPick Operation = "cherry-pick"
+	OpRevert     Operation = "revert"
+	OpBisect     Operation = "bisect"
+)
+
+// Description returns a human readable name of the operation
+func (o Operation) Description() string {
+	switch o {
+	case OpRebase:
+		return "变基 (rebase)"
+	case OpAm:
+		return "应用补丁 (am)"
+	case OpMerge:
+		return "合并 (merge)"
+	case OpCherryPick:
+		return "拣选 (cherry-pick)"
+	case OpRevert:
+		return "回滚 (revert)"
+	case OpBisect:
+		return "二分查找 (bisect)"
+	}
+	return string(o)
+}
+
+// ReadHeadState determines the HEAD state by reading the git directory directly.
+// commonDir is the shared git directory of a linked worktree and may be empty.
+func ReadHeadState(gitDir, commonDir string) (HeadInfo, error) {
+	if commonDir == "" {
+		commonDir = gitDir
+	}
+
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return HeadInfo{}, err
+	}
+	value := strings.TrimSpace(string(data))
+
+	ref, symbolic := strings.CutPrefix(value, "ref: ")
+	if !symbolic {
+		return HeadInfo{State: HeadDetached, Commit: value}, nil
+	}
+
+	ref = strings.TrimSpace(ref)
+	info := HeadInfo{State: HeadBranch, Branch: strings.TrimPrefix(ref, "refs/heads/")}
+	commit, err := readRef(gitDir, commonDir, ref)
+	if err != nil {
+		// 分支引用不存在，说明还没有任何提交
+		info.State = HeadUnborn
+		return info, nil
+	}
+	info.Commit = commit
+	return info, nil
+}
+
+// RebaseOrigin returns the branch being rebased and the commit it pointed at
+// before the rebase started, or empty strings when no rebase is in progress
+func RebaseOrigin(gitDir string) (branch, origHead string) {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		head, err := os.ReadFile(filepath.Join(gitDir, dir, "head-name"))
+		if err != nil {
+			continue
+		}
+		orig, _ := os.ReadFile(filepath.Join(gitDir, dir, "orig-head"))
+		return strings.TrimPrefix(strings.TrimSpace(string(head)), "refs/heads/"), strings.TrimSpace(string(orig))
+	}
+	return "", ""
+}
+
+// InProgressOperation reports which operation, if any, was left unfinished in gitDir.
+// The marker files live in the per-worktree git directory.
+func InProgressOperation(gitDir string) Operation {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+
+	switch {
+	case exists("rebase-merge"):
+		return OpRebase
+	case exists("rebase-apply"):
+		// git am 同样使用 rebase-apply 目录，通过 applying 文件区分
+		if exists(filepath.Join("rebase-apply", "applying")) {
+			return OpAm
+		}
+		return OpRebase
+	case exists("MERGE_HEAD"):
+		return OpMerge
+	case exists("CHERRY_PICK_HEAD"):
+		return OpCherryPick
+	case exists("REVERT_HEAD"):
+		return OpRevert
+	case exists("BISECT_LOG"):
+		return OpBisect
+	}
+	return OpNone
+}
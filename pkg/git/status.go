@@ -0,0 +1,216 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FileState classifies an entry of git status
+type FileState string
+
+const (
+	FileChanged    FileState = "changed"
+	FileRenamed    FileState = "renamed"
+	FileCopied     FileState = "copied"
+	FileConflicted FileState = "conflicted"
+	FileUntracked  FileState = "untracked"
+)
+
+// FileStatus is a single path reported by git status
+type FileStatus struct {
+	Path     string    `json:"path"`
+	OrigPath string    `json:"orig_path,omitempty"` // 重命名或复制前的路径
+	State    FileState `json:"state"`
+	Index    string    `json:"index,omitempty"`    // 暂存区状态码 (XY 中的 X)
+	WorkTree string    `json:"worktree,omitempty"` // 工作区状态码 (XY 中的 Y)
+}
+
+// ChangeCounts counts changed paths by type of change
+type ChangeCounts struct {
+	Modified    int `json:"modified"`
+	Added       int `json:"added"`
+	Deleted     int `json:"deleted"`
+	Renamed     int `json:"renamed"`
+	Copied      int `json:"copied"`
+	TypeChanged int `json:"type_changed"`
+}
+
+// Total returns the number of changed paths
+func (c ChangeCounts) Total() int {
+	return c.Modified + c.Added + c.Deleted + c.Renamed + c.Copied + c.TypeChanged
+}
+
+// count records one status code
+func (c *ChangeCounts) count(code byte) {
+	switch code {
+	case 'M':
+		c.Modified++
+	case 'A':
+		c.Added++
+	case 'D':
+		c.Deleted++
+	case 'R':
+		c.Renamed++
+	case 'C':
+		c.Copied++
+	case 'T':
+		c.TypeChanged++
+	}
+}
+
+// WorkingTreeStatus is the parsed output of git status --porcelain=v2 --branch
+type WorkingTreeStatus struct {
+	Head         string       `json:"head,omitempty"`   // HEAD 提交，新仓库为空
+	Branch       string       `json:"branch,omitempty"` // 分离 HEAD 时为空
+	Upstream     string       `json:"upstream,omitempty"`
+	UpstreamGone bool         `json:"upstream_gone,omitempty"` // 配置了上游但远程跟踪分支不存在
+	Ahead        int          `json:"ahead"`
+	Behind       int          `json:"behind"`
+	Staged       ChangeCounts `json:"staged"`
+	Unstaged     ChangeCounts `json:"unstaged"`
+	Conflicted   []string     `json:"conflicted,omitempty"`
+	Untracked    int          `json:"untracked"`
+	Ignored      int          `json:"ignored,omitempty"` // 只由 ReadWorkingTreeStatusWithIgnored 统计，不列入 Files
+	Stashes      int          `json:"stashes"`           // 需要 git 2.35 及以上，更早的版本始终为 0
+	Files        []FileStatus `json:"files,omitempty"`
+
+	hasAheadBehind bool // 输出中包含 branch.ab，即远程跟踪分支存在
+}
+
+// Clean reports whether there is nothing to commit; ignored files and stashes do not count
+func (s *WorkingTreeStatus) Clean() bool {
+	return s.Staged.Total() == 0 && s.Unstaged.Total() == 0 && len(s.Conflicted) == 0 && s.Untracked == 0
+}
+
+// ReadWorkingTreeStatus runs git status in porcelain v2 format and parses the result.
+// Ignored files are not examined; the stash count needs git 2.35 or later, older
+// versions accept --show-stash but do not report it in porcelain output.
+func ReadWorkingTreeStatus(ctx context.Context, r Runner, dir string) (*WorkingTreeStatus, error) {
+	return readWorkingTreeStatus(ctx, r, dir)
+}
+
+// ReadWorkingTreeStatusWithIgnored is like ReadWorkingTreeStatus but also counts
+// ignored paths. Ignored directories are counted once rather than file by file.
+func ReadWorkingTreeStatusWithIgnored(ctx context.Context, r Runner, dir string) (*WorkingTreeStatus, error) {
+	return readWorkingTreeStatus(ctx, r, dir, "--ignored=matching")
+}
+
+func readWorkingTreeStatus(ctx context.Context, r Runner, dir string, extra ...string) (*WorkingTreeStatus, error) {
+	args := append([]string{"status", "--porcelain=v2", "--branch", "--show-stash", "-z"}, extra...)
+	result, err := r.Run(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWorkingTreeStatus(result.Stdout)
+}
+
+// ParseWorkingTreeStatus parses NUL-separated git status --porcelain=v2 output
+func ParseWorkingTreeStatus(output string) (*WorkingTreeStatus, error) {
+	status := &WorkingTreeStatus{}
+	records := strings.Split(output, "\x00")
+
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if record == "" {
+			continue
+		}
+
+		switch record[0] {
+		case '#':
+			status.parseHeader(record)
+		case '1':
+			// 1 XY sub mH mI mW hH hI path
+			fields := strings.SplitN(record, " ", 9)
+			if len(fields) != 9 {
+				return nil, fmt.Errorf("无法解析状态记录: %q", record)
+			}
+			status.addChange(fields[1], fields[8], "")
+		case '2':
+			// 2 XY sub mH mI mW hH hI Xscore path，原路径在下一条记录中
+			fields := strings.SplitN(record, " ", 10)
+			if len(fields) != 10 || i+1 >= len(records) || records[i+1] == "" {
+				return nil, fmt.Errorf("无法解析状态记录: %q", record)
+			}
+			i++
+			status.addChange(fields[1], fields[9], records[i])
+		case 'u':
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			fields := strings.SplitN(record, " ", 11)
+			if len(fields) != 11 {
+				return nil, fmt.Errorf("无法解析状态记录: %q", record)
+			}
+			status.Conflicted = append(status.Conflicted, fields[10])
+			status.Files = append(status.Files, FileStatus{
+				Path:     fields[10],
+				State:    FileConflicted,
+				Index:    fields[1][:1],
+				WorkTree: fields[1][1:],
+			})
+		case '?':
+			status.Untracked++
+			status.Files = append(status.Files, FileStatus{Path: record[2:], State: FileUntracked})
+		case '!':
+			// 忽略的文件可能很多 (node_modules、构建输出)，只计数
+			status.Ignored++
+		default:
+			return nil, fmt.Errorf("未知的状态记录: %q", record)
+		}
+	}
+
+	if status.Upstream != "" && !status.hasAheadBehind {
+		status.UpstreamGone = true
+	}
+	return status, nil
+}
+
+// parseHeader reads a "# name value" header line
+func (s *WorkingTreeStatus) parseHeader(record string) {
+	name, value, _ := strings.Cut(strings.TrimPrefix(record, "# "), " ")
+	switch name {
+	case "branch.oid":
+		if value != "(initial)" {
+			s.Head = value
+		}
+	case "branch.head":
+		if value != "(detached)" {
+			s.Branch = value
+		}
+	case "branch.upstream":
+		s.Upstream = value
+	case "branch.ab":
+		// +ahead -behind
+		if ahead, behind, found := strings.Cut(value, " "); found {
+			s.Ahead, _ = strconv.Atoi(strings.TrimPrefix(ahead, "+"))
+			s.Behind, _ = strconv.Atoi(strings.TrimPrefix(behind, "-"))
+			s.hasAheadBehind = true
+		}
+	case "stash":
+		s.Stashes, _ = strconv.Atoi(value)
+	}
+}
+
+// addChange records an ordinary or renamed/copied entry with status code xy
+func (s *WorkingTreeStatus) addChange(xy, path, origPath string) {
+	if len(xy) != 2 {
+		return
+	}
+	s.Staged.count(xy[0])
+	s.Unstaged.count(xy[1])
+
+	state := FileChanged
+	switch {
+	case xy[0] == 'R' || xy[1] == 'R':
+		state = FileRenamed
+	case xy[0] == 'C' || xy[1] == 'C':
+		state = FileCopied
+	}
+	s.Files = append(s.Files, FileStatus{
+		Path:     path,
+		OrigPath: origPath,
+		State:    state,
+		Index:    xy[:1],
+		WorkTree: xy[1:],
+	})
+}
@@ -0,0 +1,164 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// porcelain joins status records the way git status -z separates them
+func porcelain(records ...string) string {
+	return strings.Join(records, "\x00") + "\x00"
+}
+
+func TestParseWorkingTreeStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		check  func(t *testing.T, s *WorkingTreeStatus)
+	}{
+		{
+			name: "clean branch with upstream",
+			output: porcelain(
+				"# branch.oid 1234567890abcdef",
+				"# branch.head main",
+				"# branch.upstream origin/main",
+				"# branch.ab +2 -3",
+				"# stash 4",
+			),
+			check: func(t *testing.T, s *WorkingTreeStatus) {
+				if s.Head != "1234567890abcdef" || s.Branch != "main" || s.Upstream != "origin/main" {
+					t.Errorf("head = %q, branch = %q, upstream = %q", s.Head, s.Branch, s.Upstream)
+				}
+				if s.Ahead != 2 || s.Behind != 3 || s.UpstreamGone {
+					t.Errorf("ahead = %d, behind = %d, gone = %v", s.Ahead, s.Behind, s.UpstreamGone)
+				}
+				if s.Stashes != 4 {
+					t.Errorf("stashes = %d, want 4", s.Stashes)
+				}
+				if !s.Clean() {
+					t.Error("Clean() = false, want true")
+				}
+			},
+		},
+		{
+			name: "initial commit and detached head",
+			output: porcelain(
+				"# branch.oid (initial)",
+				"# branch.head (detached)",
+			),
+			check: func(t *testing.T, s *WorkingTreeStatus) {
+				if s.Head != "" || s.Branch != "" {
+					t.Errorf("head = %q, branch = %q, want both empty", s.Head, s.Branch)
+				}
+			},
+		},
+		{
+			name: "upstream gone",
+			output: porcelain(
+				"# branch.oid abc",
+				"# branch.head feature",
+				"# branch.upstream origin/feature",
+			),
+			check: func(t *testing.T, s *WorkingTreeStatus) {
+				if !s.UpstreamGone {
+					t.Error("UpstreamGone = false, want true")
+				}
+			},
+		},
+		{
+			name: "changes",
+			output: porcelain(
+				"# branch.oid abc",
+				"# branch.head main",
+				"1 M. N... 100644 100644 100644 aaa bbb staged.go",
+				"1 .M N... 100644 100644 100644 aaa aaa unstaged file.go",
+				"1 AD N... 000000 100644 000000 000 bbb added-then-deleted.txt",
+				"1 .T N... 100644 120000 120000 aaa aaa link",
+				"2 R. N... 100644 100644 100644 aaa aaa R100 new name.go",
+				"old name.go",
+				"u UU N... 100644 100644 100644 100644 a b c conflict.go",
+				"? 新文件.txt",
+				"! build/",
+			),
+			check: func(t *testing.T, s *WorkingTreeStatus) {
+				staged := ChangeCounts{Modified: 1, Added: 1, Renamed: 1}
+				unstaged := ChangeCounts{Modified: 1, Deleted: 1, TypeChanged: 1}
+				if s.Staged != staged {
+					t.Errorf("Staged = %+v, want %+v", s.Staged, staged)
+				}
+				if s.Unstaged != unstaged {
+					t.Errorf("Unstaged = %+v, want %+v", s.Unstaged, unstaged)
+				}
+				if len(s.Conflicted) != 1 || s.Conflicted[0] != "conflict.go" {
+					t.Errorf("Conflicted = %v", s.Conflicted)
+				}
+				if s.Untracked != 1 || s.Ignored != 1 {
+					t.Errorf("untracked = %d, ignored = %d, want 1 and 1", s.Untracked, s.Ignored)
+				}
+				if s.Clean() {
+					t.Error("Clean() = true, want false")
+				}
+
+				// 忽略的文件只计数，不列入 Files
+				want := []FileStatus{
+					{Path: "staged.go", State: FileChanged, Index: "M", WorkTree: "."},
+					{Path: "unstaged file.go", State: FileChanged, Index: ".", WorkTree: "M"},
+					{Path: "added-then-deleted.txt", State: FileChanged, Index: "A", WorkTree: "D"},
+					{Path: "link", State: FileChanged, Index: ".", WorkTree: "T"},
+					{Path: "new name.go", OrigPath: "old name.go", State: FileRenamed, Index: "R", WorkTree: "."},
+					{Path: "conflict.go", State: FileConflicted, Index: "U", WorkTree: "U"},
+					{Path: "新文件.txt", State: FileUntracked},
+				}
+				if len(s.Files) != len(want) {
+					t.Fatalf("Files = %+v, want %d entries", s.Files, len(want))
+				}
+				for i := range want {
+					if s.Files[i] != want[i] {
+						t.Errorf("Files[%d] = %+v, want %+v", i, s.Files[i], want[i])
+					}
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := ParseWorkingTreeStatus(tt.output)
+			if err != nil {
+				t.Fatalf("ParseWorkingTreeStatus() error: %v", err)
+			}
+			tt.check(t, status)
+		})
+	}
+}
+
+func TestParseWorkingTreeStatusErrors(t *testing.T) {
+	for _, output := range []string{
+		porcelain("1 M. N... short"),
+		porcelain("2 R. N... 100644 100644 100644 aaa aaa R100 renamed-without-origin"),
+		porcelain("u UU N... too few fields"),
+		porcelain("x unknown"),
+	} {
+		if _, err := ParseWorkingTreeStatus(output); err == nil {
+			t.Errorf("ParseWorkingTreeStatus(%q) succeeded, want error", output)
+		}
+	}
+}
+
+func TestReadWorkingTreeStatusIgnored(t *testing.T) {
+	ctx := context.Background()
+	base := []string{"status", "--porcelain=v2", "--branch", "--show-stash", "-z"}
+	fake := NewFakeRunner()
+	fake.SetOutput("", porcelain("# branch.head main"), base...)
+	fake.SetOutput("", porcelain("# branch.head main", "! node_modules/"), append(base, "--ignored=matching")...)
+
+	status, err := ReadWorkingTreeStatus(ctx, fake, "/repo")
+	if err != nil || status.Ignored != 0 {
+		t.Errorf("ReadWorkingTreeStatus() = %+v, %v, want no ignored paths", status, err)
+	}
+	status, err = ReadWorkingTreeStatusWithIgnored(ctx, fake, "/repo")
+	if err != nil || status.Ignored != 1 || len(status.Files) != 0 {
+		t.Errorf("ReadWorkingTreeStatusWithIgnored() = %+v, %v, want one ignored path and no files", status, err)
+	}
+}
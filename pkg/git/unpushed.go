@@ -0,0 +1,82 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Remotes returns the names of the configured remotes
+func Remotes(ctx context.Context, r Runner, dir string) ([]string, error) {
+	out, err := Output(ctx, r, dir, "remote")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// CountUnpushed returns how many commits reachable from rev are not on any
+// remote-tracking branch, i.e. exist only in this clone
+func CountUnpushed(ctx context.Context, r Runner, dir, rev string) (int, error) {
+	out, err := Output(ctx, r, dir, "rev-list", "--count", rev, "--not", "--remotes")
+	if err != nil {
+		return 0, err
+	}
+	return ParseCount(out)
+}
+
+// LocalOnlyTags returns the tags that no remote has with the same name and
+// object. git keeps no remote-tracking refs for tags, so every remote is asked
+// with ls-remote; a tag on an already pushed commit may still exist only here.
+func LocalOnlyTags(ctx context.Context, r Runner, dir string) ([]string, error) {
+	out, err := Output(ctx, r, dir, "for-each-ref", "--format=%(refname)%00%(objectname)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+
+	remotes, err := Remotes(ctx, r, dir)
+	if err != nil {
+		return nil, err
+	}
+	// 远程仓库中的标签，键为 "引用\x00对象"
+	published := make(map[string]bool)
+	for _, remote := range remotes {
+		refs, err := Output(ctx, r, dir, "ls-remote", "--tags", remote)
+		if err != nil {
+			return nil, fmt.Errorf("读取远程 %s 的标签失败: %w", remote, err)
+		}
+		for _, line := range strings.Split(refs, "\n") {
+			if hash, ref, found := strings.Cut(line, "\t"); found {
+				published[ref+"\x00"+hash] = true
+			}
+		}
+	}
+
+	var tags []string
+	for _, line := range strings.Split(out, "\n") {
+		ref, _, found := strings.Cut(line, "\x00")
+		if !found {
+			return nil, fmt.Errorf("无法解析标签信息: %q", line)
+		}
+		// 同名标签指向不同对象时，本地的标签同样会丢失
+		if !published[line] {
+			tags = append(tags, strings.TrimPrefix(ref, "refs/tags/"))
+		}
+	}
+	return tags, nil
+}
+
+// StashList returns the stash entries, e.g. "stash@{0}: WIP on main: 1234567 message"
+func StashList(ctx context.Context, r Runner, dir string) ([]string, error) {
+	out, err := Output(ctx, r, dir, "stash", "list", "--format=%gd: %gs")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
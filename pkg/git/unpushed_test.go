@@ -0,0 +1,31 @@
+package git
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLocalOnlyTags(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFakeRunner()
+	fake.SetOutput("", "refs/tags/v1\x00aaa\nrefs/tags/v2\x00bbb\nrefs/tags/moved\x00ccc\nrefs/tags/local\x00ddd\n",
+		"for-each-ref", "--format=%(refname)%00%(objectname)", "refs/tags")
+	fake.SetOutput("", "origin\nbackup\n", "remote")
+	fake.SetOutput("", "aaa\trefs/tags/v1\naaa1\trefs/tags/v1^{}\neee\trefs/tags/moved\n", "ls-remote", "--tags", "origin")
+	fake.SetOutput("", "bbb\trefs/tags/v2\n", "ls-remote", "--tags", "backup")
+
+	tags, err := LocalOnlyTags(ctx, fake, "/repo")
+	if err != nil {
+		t.Fatalf("LocalOnlyTags() error: %v", err)
+	}
+	// 远程的同名标签指向其他对象时也视为只存在于本地
+	want := []string{"moved", "local"}
+	if len(tags) != len(want) || tags[0] != want[0] || tags[1] != want[1] {
+		t.Errorf("LocalOnlyTags() = %v, want %v", tags, want)
+	}
+
+	fake.Set("", FakeResponse{Stderr: "fatal: unable to access", ExitCode: 128}, "ls-remote", "--tags", "backup")
+	if _, err := LocalOnlyTags(ctx, fake, "/repo"); err == nil {
+		t.Error("LocalOnlyTags() succeeded although a remote could not be read")
+	}
+}
@@ -0,0 +1,86 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"reposense/pkg/updater"
+)
+
+// ReportBranchResults prints one row per repository with the outcome of each
+// phase of a branch operation: check, apply and rollback
+func (r *Reporter) ReportBranchResults(options updater.BranchOptions, results []updater.UpdateResult) {
+	if r.format == FormatJSON {
+		r.printJSON(map[string]interface{}{
+			"operation":      options.Operation,
+			"branch":         options.Name,
+			"branch_results": results,
+			"timestamp":      time.Now(),
+		})
+		return
+	}
+
+	fmt.Printf("分支操作 %s %s (%d个仓库):\n", options.Operation, options.Name, len(results))
+	fmt.Printf("%-30s %-6s %-6s %-6s %s\n", "仓库名称", "预检", "执行", "回滚", "说明")
+	fmt.Println(strings.Repeat("-", 90))
+
+	applied, skipped, failed, rolledBack := 0, 0, 0, 0
+	for _, result := range results {
+		op := result.BranchOp
+		if op == nil {
+			continue
+		}
+
+		check := "✓"
+		switch op.Check {
+		case updater.CheckNotNeeded:
+			check = "-"
+		case updater.CheckBlocked:
+			check = "✗"
+		}
+
+		apply := "-"
+		switch {
+		case op.Applied:
+			apply = "✓"
+			applied++
+		case result.Status == updater.StatusFailed && op.Check == updater.CheckReady:
+			apply = "✗"
+		}
+		switch result.Status {
+		case updater.StatusSkipped:
+			skipped++
+		case updater.StatusFailed:
+			failed++
+		}
+
+		rollback := ""
+		switch {
+		case op.RolledBack:
+			rollback = "↶"
+			rolledBack++
+		case op.RollbackError != "":
+			rollback = "✗"
+		}
+
+		name := result.Repository.Name
+		if len(name) > 28 {
+			name = name[:25] + "..."
+		}
+		message, _, _ := strings.Cut(result.Message, "\n")
+		fmt.Printf("%-30s %-6s %-6s %-6s %s\n", name, check, apply, rollback, message)
+		if r.verbose && result.Error != "" {
+			fmt.Printf("   错误 [%s]: %s\n", result.ErrorCode, result.Error)
+		}
+		if op.RollbackError != "" {
+			fmt.Printf("   回滚错误: %s\n", op.RollbackError)
+		}
+	}
+
+	fmt.Printf("\n已执行: %d, 跳过: %d, 失败: %d", applied, skipped, failed)
+	if rolledBack > 0 {
+		fmt.Printf(", 已回滚: %d", rolledBack)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,62 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"reposense/pkg/updater"
+)
+
+// ReportExecResults reports the output and exit codes of a command run in each repository.
+// Output is printed grouped by repository unless it was already streamed.
+func (r *Reporter) ReportExecResults(results []updater.UpdateResult, streamed bool) {
+	if r.format == FormatJSON {
+		r.printJSON(map[string]interface{}{
+			"exec_results": results,
+			"timestamp":    time.Now(),
+		})
+		return
+	}
+
+	if !streamed {
+		for _, result := range results {
+			if result.Exec == nil || result.Exec.Output == "" {
+				continue
+			}
+			fmt.Printf("━━ %s (%s)\n", result.Repository.Name, result.Repository.Path)
+			fmt.Println(result.Exec.Output)
+			fmt.Println()
+		}
+	}
+
+	fmt.Printf("%-30s %-8s %-10s %s\n", "仓库名称", "退出码", "耗时", "结果")
+	fmt.Println(strings.Repeat("-", 80))
+
+	successful, skipped, failed := 0, 0, 0
+	for _, result := range results {
+		mark := "✓"
+		switch result.Status {
+		case updater.StatusSkipped:
+			mark = "⏭"
+			skipped++
+		case updater.StatusFailed:
+			mark = "✗"
+			failed++
+		default:
+			successful++
+		}
+
+		name := result.Repository.Name
+		if len(name) > 28 {
+			name = name[:25] + "..."
+		}
+		exitCode := "-"
+		if result.Exec != nil && result.Exec.ExitCode >= 0 {
+			exitCode = fmt.Sprintf("%d", result.Exec.ExitCode)
+		}
+		fmt.Printf("%-30s %-8s %-10s %s %s\n", name, exitCode,
+			formatDuration(result.Duration), mark, result.Message)
+	}
+	fmt.Printf("\n成功: %d, 跳过: %d, 失败: %d\n", successful, skipped, failed)
+}
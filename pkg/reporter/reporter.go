@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"reposense/pkg/git"
 	"reposense/pkg/scanner"
 	"reposense/pkg/updater"
 
@@ -52,6 +53,7 @@ func NewReporter(format ReportFormat, verbose bool) *Reporter {
 // InitProgressBar initializes progress bar for updates
 func (r *Reporter) InitProgressBar(total int, description string) {
 	r.progressBar = progressbar.NewOptions(total,
+		progressbar.OptionSetWriter(r.progressWriter()),
 		progressbar.OptionSetDescription(description),
 		progressbar.OptionSetWidth(50),
 		progressbar.OptionShowCount(),
@@ -72,10 +74,18 @@ func (r *Reporter) UpdateProgress() {
 func (r *Reporter) FinishProgress() {
 	if r.progressBar != nil {
 		r.progressBar.Finish()
-		fmt.Println() // 添加换行
+		fmt.Fprintln(r.progressWriter()) // 添加换行
 	}
 }
 
+// progressWriter returns where the progress bar is drawn; JSON output keeps stdout for the report
+func (r *Reporter) progressWriter() *os.File {
+	if r.format == FormatJSON {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
 // ReportScanResults reports repository scan results
 func (r *Reporter) ReportScanResults(repositories []scanner.Repository) {
 	total := len(repositories)
@@ -105,8 +115,10 @@ func (r *Reporter) ReportUpdateResults(results []updater.UpdateResult) {
 		r.reportUpdateResultsText(results)
 	}
 	
-	// 显示统计信息
-	r.reportStatistics(results)
+	// 显示统计信息，JSON 结果中已包含每个仓库的状态
+	if r.format != FormatJSON {
+		r.reportStatistics(results)
+	}
 }
 
 // ReportStatusResults reports repository status results
@@ -121,18 +133,41 @@ func (r *Reporter) ReportStatusResults(statuses []scanner.RepositoryStatus) {
 	}
 }
 
-// ReportListResults reports repository list results with descriptions
-func (r *Reporter) ReportListResults(repositories []scanner.RepositoryWithDescription, sortByTime, reverse bool) {
+// ReportAuditResults reports the unpushed-work audit collected with StatusCollector.SetAudit
+func (r *Reporter) ReportAuditResults(statuses []scanner.RepositoryStatus) {
+	switch r.format {
+	case FormatJSON:
+		r.reportAuditResultsJSON(statuses)
+	case FormatTable:
+		r.reportAuditResultsTable(statuses)
+	default:
+		r.reportAuditResultsText(statuses)
+	}
+}
+
+// ReportInventoryDiff reports repositories added, removed or moved since the last scan
+func (r *Reporter) ReportInventoryDiff(diff *scanner.InventoryDiff) {
+	switch r.format {
+	case FormatJSON:
+		r.reportInventoryDiffJSON(diff)
+	default:
+		r.reportInventoryDiffText(diff)
+	}
+}
+
+// ReportListResults reports repository list results with descriptions, optionally ordered by the commit date selected by sortKey
+func (r *Reporter) ReportListResults(repositories []scanner.RepositoryWithDescription, sortByTime bool, sortKey scanner.SortKey, reverse bool) {
 	// 排序
 	sortedRepos := make([]scanner.RepositoryWithDescription, len(repositories))
 	copy(sortedRepos, repositories)
 	
 	if sortByTime {
-		sort.Slice(sortedRepos, func(i, j int) bool {
+		sort.SliceStable(sortedRepos, func(i, j int) bool {
+			ti, tj := sortedRepos[i].SortTime(sortKey), sortedRepos[j].SortTime(sortKey)
 			if reverse {
-				return sortedRepos[i].LastCommitDate.After(sortedRepos[j].LastCommitDate)
+				return ti.After(tj)
 			}
-			return sortedRepos[i].LastCommitDate.Before(sortedRepos[j].LastCommitDate)
+			return ti.Before(tj)
 		})
 	} else {
 		sort.Slice(sortedRepos, func(i, j int) bool {
@@ -159,9 +194,12 @@ func (r *Reporter) reportScanResultsText(repositories []scanner.Repository) {
 	fmt.Println(strings.Repeat("-", 60))
 	
 	for i, repo := range repositories {
-		fmt.Printf("%d. %s\n", i+1, repo.Name)
+		fmt.Printf("%d. %s%s\n", i+1, repo.Name, kindLabel(repo.Kind))
 		if r.verbose {
 			fmt.Printf("   路径: %s\n", repo.Path)
+			if repo.Parent != "" {
+				fmt.Printf("   所属仓库: %s\n", repo.Parent)
+			}
 		}
 	}
 	fmt.Println()
@@ -169,8 +207,8 @@ func (r *Reporter) reportScanResultsText(repositories []scanner.Repository) {
 
 // reportScanResultsTable reports scan results in table format
 func (r *Reporter) reportScanResultsTable(repositories []scanner.Repository) {
-	fmt.Printf("%-4s %-30s %s\n", "序号", "仓库名称", "路径")
-	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-4s %-30s %-10s %s\n", "序号", "仓库名称", "类型", "路径")
+	fmt.Println(strings.Repeat("-", 90))
 	
 	for i, repo := range repositories {
 		name := repo.Name
@@ -178,12 +216,17 @@ func (r *Reporter) reportScanResultsTable(repositories []scanner.Repository) {
 			name = name[:25] + "..."
 		}
 		
+		kind := string(repo.Kind)
+		if kind == "" {
+			kind = string(scanner.KindNormal)
+		}
+		
 		path := repo.Path
 		if len(path) > 45 {
 			path = "..." + path[len(path)-42:]
 		}
 		
-		fmt.Printf("%-4d %-30s %s\n", i+1, name, path)
+		fmt.Printf("%-4d %-30s %-10s %s\n", i+1, name, kind, path)
 	}
 	fmt.Println()
 }
@@ -200,35 +243,109 @@ func (r *Reporter) reportScanResultsJSON(repositories []scanner.Repository) {
 	fmt.Println(string(jsonData))
 }
 
+// reportInventoryDiffText reports inventory changes in text format
+func (r *Reporter) reportInventoryDiffText(diff *scanner.InventoryDiff) {
+	if diff.Empty() {
+		fmt.Printf("与上次扫描相比没有变化 (%d个仓库)\n\n", diff.Unchanged)
+		return
+	}
+	
+	fmt.Printf("与上次扫描相比: 新增 %d，删除 %d，移动 %d，未变 %d\n",
+		len(diff.Added), len(diff.Removed), len(diff.Moved), diff.Unchanged)
+	fmt.Println(strings.Repeat("-", 60))
+	
+	for _, entry := range diff.Added {
+		fmt.Printf("+ %s  %s\n", entry.Name, entry.Path)
+	}
+	for _, entry := range diff.Removed {
+		fmt.Printf("- %s  %s\n", entry.Name, entry.Path)
+	}
+	for _, move := range diff.Moved {
+		fmt.Printf("→ %s  %s -> %s\n", move.To.Name, move.From.Path, move.To.Path)
+	}
+	fmt.Println()
+}
+
+// reportInventoryDiffJSON reports inventory changes in JSON format
+func (r *Reporter) reportInventoryDiffJSON(diff *scanner.InventoryDiff) {
+	output := map[string]interface{}{
+		"inventory_diff": diff,
+		"timestamp":      time.Now(),
+	}
+	
+	jsonData, _ := json.MarshalIndent(output, "", "  ")
+	fmt.Println(string(jsonData))
+}
+
 // reportUpdateResultsText reports update results in text format
 func (r *Reporter) reportUpdateResultsText(results []updater.UpdateResult) {
 	fmt.Printf("更新结果 (%d个仓库):\n", len(results))
 	fmt.Println(strings.Repeat("-", 80))
 	
 	successful := 0
+	skipped := 0
 	failed := 0
 	
 	for _, result := range results {
 		status := "✓"
-		if !result.Success {
+		switch result.Status {
+		case updater.StatusSkipped:
+			status = "⏭"
+			skipped++
+		case updater.StatusFailed:
 			status = "✗"
 			failed++
-		} else {
+		default:
 			successful++
 		}
 		
 		fmt.Printf("%s %s: %s", status, result.Repository.Name, result.Message)
+		if result.Attempts > 1 {
+			fmt.Printf(" (共尝试 %d 次)", result.Attempts)
+		}
 		if r.verbose {
 			fmt.Printf(" (耗时: %s)", formatDuration(result.Duration))
 		}
 		fmt.Println()
 		
 		if !result.Success && result.Error != "" {
-			fmt.Printf("   错误: %s\n", result.Error)
+			fmt.Printf("   错误 [%s]: %s\n", result.ErrorCode, result.Error)
+		}
+		
+		if r.verbose && result.Changes != nil {
+			printPullChanges(result.Changes)
+		}
+		for _, branch := range result.Branches {
+			switch branch.Status {
+			case updater.BranchFastForwarded:
+				fmt.Printf("   ⏩ %s: %s (%s..%s)\n", branch.Branch, branch.Message, git.ShortHash(branch.Old), git.ShortHash(branch.New))
+			case updater.BranchDiverged:
+				fmt.Printf("   🔀 %s: %s\n", branch.Branch, branch.Message)
+			default:
+				fmt.Printf("   ✗ %s: %s\n", branch.Branch, branch.Message)
+			}
+		}
+		for _, hook := range result.Hooks {
+			printHookResult(hook, r.verbose)
+		}
+		
+		if fetch := result.Fetch; fetch != nil {
+			for _, update := range fetch.Updated {
+				fmt.Printf("   ↻ %s: %s..%s (%d个新提交)\n", shortRef(update.Ref), git.ShortHash(update.Old), git.ShortHash(update.New), update.Commits)
+			}
+			for _, ref := range fetch.Added {
+				fmt.Printf("   + %s\n", shortRef(ref))
+			}
+			for _, ref := range fetch.Deleted {
+				fmt.Printf("   - %s (分支已在远程删除)\n", shortRef(ref))
+			}
+			for _, ref := range fetch.DeletedTags {
+				fmt.Printf("   - %s (标签已在远程删除)\n", shortRef(ref))
+			}
 		}
 	}
 	
-	fmt.Printf("\n成功: %d, 失败: %d\n", successful, failed)
+	fmt.Printf("\n成功: %d, 跳过: %d, 失败: %d\n", successful, skipped, failed)
 }
 
 // reportUpdateResultsTable reports update results in table format
@@ -238,7 +355,10 @@ func (r *Reporter) reportUpdateResultsTable(results []updater.UpdateResult) {
 	
 	for i, result := range results {
 		status := "成功"
-		if !result.Success {
+		switch result.Status {
+		case updater.StatusSkipped:
+			status = "跳过"
+		case updater.StatusFailed:
 			status = "失败"
 		}
 		
@@ -277,13 +397,23 @@ func (r *Reporter) reportStatusResultsText(statuses []scanner.RepositoryStatus)
 	fmt.Println(strings.Repeat("-", 80))
 	
 	for _, status := range statuses {
-		fmt.Printf("📁 %s (%s)\n", status.Repository.Name, status.Branch)
+		fmt.Printf("📁 %s (%s)%s\n", status.Repository.Name, branchLabel(status), kindLabel(status.Repository.Kind))
 		
 		if status.Error != "" {
 			fmt.Printf("   ❌ 错误: %s\n", status.Error)
 			continue
 		}
 		
+		if status.Operation != git.OpNone {
+			fmt.Printf("   🚧 未完成的操作: %s，请先完成或中止\n", status.Operation.Description())
+		}
+		switch status.Head.State {
+		case git.HeadDetached:
+			fmt.Printf("   📍 HEAD 处于分离状态: %s\n", status.Head.ShortCommit())
+		case git.HeadUnborn:
+			fmt.Printf("   🌱 分支 %s 尚无任何提交\n", status.Head.Branch)
+		}
+		
 		if status.LastCommitMsg != "" {
 			msg := status.LastCommitMsg
 			if len(msg) > 50 {
@@ -302,8 +432,47 @@ func (r *Reporter) reportStatusResultsText(statuses []scanner.RepositoryStatus)
 			fmt.Printf("   ✅ 工作区: 干净\n")
 		}
 		
-		if status.Behind > 0 || status.Ahead > 0 {
-			fmt.Printf("   🔀 远程差异: 领先%d个提交, 落后%d个提交\n", status.Ahead, status.Behind)
+		if wt := status.WorkingTree; wt != nil {
+			if len(wt.Conflicted) > 0 {
+				fmt.Printf("   ⚔️  冲突文件: %s\n", strings.Join(wt.Conflicted, ", "))
+			}
+			if wt.Stashes > 0 {
+				fmt.Printf("   📦 贮藏: %d个\n", wt.Stashes)
+			}
+			if r.verbose && wt.Ignored > 0 {
+				fmt.Printf("   🙈 已忽略: %d个\n", wt.Ignored)
+			}
+		}
+		
+		if status.Upstream != "" {
+			if status.Behind > 0 || status.Ahead > 0 {
+				fmt.Printf("   🔀 上游 %s: 领先%d个提交, 落后%d个提交\n", status.Upstream, status.Ahead, status.Behind)
+			} else {
+				fmt.Printf("   🔀 上游 %s: 已同步\n", status.Upstream)
+			}
+			fmt.Printf("   📡 上次获取: %s\n", formatAge(status.LastFetch))
+		} else if status.Repository.Kind.HasWorkTree() {
+			fmt.Printf("   🔀 上游: 未设置\n")
+		}
+		
+		if status.FetchError != "" {
+			fmt.Printf("   ⚠️  获取远程更新失败: %s\n", status.FetchError)
+		}
+		
+		if len(status.Branches) > 0 {
+			defaultBranch := status.DefaultBranch
+			if defaultBranch == "" {
+				defaultBranch = "未知"
+			}
+			fmt.Printf("   🌿 本地分支 (默认分支: %s):\n", defaultBranch)
+			for _, branch := range status.Branches {
+				marker := " "
+				if branch.Current {
+					marker = "*"
+				}
+				fmt.Printf("     %s %-20s %-28s %s  %s\n", marker, branch.Name, branchUpstreamLabel(branch),
+					branch.LastCommitDate.Format("2006-01-02"), branchStateLabel(branch, status.DefaultBranch))
+			}
 		}
 		
 		fmt.Println()
@@ -312,8 +481,8 @@ func (r *Reporter) reportStatusResultsText(statuses []scanner.RepositoryStatus)
 
 // reportStatusResultsTable reports status results in table format
 func (r *Reporter) reportStatusResultsTable(statuses []scanner.RepositoryStatus) {
-	fmt.Printf("%-25s %-15s %-15s %-10s %-20s\n", "仓库名称", "分支", "工作区状态", "远程差异", "最后提交")
-	fmt.Println(strings.Repeat("-", 100))
+	fmt.Printf("%-25s %-15s %-15s %-20s %-10s %-12s %-20s\n", "仓库名称", "分支", "工作区状态", "上游", "远程差异", "上次获取", "最后提交")
+	fmt.Println(strings.Repeat("-", 130))
 	
 	for _, status := range statuses {
 		name := status.Repository.Name
@@ -322,6 +491,9 @@ func (r *Reporter) reportStatusResultsTable(statuses []scanner.RepositoryStatus)
 		}
 		
 		branch := status.Branch
+		if status.Head.State == git.HeadDetached {
+			branch = "(" + status.Head.ShortCommit() + ")"
+		}
 		if len(branch) > 13 {
 			branch = branch[:10] + "..."
 		}
@@ -330,18 +502,64 @@ func (r *Reporter) reportStatusResultsTable(statuses []scanner.RepositoryStatus)
 		if status.HasChanges {
 			workStatus = "有变更"
 		}
+		if status.WorkingTree != nil && len(status.WorkingTree.Conflicted) > 0 {
+			workStatus = "有冲突"
+		}
+		if status.Operation != git.OpNone {
+			workStatus = string(status.Operation) + "中"
+		}
 		if status.Error != "" {
 			workStatus = "错误"
 		}
 		
-		remoteDiff := fmt.Sprintf("+%d/-%d", status.Ahead, status.Behind)
+		upstream := status.Upstream
+		if len(upstream) > 18 {
+			upstream = upstream[:15] + "..."
+		}
+		
+		remoteDiff := "-"
+		lastFetch := "-"
+		if status.Upstream != "" {
+			remoteDiff = fmt.Sprintf("+%d/-%d", status.Ahead, status.Behind)
+			lastFetch = formatAge(status.LastFetch)
+		}
 		
 		lastCommit := ""
 		if !status.LastCommitDate.IsZero() {
 			lastCommit = status.LastCommitDate.Format("01-02 15:04")
 		}
 		
-		fmt.Printf("%-25s %-15s %-15s %-10s %-20s\n", name, branch, workStatus, remoteDiff, lastCommit)
+		fmt.Printf("%-25s %-15s %-15s %-20s %-10s %-12s %-20s\n", name, branch, workStatus, upstream, remoteDiff, lastFetch, lastCommit)
+	}
+	fmt.Println()
+	
+	r.reportBranchesTable(statuses)
+}
+
+// reportBranchesTable lists the local branches collected with status --branches
+func (r *Reporter) reportBranchesTable(statuses []scanner.RepositoryStatus) {
+	hasBranches := false
+	for _, status := range statuses {
+		if len(status.Branches) > 0 {
+			hasBranches = true
+			break
+		}
+	}
+	if !hasBranches {
+		return
+	}
+	
+	fmt.Printf("%-25s %-25s %-30s %-12s %-20s\n", "仓库名称", "分支", "上游", "最后提交", "状态")
+	fmt.Println(strings.Repeat("-", 110))
+	for _, status := range statuses {
+		for _, branch := range status.Branches {
+			name := branch.Name
+			if branch.Current {
+				name = "* " + name
+			}
+			fmt.Printf("%-25s %-25s %-30s %-12s %-20s\n", status.Repository.Name, name, branchUpstreamLabel(branch),
+				branch.LastCommitDate.Format("2006-01-02"), branchStateLabel(branch, status.DefaultBranch))
+		}
 	}
 	fmt.Println()
 }
@@ -358,6 +576,138 @@ func (r *Reporter) reportStatusResultsJSON(statuses []scanner.RepositoryStatus)
 	fmt.Println(string(jsonData))
 }
 
+// reportAuditResultsText reports audit results in text format
+func (r *Reporter) reportAuditResultsText(statuses []scanner.RepositoryStatus) {
+	fmt.Printf("未推送工作审计 (%d个仓库, %d个不安全):\n", len(statuses), countUnsafe(statuses))
+	fmt.Println(strings.Repeat("-", 80))
+	
+	for _, status := range statuses {
+		if status.AuditSafe() {
+			fmt.Printf("✅ %s: 安全\n", status.Repository.Name)
+			continue
+		}
+		
+		fmt.Printf("❌ %s: 不安全 (%s)\n", status.Repository.Name, status.Repository.Path)
+		if status.Audit == nil {
+			fmt.Printf("   - [%s] %s\n", auditKindLabel(scanner.AuditUnverifiable), status.Error)
+			continue
+		}
+		for _, item := range status.Audit.Items {
+			if item.Name != "" {
+				fmt.Printf("   - [%s] %s: %s\n", auditKindLabel(item.Kind), item.Name, item.Detail)
+			} else {
+				fmt.Printf("   - [%s] %s\n", auditKindLabel(item.Kind), item.Detail)
+			}
+		}
+	}
+	fmt.Println()
+}
+
+// reportAuditResultsTable reports audit results in table format
+func (r *Reporter) reportAuditResultsTable(statuses []scanner.RepositoryStatus) {
+	fmt.Printf("%-25s %-8s %-8s %-8s %-8s %-10s %-8s %-8s\n", "仓库名称", "结论", "未提交", "未跟踪", "贮藏", "未推送分支", "标签", "其他")
+	fmt.Println(strings.Repeat("-", 100))
+	
+	for _, status := range statuses {
+		name := status.Repository.Name
+		if len(name) > 23 {
+			name = name[:20] + "..."
+		}
+		
+		verdict := "安全"
+		if !status.AuditSafe() {
+			verdict = "不安全"
+		}
+		
+		counts := make(map[scanner.AuditItemKind]int)
+		if status.Audit != nil {
+			for _, item := range status.Audit.Items {
+				counts[item.Kind]++
+			}
+		} else {
+			counts[scanner.AuditUnverifiable]++
+		}
+		
+		branches := counts[scanner.AuditUnpushedCommits] + counts[scanner.AuditLocalBranch] + counts[scanner.AuditDetachedCommits]
+		uncommitted := 0
+		if counts[scanner.AuditUncommitted] > 0 && status.WorkingTree != nil {
+			uncommitted = status.WorkingTree.Staged.Total() + status.WorkingTree.Unstaged.Total() + len(status.WorkingTree.Conflicted)
+		}
+		
+		fmt.Printf("%-25s %-8s %-8d %-8d %-8d %-10d %-8d %-8d\n", name, verdict, uncommitted, counts[scanner.AuditUntracked],
+			counts[scanner.AuditStash], branches, counts[scanner.AuditLocalTag], counts[scanner.AuditUnverifiable])
+	}
+	fmt.Println()
+}
+
+// reportAuditResultsJSON reports audit results in JSON format
+func (r *Reporter) reportAuditResultsJSON(statuses []scanner.RepositoryStatus) {
+	type auditResult struct {
+		Repository scanner.Repository  `json:"repository"`
+		Safe       bool                `json:"safe"`
+		Items      []scanner.AuditItem `json:"items"`
+		Error      string              `json:"error,omitempty"`
+	}
+	
+	results := make([]auditResult, 0, len(statuses))
+	for _, status := range statuses {
+		result := auditResult{
+			Repository: status.Repository,
+			Safe:       status.AuditSafe(),
+			Items:      []scanner.AuditItem{},
+			Error:      status.Error,
+		}
+		if status.Audit != nil {
+			result.Items = status.Audit.Items
+		}
+		results = append(results, result)
+	}
+	
+	output := map[string]interface{}{
+		"audit_results": results,
+		"total":         len(statuses),
+		"unsafe":        countUnsafe(statuses),
+		"timestamp":     time.Now(),
+	}
+	
+	jsonData, _ := json.MarshalIndent(output, "", "  ")
+	fmt.Println(string(jsonData))
+}
+
+// countUnsafe counts repositories whose audit found work that exists nowhere else
+func countUnsafe(statuses []scanner.RepositoryStatus) int {
+	unsafe := 0
+	for _, status := range statuses {
+		if !status.AuditSafe() {
+			unsafe++
+		}
+	}
+	return unsafe
+}
+
+// auditKindLabel returns a short label for an audit item kind
+func auditKindLabel(kind scanner.AuditItemKind) string {
+	switch kind {
+	case scanner.AuditUncommitted:
+		return "未提交变更"
+	case scanner.AuditUntracked:
+		return "未跟踪文件"
+	case scanner.AuditStash:
+		return "贮藏"
+	case scanner.AuditUnpushedCommits:
+		return "未推送提交"
+	case scanner.AuditLocalBranch:
+		return "本地分支"
+	case scanner.AuditDetachedCommits:
+		return "游离提交"
+	case scanner.AuditLocalTag:
+		return "本地标签"
+	case scanner.AuditUnverifiable:
+		return "无法确认"
+	}
+	return string(kind)
+}
+
 // reportStatistics reports update statistics
 func (r *Reporter) reportStatistics(results []updater.UpdateResult) {
 	if len(results) == 0 {
@@ -365,31 +715,151 @@ func (r *Reporter) reportStatistics(results []updater.UpdateResult) {
 	}
 	
 	successful := 0
+	skipped := 0
 	failed := 0
 	var totalDuration time.Duration
 	
 	for _, result := range results {
-		if result.Success {
-			successful++
-		} else {
+		switch result.Status {
+		case updater.StatusSkipped:
+			skipped++
+		case updater.StatusFailed:
 			failed++
+		default:
+			successful++
 		}
 		totalDuration += result.Duration
 	}
 	
 	avgDuration := totalDuration / time.Duration(len(results))
-	successRate := float64(successful) / float64(len(results)) * 100
+	percent := func(n int) float64 {
+		return float64(n) / float64(len(results)) * 100
+	}
 	
 	fmt.Println(strings.Repeat("=", 60))
 	fmt.Println("📊 统计信息:")
 	fmt.Printf("   总计: %d 个仓库\n", len(results))
-	fmt.Printf("   成功: %d 个 (%.1f%%)\n", successful, successRate)
-	fmt.Printf("   失败: %d 个 (%.1f%%)\n", failed, 100-successRate)
+	fmt.Printf("   成功: %d 个 (%.1f%%)\n", successful, percent(successful))
+	fmt.Printf("   跳过: %d 个 (%.1f%%)\n", skipped, percent(skipped))
+	fmt.Printf("   失败: %d 个 (%.1f%%)\n", failed, percent(failed))
 	fmt.Printf("   总耗时: %s\n", formatDuration(totalDuration))
 	fmt.Printf("   平均耗时: %s\n", formatDuration(avgDuration))
 	fmt.Println(strings.Repeat("=", 60))
 }
 
+// kindLabel returns a short suffix for repositories that are not plain clones
+func kindLabel(kind scanner.RepositoryKind) string {
+	switch kind {
+	case scanner.KindWorktree:
+		return " [worktree]"
+	case scanner.KindSubmodule:
+		return " [submodule]"
+	case scanner.KindBare:
+		return " [bare]"
+	default:
+		return ""
+	}
+}
+
+// branchLabel returns the branch name, or a description of a detached or unborn HEAD
+func branchLabel(status scanner.RepositoryStatus) string {
+	switch status.Head.State {
+	case git.HeadDetached:
+		return "分离于 " + status.Head.ShortCommit()
+	case git.HeadUnborn:
+		return status.Head.Branch + ", 无提交"
+	}
+	return status.Branch
+}
+
+// branchUpstreamLabel describes the upstream of a branch and how far it diverged
+func branchUpstreamLabel(branch git.BranchInfo) string {
+	switch {
+	case branch.Upstream == "":
+		return "无上游"
+	case branch.UpstreamGone:
+		return branch.Upstream + " (已删除)"
+	}
+	return fmt.Sprintf("%s +%d/-%d", branch.Upstream, branch.Ahead, branch.Behind)
+}
+
+// branchStateLabel tells whether a branch still holds work that exists nowhere else
+func branchStateLabel(branch git.BranchInfo, defaultBranch string) string {
+	switch {
+	case branch.Name == defaultBranch:
+		return "默认分支"
+	case branch.HasUnpushedWork():
+		return "⚠️ 有未推送的提交"
+	case branch.Merged:
+		return "已合并"
+	}
+	return "已推送"
+}
+
+// shortRef strips the refs/remotes/, refs/tags/ or refs/heads/ prefix
+func shortRef(ref string) string {
+	for _, prefix := range []string{"refs/remotes/", "refs/tags/", "refs/heads/"} {
+		if strings.HasPrefix(ref, prefix) {
+			return strings.TrimPrefix(ref, prefix)
+		}
+	}
+	return ref
+}
+
+// printPullChanges lists the commits, files, tags and manifests a pull brought in
+func printPullChanges(changes *updater.PullChanges) {
+	if changes.OldHead != changes.NewHead {
+		fmt.Printf("   ↳ %s..%s: %d个新提交, %d个文件 (+%d -%d)\n",
+			git.ShortHash(changes.OldHead), git.ShortHash(changes.NewHead),
+			changes.CommitCount, changes.FilesChanged, changes.Insertions, changes.Deletions)
+		for _, commit := range changes.Commits {
+			fmt.Printf("     • %s %s (%s)\n", git.ShortHash(commit.Hash), commit.Subject, commit.Author)
+		}
+		if more := changes.CommitCount - len(changes.Commits); more > 0 {
+			fmt.Printf("     … 另有 %d 个提交\n", more)
+		}
+	}
+	if len(changes.NewTags) > 0 {
+		fmt.Printf("   🏷️  新标签: %s\n", strings.Join(changes.NewTags, ", "))
+	}
+	if len(changes.Manifests) > 0 {
+		fmt.Printf("   📦 依赖清单有变化: %s\n", strings.Join(changes.Manifests, ", "))
+	}
+}
+
+// printHookResult shows a hook run; output is shown for failures, or always in verbose mode
+func printHookResult(hook updater.HookResult, verbose bool) {
+	if hook.Success {
+		fmt.Printf("   🪝 %s: 完成 (%s, 由 %s 触发)\n", hook.Name, formatDuration(hook.Duration), hook.Trigger)
+	} else {
+		fmt.Printf("   🪝 %s: 失败 (%s): %s\n", hook.Name, formatDuration(hook.Duration), hook.Error)
+	}
+	if hook.Output != "" && (verbose || !hook.Success) {
+		for _, line := range strings.Split(hook.Output, "\n") {
+			fmt.Printf("     │ %s\n", line)
+		}
+	}
+}
+
+// formatAge describes how long ago t was, e.g. "3小时前"
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "从未获取"
+	}
+	
+	age := time.Since(t)
+	switch {
+	case age < time.Minute:
+		return "刚刚"
+	case age < time.Hour:
+		return fmt.Sprintf("%d分钟前", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%d小时前", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%d天前", int(age.Hours()/24))
+	}
+}
+
 // formatDuration formats duration to a readable string
 func formatDuration(d time.Duration) string {
 	if d == 0 {
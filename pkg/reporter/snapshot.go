@@ -0,0 +1,120 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"reposense/pkg/cache"
+	"reposense/pkg/updater"
+)
+
+// ReportSnapshotSaved reports the repositories recorded in a new snapshot
+func (r *Reporter) ReportSnapshotSaved(snapshot *cache.Snapshot, results []updater.UpdateResult) {
+	if r.format == FormatJSON {
+		r.printJSON(map[string]interface{}{
+			"snapshot":  snapshot,
+			"results":   results,
+			"timestamp": time.Now(),
+		})
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		switch {
+		case result.Status == updater.StatusFailed:
+			failed++
+			fmt.Printf("✗ %s: %s\n", result.Repository.Name, result.Message)
+		case r.verbose:
+			fmt.Printf("  %s: %s\n", result.Repository.Name, result.Message)
+		}
+	}
+	dirty := 0
+	for _, repo := range snapshot.Repos {
+		if repo.Dirty {
+			dirty++
+			if !r.verbose {
+				fmt.Printf("⚠️  %s: 工作区有未提交的变更，快照只记录提交\n", repo.Name)
+			}
+		}
+	}
+
+	fmt.Printf("📸 快照 %s 记录了 %d 个仓库", snapshot.Name, len(snapshot.Repos))
+	if dirty > 0 {
+		fmt.Printf("，其中 %d 个工作区有未提交的变更", dirty)
+	}
+	if failed > 0 {
+		fmt.Printf("，%d 个仓库无法记录", failed)
+	}
+	fmt.Println()
+}
+
+// ReportSnapshots lists the stored snapshots
+func (r *Reporter) ReportSnapshots(snapshots []cache.SnapshotInfo) {
+	if r.format == FormatJSON {
+		r.printJSON(map[string]interface{}{
+			"snapshots": snapshots,
+			"timestamp": time.Now(),
+		})
+		return
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("没有保存的快照")
+		return
+	}
+
+	fmt.Printf("%-24s %-18s %-8s %s\n", "名称", "创建时间", "仓库数", "目录")
+	fmt.Println(strings.Repeat("-", 80))
+	for _, snapshot := range snapshots {
+		fmt.Printf("%-24s %-18s %-8d %s\n", snapshot.Name, snapshot.CreatedAt.Local().Format("2006-01-02 15:04"), snapshot.RepoCount, snapshot.Directory)
+	}
+}
+
+// ReportRestoreResults reports how each repository compared with the snapshot and whether it was restored
+func (r *Reporter) ReportRestoreResults(snapshot *cache.Snapshot, results []updater.UpdateResult) {
+	if r.format == FormatJSON {
+		r.printJSON(map[string]interface{}{
+			"snapshot":        snapshot.Name,
+			"restore_results": results,
+			"timestamp":       time.Now(),
+		})
+		return
+	}
+
+	fmt.Printf("恢复快照 %s (%s, %s):\n", snapshot.Name, snapshot.CreatedAt.Local().Format("2006-01-02 15:04"), snapshot.Directory)
+	fmt.Println(strings.Repeat("-", 80))
+
+	restored, unchanged, failed, drifted := 0, 0, 0, 0
+	for _, result := range results {
+		mark := "✓"
+		switch result.Status {
+		case updater.StatusSkipped:
+			mark = "="
+			unchanged++
+		case updater.StatusFailed:
+			mark = "✗"
+			failed++
+		default:
+			restored++
+		}
+
+		var drift []string
+		if result.Restore != nil {
+			drift = result.Restore.Drift
+		}
+		if len(drift) > 0 {
+			drifted++
+		}
+		if result.Status == updater.StatusSkipped && len(drift) == 0 && !r.verbose {
+			continue
+		}
+
+		fmt.Printf("%s %s: %s\n", mark, result.Repository.Name, result.Message)
+		for _, line := range drift {
+			fmt.Printf("   ≠ %s\n", line)
+		}
+	}
+	fmt.Printf("\n已恢复: %d, 无需修改: %d, 失败: %d, 有差异: %d\n", restored, unchanged, failed, drifted)
+}
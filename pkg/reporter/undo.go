@@ -0,0 +1,123 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"reposense/pkg/cache"
+	"reposense/pkg/git"
+	"reposense/pkg/updater"
+)
+
+// ReportUpdateRuns reports recorded update runs, newest first
+func (r *Reporter) ReportUpdateRuns(runs []cache.UpdateRun) {
+	if r.format == FormatJSON {
+		r.printJSON(map[string]interface{}{
+			"update_runs": runs,
+			"timestamp":   time.Now(),
+		})
+		return
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("没有更新记录")
+		return
+	}
+
+	fmt.Printf("最近的更新记录 (%d次):\n", len(runs))
+	fmt.Println(strings.Repeat("-", 80))
+	for _, run := range runs {
+		changed, undone := 0, 0
+		counts := map[string]int{}
+		for _, entry := range run.Entries {
+			counts[entry.Status]++
+			if entry.Changed() {
+				changed++
+			}
+			if entry.Undone() {
+				undone++
+			}
+		}
+
+		fmt.Printf("#%d  %s  %s  %s\n", run.ID, run.StartedAt.Local().Format("2006-01-02 15:04"), run.Strategy, run.Directory)
+		fmt.Printf("   %d个仓库有变化, 成功 %d, 跳过 %d, 失败 %d",
+			changed, counts[string(updater.StatusSuccess)], counts[string(updater.StatusSkipped)], counts[string(updater.StatusFailed)])
+		if undone > 0 {
+			fmt.Printf(", 已撤销 %d", undone)
+		}
+		if run.FinishedAt.IsZero() {
+			fmt.Print(" (未完成)")
+		}
+		fmt.Println()
+
+		for _, entry := range run.Entries {
+			switch {
+			case entry.Changed():
+				mark := "↻"
+				if entry.Undone() {
+					mark = "↶"
+				}
+				fmt.Printf("   %s %s (%s): ", mark, entry.Name, entry.Branch)
+				if entry.HeadChanged() {
+					fmt.Printf("%s -> %s", git.ShortHash(entry.HeadBefore), git.ShortHash(entry.HeadAfter))
+				} else {
+					fmt.Print("当前分支未变化")
+				}
+				if entry.Operation != "" {
+					fmt.Printf("，留下未完成的%s", git.Operation(entry.Operation).Description())
+				}
+				if len(entry.Refs) > 0 {
+					fmt.Printf("，另有%d个分支已快进", len(entry.Refs))
+				}
+				fmt.Println()
+			case entry.Status == string(updater.StatusFailed):
+				message, _, _ := strings.Cut(entry.Message, "\n")
+				fmt.Printf("   ✗ %s: %s\n", entry.Name, message)
+			}
+		}
+	}
+	fmt.Println()
+}
+
+// ReportUndoResults reports the outcome of undoing an update run
+func (r *Reporter) ReportUndoResults(run *cache.UpdateRun, results []updater.UndoResult) {
+	if r.format == FormatJSON {
+		r.printJSON(map[string]interface{}{
+			"run_id":       run.ID,
+			"undo_results": results,
+			"timestamp":    time.Now(),
+		})
+		return
+	}
+
+	fmt.Printf("撤销更新 #%d (%s, %s):\n", run.ID, run.StartedAt.Local().Format("2006-01-02 15:04"), run.Directory)
+	fmt.Println(strings.Repeat("-", 80))
+
+	restored, skipped, refused := 0, 0, 0
+	for _, result := range results {
+		mark := "✓"
+		switch result.Status {
+		case updater.StatusSkipped:
+			mark = "⏭"
+			skipped++
+		case updater.StatusFailed:
+			mark = "✗"
+			refused++
+		default:
+			restored++
+		}
+		if result.Status == updater.StatusSkipped && !r.verbose {
+			continue
+		}
+		fmt.Printf("%s %s: %s\n", mark, result.Entry.Name, result.Message)
+	}
+	fmt.Printf("\n已恢复: %d, 跳过: %d, 拒绝: %d\n", restored, skipped, refused)
+}
+
+// printJSON prints data as indented JSON
+func (r *Reporter) printJSON(data interface{}) {
+	jsonData, _ := json.MarshalIndent(data, "", "  ")
+	fmt.Println(string(jsonData))
+}
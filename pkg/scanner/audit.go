@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+
+	"reposense/pkg/git"
+)
+
+// AuditItemKind classifies work that exists only in a local clone
+type AuditItemKind string
+
+const (
+	AuditUncommitted     AuditItemKind = "uncommitted"      // 未提交的变更
+	AuditUntracked       AuditItemKind = "untracked"        // 未跟踪的文件
+	AuditStash           AuditItemKind = "stash"            // 贮藏
+	AuditUnpushedCommits AuditItemKind = "unpushed_commits" // 分支领先上游
+	AuditLocalBranch     AuditItemKind = "local_branch"     // 没有可用上游且有远程不存在的提交
+	AuditDetachedCommits AuditItemKind = "detached_commits" // 分离 HEAD 上不属于任何分支的提交
+	AuditLocalTag        AuditItemKind = "local_tag"        // 没有推送到任何远程仓库的标签
+	AuditUnverifiable    AuditItemKind = "unverifiable"     // 无法确认是否安全
+)
+
+// AuditItem is a single piece of work that would be lost with the clone
+type AuditItem struct {
+	Kind   AuditItemKind `json:"kind"`
+	Name   string        `json:"name,omitempty"`
+	Count  int           `json:"count,omitempty"`
+	Detail string        `json:"detail"`
+}
+
+// UnpushedAudit lists the work that exists only in a repository's clone
+type UnpushedAudit struct {
+	Safe  bool        `json:"safe"`
+	Items []AuditItem `json:"items"`
+}
+
+// SetAudit makes the collector audit each repository for unpushed work.
+// Auditing needs every local branch, so it also enables branch collection.
+func (sc *StatusCollector) SetAudit(audit bool) {
+	sc.audit = audit
+	if audit {
+		sc.branches = true
+	}
+}
+
+// auditUnpushed finds work in a collected repository that exists nowhere else
+func (sc *StatusCollector) auditUnpushed(ctx context.Context, repo Repository, status *RepositoryStatus) *UnpushedAudit {
+	audit := &UnpushedAudit{Items: []AuditItem{}}
+	add := func(item AuditItem) {
+		audit.Items = append(audit.Items, item)
+	}
+
+	if status.Error != "" {
+		add(AuditItem{Kind: AuditUnverifiable, Detail: "收集状态失败: " + status.Error})
+		return audit
+	}
+	if !repo.Kind.HasWorkTree() {
+		// 裸仓库通常就是其他克隆的远程仓库
+		add(AuditItem{Kind: AuditUnverifiable, Detail: "裸仓库可能是其他克隆的远程仓库，请手动确认"})
+		return audit
+	}
+
+	if wt := status.WorkingTree; wt != nil {
+		if changes := wt.Staged.Total() + wt.Unstaged.Total() + len(wt.Conflicted); changes > 0 {
+			// 未跟踪文件单独列出
+			tracked := *wt
+			tracked.Untracked = 0
+			add(AuditItem{Kind: AuditUncommitted, Count: changes, Detail: describeWorkingTree(&tracked)})
+		}
+		for _, file := range wt.Files {
+			if file.State == git.FileUntracked {
+				add(AuditItem{Kind: AuditUntracked, Name: file.Path, Detail: "未跟踪的文件"})
+			}
+		}
+	} else {
+		add(AuditItem{Kind: AuditUnverifiable, Detail: "无法读取工作区状态"})
+	}
+
+	if status.WorkingTree != nil && status.WorkingTree.Stashes > 0 {
+		stashes, err := git.StashList(ctx, sc.runner, repo.Path)
+		if err != nil {
+			add(AuditItem{Kind: AuditStash, Count: status.WorkingTree.Stashes, Detail: "读取贮藏列表失败: " + err.Error()})
+		}
+		for _, stash := range stashes {
+			add(AuditItem{Kind: AuditStash, Detail: stash})
+		}
+	}
+
+	for _, branch := range status.Branches {
+		switch {
+		case branch.Upstream != "" && !branch.UpstreamGone:
+			if branch.Ahead > 0 {
+				add(AuditItem{
+					Kind:   AuditUnpushedCommits,
+					Name:   branch.Name,
+					Count:  branch.Ahead,
+					Detail: fmt.Sprintf("领先 %s %d 个提交", branch.Upstream, branch.Ahead),
+				})
+			}
+		default:
+			// 没有上游或上游已删除，看分支上的提交是否存在于任何远程分支
+			count, err := git.CountUnpushed(ctx, sc.runner, repo.Path, "refs/heads/"+branch.Name)
+			if err != nil {
+				add(AuditItem{Kind: AuditUnverifiable, Name: branch.Name, Detail: "统计未推送提交失败: " + err.Error()})
+				continue
+			}
+			if count > 0 {
+				detail := fmt.Sprintf("仅存在于本地的分支，有 %d 个未推送的提交", count)
+				if branch.UpstreamGone {
+					detail = fmt.Sprintf("上游 %s 已删除，有 %d 个未推送的提交", branch.Upstream, count)
+				}
+				add(AuditItem{Kind: AuditLocalBranch, Name: branch.Name, Count: count, Detail: detail})
+			}
+		}
+	}
+
+	if status.Head.State == git.HeadDetached {
+		out, err := git.Output(ctx, sc.runner, repo.Path, "rev-list", "--count", "HEAD", "--not", "--branches", "--remotes")
+		if count, parseErr := git.ParseCount(out); err == nil && parseErr == nil && count > 0 {
+			add(AuditItem{
+				Kind:   AuditDetachedCommits,
+				Name:   status.Head.ShortCommit(),
+				Count:  count,
+				Detail: fmt.Sprintf("分离 HEAD 上有 %d 个不属于任何分支的提交", count),
+			})
+		}
+	}
+
+	tags, err := git.LocalOnlyTags(ctx, sc.runner, repo.Path)
+	if err != nil {
+		add(AuditItem{Kind: AuditUnverifiable, Detail: "读取标签失败: " + err.Error()})
+	}
+	for _, tag := range tags {
+		add(AuditItem{Kind: AuditLocalTag, Name: tag, Detail: "标签没有推送到任何远程仓库"})
+	}
+
+	audit.Safe = len(audit.Items) == 0
+	return audit
+}
+
+// AuditSafe reports whether the audit found nothing that would be lost with the clone.
+// Repositories that failed or were not audited are never considered safe.
+func (s RepositoryStatus) AuditSafe() bool {
+	return s.Error == "" && s.Audit != nil && s.Audit.Safe
+}
@@ -1,13 +1,15 @@
 package scanner
 
 import (
+	"context"
 	"io/ioutil"
-	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
 	"reposense/pkg/cache"
+	"reposense/pkg/git"
 
 	"github.com/sirupsen/logrus"
 )
@@ -16,6 +18,9 @@ import (
 type CachedScanner struct {
 	logger       *logrus.Logger
 	cacheManager *cache.Manager
+	scanOptions  ScanOptions
+	runner       git.Runner
+	workers      int // 同时获取仓库标识的协程数
 }
 
 // NewCachedScanner creates a new Scanner instance with cache support
@@ -26,9 +31,25 @@ func NewCachedScanner(cacheManager *cache.Manager) *CachedScanner {
 	return &CachedScanner{
 		logger:       logger,
 		cacheManager: cacheManager,
+		scanOptions:  DefaultScanOptions(),
+		runner:       git.NewExecRunner(),
+		workers:      runtime.NumCPU(),
 	}
 }
 
+// SetScanOptions sets the directory traversal options
+func (cs *CachedScanner) SetScanOptions(options ScanOptions) {
+	cs.scanOptions = options
+}
+
+// SetWorkerCount sets how many repositories are identified concurrently when syncing the inventory
+func (cs *CachedScanner) SetWorkerCount(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	cs.workers = workers
+}
+
 // SetLogLevel sets the logging level
 func (cs *CachedScanner) SetLogLevel(level logrus.Level) {
 	cs.logger.SetLevel(level)
@@ -41,11 +62,22 @@ func (cs *CachedScanner) SetLogLevel(level logrus.Level) {
 func (cs *CachedScanner) ScanDirectoryWithDescription(rootPath string, includePatterns, excludePatterns []string, llmProvider, llmModel, llmLanguage string) ([]RepositoryWithDescription, error) {
 	// 首先使用普通scanner获取仓库列表
 	basicScanner := NewScanner()
+	basicScanner.SetScanOptions(cs.scanOptions)
+	if cs.cacheManager != nil {
+		basicScanner.SetMetadataSource(cs.cacheManager.GetCache().GetMetadataCache())
+	}
 	repositories, err := basicScanner.ScanDirectoryWithFilter(rootPath, includePatterns, excludePatterns)
 	if err != nil {
 		return nil, err
 	}
 	
+	// 先同步仓库清单，使移动过的仓库沿用已缓存的描述
+	if cs.cacheManager != nil {
+		if _, err := cs.SyncInventory(repositories); err != nil {
+			cs.logger.Warnf("更新仓库清单失败: %v", err)
+		}
+	}
+	
 	var reposWithDesc []RepositoryWithDescription
 	for _, repo := range repositories {
 		repoWithDesc := RepositoryWithDescription{
@@ -74,10 +106,8 @@ func (cs *CachedScanner) ScanDirectoryWithDescription(rootPath string, includePa
 			repoWithDesc.Description = cs.fallbackDescription(readmeContent)
 		}
 		
-		// 获取最后提交时间（用于排序）
-		if lastCommitDate := cs.getLastCommitDate(repo.Path); !lastCommitDate.IsZero() {
-			repoWithDesc.LastCommitDate = lastCommitDate
-		}
+		// 获取提交时间（用于排序），按 HEAD 缓存
+		repoWithDesc.setCommitDates(cs.commitDates(repo))
 		
 		reposWithDesc = append(reposWithDesc, repoWithDesc)
 		cs.logger.Debugf("收集描述完成: %s - %s", repo.Name, repoWithDesc.Description)
@@ -154,12 +184,33 @@ func (cs *CachedScanner) fallbackDescription(readmeContent string) string {
 	return "No description available"
 }
 
-// getLastCommitDate gets the last commit date for sorting
-func (cs *CachedScanner) getLastCommitDate(repoPath string) time.Time {
-	// 使用.git目录的修改时间作为近似值
-	gitPath := filepath.Join(repoPath, ".git")
-	if info, err := os.Stat(gitPath); err == nil {
-		return info.ModTime()
+// commitDates returns the repository's commit dates, reusing the cached values while HEAD is unchanged
+func (cs *CachedScanner) commitDates(repo Repository) git.CommitDates {
+	gitDir := repo.GitDir
+	if gitDir == "" {
+		gitDir = filepath.Join(repo.Path, ".git")
 	}
-	return time.Time{}
-}
\ No newline at end of file
+	
+	head, err := git.ReadHead(gitDir, repo.CommonDir)
+	if err == nil && cs.cacheManager != nil {
+		if dates, ok := cs.cacheManager.GetCache().GetCommitDates(repo.Path, head); ok {
+			return dates
+		}
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	
+	dates, err := git.ReadCommitDates(ctx, cs.runner, repo.Path)
+	if err != nil {
+		cs.logger.Debugf("获取提交时间失败 %s: %v", repo.Path, err)
+		return git.CommitDates{}
+	}
+	
+	if cs.cacheManager != nil {
+		if err := cs.cacheManager.GetCache().SaveCommitDates(repo.Path, dates); err != nil {
+			cs.logger.Debugf("缓存提交时间失败 %s: %v", repo.Path, err)
+		}
+	}
+	return dates
+}
@@ -0,0 +1,140 @@
+package scanner
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"reposense/pkg/filter"
+)
+
+// IgnoreFileName is the per-directory ignore file honoured during scanning
+const IgnoreFileName = ".reposenseignore"
+
+// ignoreRule is a single compiled line of an ignore file
+type ignoreRule struct {
+	pattern  string
+	regex    *regexp.Regexp
+	negate   bool
+	anchored bool // 包含斜杠的模式相对于忽略文件所在目录匹配
+}
+
+// ignoreFile holds the rules of one ignore file and the directory it lives in
+type ignoreFile struct {
+	base  string
+	rules []ignoreRule
+}
+
+// loadIgnoreFile reads dir/.reposenseignore, returning nil if it does not exist
+func loadIgnoreFile(dir string) (*ignoreFile, error) {
+	file, err := os.Open(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	ignore := &ignoreFile{base: dir}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text()); ok {
+			ignore.rules = append(ignore.rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ignore.rules) == 0 {
+		return nil, nil
+	}
+	return ignore, nil
+}
+
+// parseIgnoreLine compiles one line using gitignore syntax
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, "\r")
+
+	// 去掉未转义的行尾空格
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimSuffix(line, " ")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{pattern: line}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+
+	// 扫描只关心目录，所以结尾的斜杠不改变匹配结果
+	line = strings.TrimSuffix(line, "/")
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	regex, err := regexp.Compile("^" + filter.GlobRegex(line) + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.regex = regex
+	return rule, true
+}
+
+// match reports whether path is ignored by this file; decided is false when no rule applies
+func (f *ignoreFile) match(path string) (ignored bool, decided bool) {
+	rel, err := filepath.Rel(f.base, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false, false
+	}
+	rel = filepath.ToSlash(rel)
+	name := filepath.Base(path)
+
+	// 后出现的规则优先
+	for i := len(f.rules) - 1; i >= 0; i-- {
+		rule := f.rules[i]
+		target := name
+		if rule.anchored {
+			target = rel
+		}
+		if rule.regex.MatchString(target) {
+			return !rule.negate, true
+		}
+	}
+	return false, false
+}
+
+// ignoreStack is the chain of ignore files that apply to a directory
+type ignoreStack []*ignoreFile
+
+// push returns a new stack with file appended, leaving the receiver untouched
+func (s ignoreStack) push(file *ignoreFile) ignoreStack {
+	if file == nil {
+		return s
+	}
+	next := make(ignoreStack, len(s), len(s)+1)
+	copy(next, s)
+	return append(next, file)
+}
+
+// ignored reports whether path is excluded; deeper ignore files take precedence
+func (s ignoreStack) ignored(path string) bool {
+	for i := len(s) - 1; i >= 0; i-- {
+		if ignored, decided := s[i].match(path); decided {
+			return ignored
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"reposense/pkg/cache"
+	"reposense/pkg/git"
+)
+
+// InventoryDiff describes how the repositories found by a scan differ from the previous one
+type InventoryDiff struct {
+	Added     []cache.InventoryEntry `json:"added"`
+	Removed   []cache.InventoryEntry `json:"removed"`
+	Moved     []cache.InventoryMove  `json:"moved"`
+	Unchanged int                    `json:"unchanged"`
+}
+
+// Empty reports whether nothing changed
+func (d *InventoryDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0
+}
+
+// SyncInventory compares repositories with the persisted inventory, records the
+// result and moves cached rows of relocated repositories to their new path.
+// A previously known repository only counts as removed once its directory is no
+// longer a repository, so filtered or partial scans do not drop entries.
+func (cs *CachedScanner) SyncInventory(repositories []Repository) (*InventoryDiff, error) {
+	if cs.cacheManager == nil {
+		return nil, fmt.Errorf("未启用缓存，无法记录仓库清单")
+	}
+	store := cs.cacheManager.GetCache()
+
+	known, err := store.LoadInventory()
+	if err != nil {
+		return nil, err
+	}
+	knownByPath := make(map[string]cache.InventoryEntry, len(known))
+	for _, entry := range known {
+		knownByPath[entry.Path] = entry
+	}
+
+	diff := &InventoryDiff{}
+	var seen []cache.InventoryEntry
+	var added, unidentified []int // seen 中新发现的和需要获取标识的条目
+	scanned := make(map[string]bool, len(repositories))
+
+	for _, repo := range repositories {
+		path, err := filepath.Abs(repo.Path)
+		if err != nil {
+			path = repo.Path
+		}
+		scanned[path] = true
+
+		if entry, ok := knownByPath[path]; ok {
+			entry.Name = repo.Name
+			entry.Kind = string(repo.Kind)
+			// 之前没有提交的仓库重新获取标识
+			if entry.RootCommit == "" {
+				unidentified = append(unidentified, len(seen))
+			}
+			seen = append(seen, entry)
+			diff.Unchanged++
+			continue
+		}
+
+		unidentified = append(unidentified, len(seen))
+		added = append(added, len(seen))
+		seen = append(seen, cache.InventoryEntry{
+			Path: path,
+			Name: repo.Name,
+			Kind: string(repo.Kind),
+		})
+	}
+
+	// 查找根提交要遍历整个历史，首次扫描大量仓库时并发执行
+	cs.identifyAll(seen, unidentified)
+	for _, i := range added {
+		diff.Added = append(diff.Added, seen[i])
+	}
+
+	for _, entry := range known {
+		if scanned[entry.Path] {
+			continue
+		}
+		if _, ok := DetectRepository(entry.Path); ok {
+			continue
+		}
+		diff.Removed = append(diff.Removed, entry)
+	}
+
+	cs.matchMoves(diff)
+
+	// 移动的仓库保留首次发现时间
+	for i := range seen {
+		for _, move := range diff.Moved {
+			if seen[i].Path == move.To.Path {
+				seen[i].FirstSeen = move.From.FirstSeen
+			}
+		}
+	}
+
+	if err := store.SaveInventory(seen, diff.Removed, diff.Moved); err != nil {
+		return nil, err
+	}
+
+	cs.logger.Infof("仓库清单已更新: 新增 %d，删除 %d，移动 %d", len(diff.Added), len(diff.Removed), len(diff.Moved))
+	return diff, nil
+}
+
+// matchMoves pairs removed and added repositories with the same identity.
+// Among several candidates one with the same name is preferred.
+func (cs *CachedScanner) matchMoves(diff *InventoryDiff) {
+	if len(diff.Added) == 0 || len(diff.Removed) == 0 {
+		return
+	}
+
+	var added, removed []cache.InventoryEntry
+	used := make([]bool, len(diff.Added))
+
+	for _, gone := range diff.Removed {
+		identity := gone.Identity()
+		match := -1
+		if identity != "" {
+			for i, entry := range diff.Added {
+				if used[i] || entry.Identity() != identity {
+					continue
+				}
+				if match < 0 || (entry.Name == gone.Name && diff.Added[match].Name != gone.Name) {
+					match = i
+				}
+			}
+		}
+
+		if match < 0 {
+			removed = append(removed, gone)
+			continue
+		}
+		used[match] = true
+		diff.Moved = append(diff.Moved, cache.InventoryMove{From: gone, To: diff.Added[match]})
+		cs.logger.Debugf("检测到仓库移动: %s -> %s", gone.Path, diff.Added[match].Path)
+	}
+
+	for i, entry := range diff.Added {
+		if !used[i] {
+			added = append(added, entry)
+		}
+	}
+	diff.Added = added
+	diff.Removed = removed
+}
+
+// identifyAll fills in the root commit and origin URL of the entries at the
+// given indexes on up to the configured number of workers
+func (cs *CachedScanner) identifyAll(entries []cache.InventoryEntry, indexes []int) {
+	jobs := make(chan int, len(indexes))
+	for _, i := range indexes {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(cs.workers, len(indexes)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entries[i].RootCommit, entries[i].RemoteURL = cs.identify(entries[i].Path)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// identify returns the root commit and origin URL of a repository
+func (cs *CachedScanner) identify(path string) (string, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	root, err := git.RootCommit(ctx, cs.runner, path)
+	if err != nil {
+		cs.logger.Debugf("获取根提交失败 %s: %v", path, err)
+		return "", ""
+	}
+	remote, _ := git.RemoteURL(ctx, cs.runner, path, "origin")
+	return root, remote
+}
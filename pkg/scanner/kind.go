@@ -0,0 +1,160 @@
+package scanner
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RepositoryKind describes how a repository is laid out on disk
+type RepositoryKind string
+
+const (
+	KindNormal    RepositoryKind = "normal"    // 普通仓库，.git 为目录
+	KindWorktree  RepositoryKind = "worktree"  // git worktree 创建的链接工作树
+	KindSubmodule RepositoryKind = "submodule" // 子模块，.git 文件指向父仓库的 modules 目录
+	KindBare      RepositoryKind = "bare"      // 裸仓库，没有工作区
+)
+
+// HasWorkTree reports whether repositories of this kind have a working tree
+func (k RepositoryKind) HasWorkTree() bool {
+	return k != KindBare
+}
+
+// DetectRepository inspects path and returns the repository found there, if any
+func DetectRepository(path string) (Repository, bool) {
+	repo := Repository{
+		Path:      path,
+		Name:      filepath.Base(path),
+		IsGitRepo: true,
+	}
+
+	gitPath := filepath.Join(path, ".git")
+	info, err := os.Stat(gitPath)
+	if err == nil {
+		if info.IsDir() {
+			repo.Kind = KindNormal
+			repo.GitDir = gitPath
+			return repo, true
+		}
+		return detectLinkedRepository(repo, gitPath)
+	}
+
+	if isBareRepository(path) {
+		repo.Kind = KindBare
+		repo.GitDir = path
+		repo.Name = strings.TrimSuffix(repo.Name, ".git")
+		return repo, true
+	}
+
+	return Repository{}, false
+}
+
+// detectLinkedRepository resolves a ".git" file to a worktree or submodule
+func detectLinkedRepository(repo Repository, gitFile string) (Repository, bool) {
+	gitDir, err := readGitDirFile(gitFile)
+	if err != nil {
+		// 无法解析的 .git 文件仍然当作普通仓库处理，交给 git 自己报错
+		repo.Kind = KindNormal
+		repo.Error = "无法解析 .git 文件: " + err.Error()
+		return repo, true
+	}
+	repo.GitDir = gitDir
+
+	// 链接工作树的 gitdir 下有 commondir 文件指向共享的对象库
+	if commonDir, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(commonDir))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		repo.Kind = KindWorktree
+		repo.CommonDir = filepath.Clean(common)
+		if filepath.Base(repo.CommonDir) == ".git" {
+			repo.Parent = filepath.Dir(repo.CommonDir)
+		} else {
+			repo.Parent = repo.CommonDir
+		}
+		return repo, true
+	}
+
+	repo.Kind = KindSubmodule
+	repo.Parent = findParentRepository(repo.Path)
+	return repo, true
+}
+
+// readGitDirFile parses a "gitdir: <path>" file
+func readGitDirFile(gitFile string) (string, error) {
+	data, err := os.ReadFile(gitFile)
+	if err != nil {
+		return "", err
+	}
+
+	line := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", os.ErrInvalid
+	}
+
+	gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(gitFile), gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
+
+// isBareRepository checks for the HEAD/objects/refs layout of a bare repository
+func isBareRepository(path string) bool {
+	if info, err := os.Stat(filepath.Join(path, "HEAD")); err != nil || info.IsDir() {
+		return false
+	}
+	for _, dir := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(path, dir)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	_, err := os.Stat(filepath.Join(path, "config"))
+	return err == nil
+}
+
+// findParentRepository walks up from path to the nearest enclosing working tree
+func findParentRepository(path string) string {
+	dir := filepath.Dir(path)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// SubmodulePaths returns the submodule paths declared in repoPath/.gitmodules, relative to repoPath
+func SubmodulePaths(repoPath string) []string {
+	file, err := os.Open(filepath.Join(repoPath, ".gitmodules"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, found := strings.Cut(line, "=")
+		if !found || strings.TrimSpace(key) != "path" {
+			continue
+		}
+		if value = strings.Trim(strings.TrimSpace(value), `"`); value != "" {
+			paths = append(paths, filepath.FromSlash(value))
+		}
+	}
+	return paths
+}
+
+// HasSubmodules reports whether the repository declares any submodules
+func HasSubmodules(repoPath string) bool {
+	return len(SubmodulePaths(repoPath)) > 0
+}
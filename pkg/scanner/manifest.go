@@ -0,0 +1,310 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"reposense/pkg/git"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFileName is the default name of a workspace manifest
+const ManifestFileName = "reposense.yaml"
+
+// ManifestVersion is the manifest format written by GenerateManifest
+const ManifestVersion = 1
+
+// Manifest lists the repositories of a workspace as an alternative to directory scanning
+type Manifest struct {
+	Version      int             `yaml:"version"`
+	Defaults     ManifestOptions `yaml:"defaults,omitempty"`
+	Repositories []ManifestEntry `yaml:"repositories"`
+
+	path string // 清单文件路径，相对路径以其所在目录为基准
+}
+
+// ManifestEntry describes a single repository in a manifest
+type ManifestEntry struct {
+	Path    string          `yaml:"path"`
+	Name    string          `yaml:"name,omitempty"`
+	Remote  string          `yaml:"remote,omitempty"`
+	Groups  []string        `yaml:"groups,omitempty"`
+	Options ManifestOptions `yaml:"options,omitempty"`
+}
+
+// ManifestOptions are per-repository settings that override command-line values
+type ManifestOptions struct {
+	PullStrategy string        `yaml:"pull_strategy,omitempty"` // ff-only, merge, rebase
+	Timeout      time.Duration `yaml:"timeout,omitempty"`
+	SkipUpdate   bool          `yaml:"skip_update,omitempty"`
+}
+
+// merge returns o with unset fields taken from defaults
+func (o ManifestOptions) merge(defaults ManifestOptions) ManifestOptions {
+	if o.PullStrategy == "" {
+		o.PullStrategy = defaults.PullStrategy
+	}
+	if o.Timeout == 0 {
+		o.Timeout = defaults.Timeout
+	}
+	if !o.SkipUpdate {
+		o.SkipUpdate = defaults.SkipUpdate
+	}
+	return o
+}
+
+// LoadManifest reads a manifest file; a directory argument means its reposense.yaml
+func LoadManifest(path string) (*Manifest, error) {
+	absPath, err := filepath.Abs(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("无法解析清单路径: %w", err)
+	}
+	if info, err := os.Stat(absPath); err == nil && info.IsDir() {
+		absPath = filepath.Join(absPath, ManifestFileName)
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取清单文件失败: %w", err)
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析清单文件失败 %s: %w", absPath, err)
+	}
+	manifest.path = absPath
+
+	if err := manifest.validate(); err != nil {
+		return nil, fmt.Errorf("清单文件无效 %s: %w", absPath, err)
+	}
+	return &manifest, nil
+}
+
+// validate checks the manifest for unsupported versions, missing paths and duplicates
+func (m *Manifest) validate() error {
+	if m.Version > ManifestVersion {
+		return fmt.Errorf("不支持的清单版本 %d", m.Version)
+	}
+	if err := m.Defaults.validate(); err != nil {
+		return fmt.Errorf("defaults: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, entry := range m.Repositories {
+		if strings.TrimSpace(entry.Path) == "" {
+			return fmt.Errorf("第 %d 个仓库缺少 path", i+1)
+		}
+		resolved := m.ResolvePath(entry)
+		if seen[resolved] {
+			return fmt.Errorf("仓库路径重复: %s", entry.Path)
+		}
+		seen[resolved] = true
+		if err := entry.Options.validate(); err != nil {
+			return fmt.Errorf("%s: %w", entry.Path, err)
+		}
+	}
+	return nil
+}
+
+// validate checks option values
+func (o ManifestOptions) validate() error {
+	switch o.PullStrategy {
+	case "", "ff-only", "merge", "rebase":
+	default:
+		return fmt.Errorf("无效的 pull_strategy: %s", o.PullStrategy)
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("timeout 不能为负数")
+	}
+	return nil
+}
+
+// Path returns the manifest file location
+func (m *Manifest) Path() string {
+	return m.path
+}
+
+// Dir returns the directory relative entry paths are resolved against
+func (m *Manifest) Dir() string {
+	if m.path == "" {
+		wd, _ := os.Getwd()
+		return wd
+	}
+	return filepath.Dir(m.path)
+}
+
+// ResolvePath returns the absolute path of an entry
+func (m *Manifest) ResolvePath(entry ManifestEntry) string {
+	path := expandHome(entry.Path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.Dir(), path)
+	}
+	return filepath.Clean(path)
+}
+
+// Entries returns the entries belonging to any of groups, or all entries when groups is empty
+func (m *Manifest) Entries(groups []string) []ManifestEntry {
+	if len(groups) == 0 {
+		return m.Repositories
+	}
+
+	var entries []ManifestEntry
+	for _, entry := range m.Repositories {
+		if entry.inAnyGroup(groups) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// inAnyGroup reports whether the entry is a member of one of groups
+func (e ManifestEntry) inAnyGroup(groups []string) bool {
+	for _, want := range groups {
+		for _, group := range e.Groups {
+			if strings.EqualFold(group, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Options returns the effective options of every entry keyed by resolved path
+func (m *Manifest) Options() map[string]ManifestOptions {
+	options := make(map[string]ManifestOptions, len(m.Repositories))
+	for _, entry := range m.Repositories {
+		options[m.ResolvePath(entry)] = entry.Options.merge(m.Defaults)
+	}
+	return options
+}
+
+// Save writes the manifest as YAML
+func (m *Manifest) Save(path string) error {
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入清单文件失败: %w", err)
+	}
+	m.path, _ = filepath.Abs(path)
+	return nil
+}
+
+// Marshal encodes the manifest as YAML
+func (m *Manifest) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(m); err != nil {
+		return nil, fmt.Errorf("生成清单失败: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("生成清单失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// scanManifest resolves the manifest entries selected by the scan options into repositories
+func (s *Scanner) scanManifest() ([]Repository, error) {
+	manifest, err := LoadManifest(s.options.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Infof("从清单加载仓库: %s", manifest.Path())
+
+	var repositories []Repository
+	for _, entry := range manifest.Entries(s.options.Groups) {
+		path := manifest.ResolvePath(entry)
+		repo, ok := DetectRepository(path)
+		if !ok {
+			if entry.Remote != "" {
+				s.logger.Warnf("清单中的仓库不存在或不是 Git 仓库: %s (可通过 git clone %s 获取)", path, entry.Remote)
+			} else {
+				s.logger.Warnf("清单中的仓库不存在或不是 Git 仓库: %s", path)
+			}
+			continue
+		}
+		if entry.Name != "" {
+			repo.Name = entry.Name
+		}
+		repositories = append(repositories, repo)
+	}
+
+	sort.Slice(repositories, func(i, j int) bool {
+		return repositories[i].Path < repositories[j].Path
+	})
+
+	s.logger.Infof("清单中共有 %d 个可用的 Git 仓库", len(repositories))
+	return repositories, nil
+}
+
+// GenerateManifest scans rootPath and builds a manifest whose paths are relative to baseDir
+func (s *Scanner) GenerateManifest(rootPath, baseDir string) (*Manifest, error) {
+	// 生成清单时总是遍历目录，而不是读取已有清单
+	walkScanner := *s
+	walkScanner.options.Manifest = ""
+
+	repositories, err := walkScanner.ScanDirectory(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析清单目录: %w", err)
+	}
+
+	manifest := &Manifest{
+		Version: ManifestVersion,
+		path:    filepath.Join(absBase, ManifestFileName),
+	}
+	for _, repo := range repositories {
+		path, err := filepath.Abs(repo.Path)
+		if err != nil {
+			path = repo.Path
+		}
+		if rel, err := filepath.Rel(absBase, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+
+		entry := ManifestEntry{
+			Path:   filepath.ToSlash(path),
+			Remote: s.originURL(repo),
+		}
+		if filepath.Base(repo.Path) != repo.Name {
+			entry.Name = repo.Name
+		}
+		manifest.Repositories = append(manifest.Repositories, entry)
+	}
+	return manifest, nil
+}
+
+// originURL returns the URL of the origin remote, or "" if there is none
+func (s *Scanner) originURL(repo Repository) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	url, err := git.RemoteURL(ctx, s.runner, repo.Path, "origin")
+	if err != nil {
+		return ""
+	}
+	return url
+}
+
+// expandHome replaces a leading "~/" with the user's home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
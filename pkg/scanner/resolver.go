@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"reposense/pkg/filter"
+	"reposense/pkg/git"
+)
+
+// MetadataSource supplies cached repository metadata for lang: and tag: filters
+type MetadataSource interface {
+	MainLanguage(repoPath string) string
+	Tags(repoPath string) []string
+}
+
+// attributeResolver resolves filter attributes from git and cached metadata
+type attributeResolver struct {
+	runner   git.Runner
+	metadata MetadataSource
+	timeout  time.Duration
+}
+
+// Resolve implements filter.Resolver
+func (r *attributeResolver) Resolve(c filter.Candidate, attribute string) []string {
+	switch attribute {
+	case filter.AttrRemote:
+		return r.remotes(c.Path)
+	case filter.AttrBranch:
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		defer cancel()
+		branch, err := git.CurrentBranch(ctx, r.runner, c.Path)
+		if err != nil || branch == "" {
+			return nil
+		}
+		return []string{branch}
+	case filter.AttrLang:
+		if r.metadata == nil {
+			return nil
+		}
+		if language := r.metadata.MainLanguage(c.Path); language != "" {
+			return []string{language}
+		}
+		return nil
+	case filter.AttrTag:
+		if r.metadata == nil {
+			return nil
+		}
+		return r.metadata.Tags(c.Path)
+	}
+	return nil
+}
+
+// remotes returns the URLs of all configured remotes
+func (r *attributeResolver) remotes(repoPath string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	output, err := git.Output(ctx, r.runner, repoPath, "config", "--get-regexp", `^remote\..*\.url$`)
+	if err != nil {
+		return nil
+	}
+
+	var urls []string
+	for _, line := range strings.Split(output, "\n") {
+		if _, url, found := strings.Cut(strings.TrimSpace(line), " "); found {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
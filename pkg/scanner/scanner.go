@@ -2,11 +2,15 @@ package scanner
 
 import (
 	"bufio"
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"reposense/pkg/filter"
+	"reposense/pkg/git"
 	"reposense/pkg/llm"
 
 	"github.com/sirupsen/logrus"
@@ -14,23 +18,97 @@ import (
 
 // Repository represents a Git repository with its metadata
 type Repository struct {
-	Path        string `json:"path"`
-	Name        string `json:"name"`
-	IsGitRepo   bool   `json:"is_git_repo"`
-	Error       string `json:"error,omitempty"`
+	Path        string         `json:"path"`
+	Name        string         `json:"name"`
+	IsGitRepo   bool           `json:"is_git_repo"`
+	Kind        RepositoryKind `json:"kind,omitempty"`
+	GitDir      string         `json:"git_dir,omitempty"`
+	CommonDir   string         `json:"common_dir,omitempty"` // 链接工作树共享的 .git 目录
+	Parent      string         `json:"parent,omitempty"`     // 子模块或链接工作树所属的仓库路径
+	Error       string         `json:"error,omitempty"`
+}
+
+// ObjectStore returns the git directory holding the repository's objects,
+// which is shared between a main repository and its linked worktrees
+func (r Repository) ObjectStore() string {
+	if r.CommonDir != "" {
+		return r.CommonDir
+	}
+	if r.GitDir != "" {
+		return r.GitDir
+	}
+	return filepath.Join(r.Path, ".git")
+}
+
+// State reads the HEAD state and any unfinished operation from the repository's git directory
+func (r Repository) State() (git.HeadInfo, git.Operation, error) {
+	gitDir := r.GitDir
+	if gitDir == "" {
+		gitDir = filepath.Join(r.Path, ".git")
+	}
+	
+	head, err := git.ReadHeadState(gitDir, r.CommonDir)
+	if err != nil {
+		return git.HeadInfo{}, git.OpNone, fmt.Errorf("读取HEAD失败: %w", err)
+	}
+	return head, git.InProgressOperation(gitDir), nil
 }
 
 // RepositoryWithDescription represents a Git repository with description and last commit date
 type RepositoryWithDescription struct {
 	Repository
-	Description    string    `json:"description"`
-	LastCommitDate time.Time `json:"last_commit_date"`
+	Description      string    `json:"description"`
+	LastCommitDate   time.Time `json:"last_commit_date"`   // HEAD 的提交时间
+	AuthorDate       time.Time `json:"author_date"`        // HEAD 的作者时间
+	LatestBranchDate time.Time `json:"latest_branch_date"` // 所有本地分支中最新的提交时间
+}
+
+// SortKey selects which commit date list --sort-by-time orders by
+type SortKey string
+
+const (
+	SortByCommitter SortKey = "committer" // HEAD 提交时间
+	SortByAuthor    SortKey = "author"    // HEAD 作者时间
+	SortByBranches  SortKey = "branches"  // 所有本地分支中最新的提交时间
+)
+
+// ParseSortKey validates a sort key name; "" selects the committer date
+func ParseSortKey(name string) (SortKey, error) {
+	switch SortKey(name) {
+	case "", SortByCommitter:
+		return SortByCommitter, nil
+	case SortByAuthor, SortByBranches:
+		return SortKey(name), nil
+	}
+	return "", fmt.Errorf("无效的排序字段: %s (可选: committer, author, branches)", name)
+}
+
+// SortTime returns the date used to order the repository for key
+func (r RepositoryWithDescription) SortTime(key SortKey) time.Time {
+	switch key {
+	case SortByAuthor:
+		return r.AuthorDate
+	case SortByBranches:
+		return r.LatestBranchDate
+	default:
+		return r.LastCommitDate
+	}
+}
+
+// setCommitDates copies commit dates into the repository
+func (r *RepositoryWithDescription) setCommitDates(dates git.CommitDates) {
+	r.LastCommitDate = dates.CommitterDate
+	r.AuthorDate = dates.AuthorDate
+	r.LatestBranchDate = dates.LatestBranchDate
 }
 
 // Scanner handles repository discovery
 type Scanner struct {
 	logger            *logrus.Logger
 	descriptionService *llm.DescriptionService
+	options           ScanOptions
+	metadata          MetadataSource
+	runner            git.Runner
 }
 
 // NewScanner creates a new Scanner instance
@@ -38,7 +116,9 @@ func NewScanner() *Scanner {
 	logger := logrus.New()
 	logger.SetLevel(logrus.InfoLevel)
 	return &Scanner{
-		logger: logger,
+		logger:  logger,
+		options: DefaultScanOptions(),
+		runner:  git.NewExecRunner(),
 	}
 }
 
@@ -49,9 +129,21 @@ func NewScannerWithLLM(descriptionService *llm.DescriptionService) *Scanner {
 	return &Scanner{
 		logger:            logger,
 		descriptionService: descriptionService,
+		options:           DefaultScanOptions(),
+		runner:            git.NewExecRunner(),
 	}
 }
 
+// SetScanOptions sets the directory traversal options
+func (s *Scanner) SetScanOptions(options ScanOptions) {
+	s.options = options
+}
+
+// SetMetadataSource sets the cached metadata used by lang: and tag: filters
+func (s *Scanner) SetMetadataSource(metadata MetadataSource) {
+	s.metadata = metadata
+}
+
 // SetLogLevel sets the logging level
 func (s *Scanner) SetLogLevel(level logrus.Level) {
 	s.logger.SetLevel(level)
@@ -60,67 +152,56 @@ func (s *Scanner) SetLogLevel(level logrus.Level) {
 	}
 }
 
-// ScanDirectory scans a directory for Git repositories
+// ScanDirectory scans a directory for Git repositories, or reads them from
+// the manifest when one is configured in the scan options
 func (s *Scanner) ScanDirectory(rootPath string) ([]Repository, error) {
-	var repositories []Repository
+	if s.options.Manifest != "" {
+		return s.scanManifest()
+	}
 	
 	s.logger.Infof("开始扫描目录: %s", rootPath)
 	
-	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			s.logger.Warnf("访问路径失败 %s: %v", path, err)
-			return nil
-		}
-		
-		// 跳过非目录
-		if !info.IsDir() {
-			return nil
-		}
-		
-		// 检查是否为 Git 仓库
-		if s.isGitRepository(path) {
-			repo := Repository{
-				Path:      path,
-				Name:      filepath.Base(path),
-				IsGitRepo: true,
-			}
-			repositories = append(repositories, repo)
-			s.logger.Debugf("发现 Git 仓库: %s", path)
-			
-			// 跳过 .git 子目录
-			return filepath.SkipDir
-		}
-		
-		// 跳过 .git 目录
-		if info.Name() == ".git" {
-			return filepath.SkipDir
-		}
-		
-		return nil
-	})
-	
+	info, err := os.Stat(rootPath)
 	if err != nil {
-		return nil, err
+		s.logger.Warnf("访问路径失败 %s: %v", rootPath, err)
+		return nil, nil
+	}
+	if !info.IsDir() {
+		return nil, nil
 	}
 	
+	repositories := newWalker(s).walk(rootPath)
+	
 	s.logger.Infof("扫描完成，共发现 %d 个 Git 仓库", len(repositories))
 	return repositories, nil
 }
 
-// ScanDirectoryWithFilter scans directory with filtering options
+// ScanDirectoryWithFilter scans directory with filtering options.
+// Patterns use the expression syntax of package filter.
 func (s *Scanner) ScanDirectoryWithFilter(rootPath string, includePatterns, excludePatterns []string) ([]Repository, error) {
+	repoFilter, err := filter.New(includePatterns, excludePatterns)
+	if err != nil {
+		return nil, err
+	}
+	
 	repositories, err := s.ScanDirectory(rootPath)
 	if err != nil {
 		return nil, err
 	}
 	
-	if len(includePatterns) == 0 && len(excludePatterns) == 0 {
+	if repoFilter.Empty() {
 		return repositories, nil
 	}
 	
+	resolver := &attributeResolver{
+		runner:   s.runner,
+		metadata: s.metadata,
+		timeout:  10 * time.Second,
+	}
+	
 	var filtered []Repository
 	for _, repo := range repositories {
-		if s.shouldIncludeRepository(repo, includePatterns, excludePatterns) {
+		if s.shouldIncludeRepository(repo, repoFilter, resolver) {
 			filtered = append(filtered, repo)
 		} else {
 			s.logger.Debugf("过滤掉仓库: %s", repo.Path)
@@ -131,45 +212,24 @@ func (s *Scanner) ScanDirectoryWithFilter(rootPath string, includePatterns, excl
 	return filtered, nil
 }
 
-// isGitRepository checks if a directory is a Git repository
+// isGitRepository checks if a directory is a Git repository (including bare repositories)
 func (s *Scanner) isGitRepository(path string) bool {
-	gitPath := filepath.Join(path, ".git")
-	
-	// 检查 .git 是否存在（可能是文件或目录）
-	if _, err := os.Stat(gitPath); err != nil {
-		return false
-	}
-	
-	return true
+	_, ok := DetectRepository(path)
+	return ok
 }
 
 // shouldIncludeRepository checks if repository matches filter criteria
-func (s *Scanner) shouldIncludeRepository(repo Repository, includePatterns, excludePatterns []string) bool {
-	repoName := strings.ToLower(repo.Name)
-	repoPath := strings.ToLower(repo.Path)
-	
-	// 检查排除模式
-	for _, pattern := range excludePatterns {
-		pattern = strings.ToLower(pattern)
-		if strings.Contains(repoName, pattern) || strings.Contains(repoPath, pattern) {
-			return false
-		}
-	}
-	
-	// 如果没有包含模式，则通过排除检查的都包含
-	if len(includePatterns) == 0 {
-		return true
+func (s *Scanner) shouldIncludeRepository(repo Repository, repoFilter *filter.Filter, resolver filter.Resolver) bool {
+	path := repo.Path
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
 	}
-	
-	// 检查包含模式
-	for _, pattern := range includePatterns {
-		pattern = strings.ToLower(pattern)
-		if strings.Contains(repoName, pattern) || strings.Contains(repoPath, pattern) {
-			return true
-		}
+	candidate := filter.Candidate{
+		Name: repo.Name,
+		Path: path,
+		Kind: string(repo.Kind),
 	}
-	
-	return false
+	return repoFilter.Match(candidate, resolver)
 }
 
 // GetRepositoryCount returns the count of repositories that would be found
@@ -258,10 +318,8 @@ func (s *Scanner) ScanDirectoryWithDescription(rootPath string, includePatterns,
 			Description: s.extractDescription(repo.Path),
 		}
 		
-		// 获取最后提交时间（用于排序）
-		if lastCommitDate := s.getLastCommitDate(repo.Path); !lastCommitDate.IsZero() {
-			repoWithDesc.LastCommitDate = lastCommitDate
-		}
+		// 获取提交时间（用于排序）
+		repoWithDesc.setCommitDates(s.readCommitDates(repo))
 		
 		reposWithDesc = append(reposWithDesc, repoWithDesc)
 		s.logger.Debugf("收集描述完成: %s - %s", repo.Name, repoWithDesc.Description)
@@ -270,13 +328,14 @@ func (s *Scanner) ScanDirectoryWithDescription(rootPath string, includePatterns,
 	return reposWithDesc, nil
 }
 
-// getLastCommitDate gets the last commit date for sorting
-func (s *Scanner) getLastCommitDate(repoPath string) time.Time {
-	// 这里实现一个简单的git log命令来获取最后提交时间
-	// 为了避免依赖复杂的git操作，我们使用.git目录的修改时间作为近似值
-	gitPath := filepath.Join(repoPath, ".git")
-	if info, err := os.Stat(gitPath); err == nil {
-		return info.ModTime()
+// readCommitDates reads commit dates with git; repositories without commits yield zero dates
+func (s *Scanner) readCommitDates(repo Repository) git.CommitDates {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	
+	dates, err := git.ReadCommitDates(ctx, s.runner, repo.Path)
+	if err != nil {
+		s.logger.Debugf("获取提交时间失败 %s: %v", repo.Path, err)
 	}
-	return time.Time{}
-}
\ No newline at end of file
+	return dates
+}
@@ -3,11 +3,12 @@ package scanner
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
+	"reposense/pkg/git"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,31 +16,61 @@ import (
 type RepositoryStatus struct {
 	Repository     Repository      `json:"repository"`
 	Branch         string          `json:"branch"`
+	Head           git.HeadInfo    `json:"head"`
+	Operation      git.Operation   `json:"operation,omitempty"` // 未完成的 rebase、merge 等操作
 	LastCommitHash string          `json:"last_commit_hash"`
 	LastCommitMsg  string          `json:"last_commit_message"`
 	LastCommitDate time.Time       `json:"last_commit_date"`
 	HasChanges     bool            `json:"has_changes"`
 	Status         string          `json:"status"`
 	RemoteURL      string          `json:"remote_url"`
+	Upstream       string          `json:"upstream,omitempty"`
 	Ahead          int             `json:"ahead"`
 	Behind         int             `json:"behind"`
+	LastFetch      time.Time       `json:"last_fetch"`
+	FetchError     string          `json:"fetch_error,omitempty"`
+	WorkingTree    *git.WorkingTreeStatus `json:"working_tree,omitempty"`
+	DefaultBranch  string          `json:"default_branch,omitempty"`
+	Branches       []git.BranchInfo `json:"branches,omitempty"` // 仅在 SetBranches(true) 时收集
+	Audit          *UnpushedAudit  `json:"audit,omitempty"`    // 仅在 SetAudit(true) 时收集
 	Error          string          `json:"error,omitempty"`
 }
 
 // StatusCollector collects detailed status information from repositories
 type StatusCollector struct {
-	logger  *logrus.Logger
-	timeout time.Duration
+	logger   *logrus.Logger
+	timeout  time.Duration
+	runner   git.Runner
+	workers  int
+	fetch    bool
+	branches bool
+	ignored  bool
+	audit    bool
+	ctx      context.Context
+	cancel   context.CancelFunc
 }
 
 // NewStatusCollector creates a new StatusCollector
 func NewStatusCollector(timeout time.Duration) *StatusCollector {
+	runner := git.NewExecRunner()
+	runner.SSHConnectTimeout = 5 * time.Second
+	return NewStatusCollectorWithRunner(timeout, runner)
+}
+
+// NewStatusCollectorWithRunner creates a new StatusCollector that executes git through runner
+func NewStatusCollectorWithRunner(timeout time.Duration, runner git.Runner) *StatusCollector {
 	logger := logrus.New()
 	logger.SetLevel(logrus.InfoLevel)
 	
+	ctx, cancel := context.WithCancel(context.Background())
+	
 	return &StatusCollector{
 		logger:  logger,
 		timeout: timeout,
+		runner:  runner,
+		workers: 1,
+		ctx:     ctx,
+		cancel:  cancel,
 	}
 }
 
@@ -48,8 +79,50 @@ func (sc *StatusCollector) SetLogLevel(level logrus.Level) {
 	sc.logger.SetLevel(level)
 }
 
+// SetWorkerCount sets how many repositories are inspected concurrently
+func (sc *StatusCollector) SetWorkerCount(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	sc.workers = workers
+}
+
+// SetFetch makes the collector fetch each repository before reading its
+// upstream; by default only the refs fetched earlier are used
+func (sc *StatusCollector) SetFetch(fetch bool) {
+	sc.fetch = fetch
+}
+
+// SetBranches makes the collector report every local branch, not only the checked-out one
+func (sc *StatusCollector) SetBranches(branches bool) {
+	sc.branches = branches
+}
+
+// SetIgnored makes the collector count ignored paths, which can be slow in
+// repositories with large build outputs or dependency directories
+func (sc *StatusCollector) SetIgnored(ignored bool) {
+	sc.ignored = ignored
+}
+
+// Stop cancels status collection; repositories not yet inspected are reported as canceled
+func (sc *StatusCollector) Stop() {
+	sc.cancel()
+}
+
 // CollectStatus collects status for a single repository
 func (sc *StatusCollector) CollectStatus(repo Repository) RepositoryStatus {
+	ctx, cancel := context.WithTimeout(sc.ctx, sc.timeout)
+	defer cancel()
+	
+	status := sc.collectStatus(ctx, repo)
+	if sc.audit {
+		status.Audit = sc.auditUnpushed(ctx, repo, &status)
+	}
+	return status
+}
+
+// collectStatus gathers the status of a repository within ctx
+func (sc *StatusCollector) collectStatus(ctx context.Context, repo Repository) RepositoryStatus {
 	status := RepositoryStatus{
 		Repository: repo,
 	}
@@ -59,9 +132,6 @@ func (sc *StatusCollector) CollectStatus(repo Repository) RepositoryStatus {
 		return status
 	}
 	
-	ctx, cancel := context.WithTimeout(context.Background(), sc.timeout)
-	defer cancel()
-	
 	// 获取当前分支
 	if branch, err := sc.getCurrentBranch(ctx, repo.Path); err != nil {
 		status.Error = "获取分支信息失败: " + err.Error()
@@ -70,17 +140,26 @@ func (sc *StatusCollector) CollectStatus(repo Repository) RepositoryStatus {
 		status.Branch = branch
 	}
 	
+	// HEAD 状态和未完成的操作直接从 git 目录读取
+	if head, operation, err := repo.State(); err != nil {
+		sc.logger.Debugf("读取HEAD状态失败 %s: %v", repo.Path, err)
+	} else {
+		status.Head = head
+		status.Operation = operation
+	}
+	
 	// 获取最后一次提交信息
 	if err := sc.getLastCommitInfo(ctx, repo.Path, &status); err != nil {
 		sc.logger.Warnf("获取提交信息失败 %s: %v", repo.Path, err)
 	}
 	
-	// 获取工作区状态
-	if hasChanges, statusStr, err := sc.getWorkingStatus(ctx, repo.Path); err != nil {
-		sc.logger.Warnf("获取工作区状态失败 %s: %v", repo.Path, err)
-	} else {
-		status.HasChanges = hasChanges
-		status.Status = statusStr
+	// 裸仓库没有工作区，也没有可比较的远程跟踪分支
+	if !repo.Kind.HasWorkTree() {
+		status.Status = "裸仓库"
+		if remoteURL, err := sc.getRemoteURL(ctx, repo.Path); err == nil {
+			status.RemoteURL = remoteURL
+		}
+		return status
 	}
 	
 	// 获取远程仓库URL
@@ -90,224 +169,242 @@ func (sc *StatusCollector) CollectStatus(repo Repository) RepositoryStatus {
 		status.RemoteURL = remoteURL
 	}
 	
-	// 获取与远程分支的差异
-	if ahead, behind, err := sc.getRemoteDiff(ctx, repo.Path); err != nil {
-		sc.logger.Debugf("获取远程差异失败 %s: %v", repo.Path, err)
+	// 先获取远程更新，失败时继续使用本地已有的远程跟踪分支
+	if sc.fetch {
+		if err := sc.fetchRemote(ctx, repo.Path); err != nil {
+			sc.logger.Warnf("获取远程更新失败 %s: %v", repo.Path, err)
+			status.FetchError = err.Error()
+		}
+	}
+	
+	// 获取工作区状态
+	readWorkingTree := git.ReadWorkingTreeStatus
+	if sc.ignored {
+		readWorkingTree = git.ReadWorkingTreeStatusWithIgnored
+	}
+	if workingTree, err := readWorkingTree(ctx, sc.runner, repo.Path); err != nil {
+		sc.logger.Warnf("获取工作区状态失败 %s: %v", repo.Path, err)
+		// 不知道工作区是否干净，记录为错误以免被当作没有变更
+		status.Error = "获取工作区状态失败: " + err.Error()
 	} else {
-		status.Ahead = ahead
-		status.Behind = behind
+		status.WorkingTree = workingTree
+		status.HasChanges = !workingTree.Clean()
+		status.Status = describeWorkingTree(workingTree)
+	}
+	
+	// 获取与上游分支的差异
+	if err := sc.getUpstreamDiff(ctx, repo.Path, &status); err != nil {
+		sc.logger.Debugf("获取上游差异失败 %s: %v", repo.Path, err)
+	}
+	
+	// 获取所有本地分支
+	if sc.branches {
+		status.DefaultBranch = git.DefaultBranch(ctx, sc.runner, repo.Path)
+		if branches, err := git.ListBranches(ctx, sc.runner, repo.Path, status.DefaultBranch); err != nil {
+			sc.logger.Warnf("获取分支列表失败 %s: %v", repo.Path, err)
+		} else {
+			status.Branches = branches
+		}
 	}
 	
 	return status
 }
 
-// CollectBatchStatus collects status for multiple repositories
-func (sc *StatusCollector) CollectBatchStatus(repositories []Repository) []RepositoryStatus {
-	var results []RepositoryStatus
+// CollectBatchStatus collects status for multiple repositories with a worker pool.
+// Results keep the order of repositories; progressCallback is invoked as each one finishes.
+func (sc *StatusCollector) CollectBatchStatus(repositories []Repository, progressCallback func(RepositoryStatus)) []RepositoryStatus {
+	results := make([]RepositoryStatus, len(repositories))
+	if len(repositories) == 0 {
+		return results
+	}
+	
+	workers := sc.workers
+	if workers > len(repositories) {
+		workers = len(repositories)
+	}
+	sc.logger.Infof("开始收集 %d 个仓库的状态信息，使用 %d 个工作协程", len(repositories), workers)
 	
-	sc.logger.Infof("开始收集 %d 个仓库的状态信息", len(repositories))
+	// 任务通道传递下标，结果直接写入对应位置以保持顺序
+	jobs := make(chan int, len(repositories))
+	done := make(chan int, len(repositories))
+	collected := make([]bool, len(repositories))
 	
-	for _, repo := range repositories {
-		status := sc.CollectStatus(repo)
-		results = append(results, status)
-		sc.logger.Debugf("收集状态完成: %s", repo.Name)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go sc.worker(i, repositories, results, jobs, done, &wg)
+	}
+	
+	// 发送任务
+	go func() {
+		defer close(jobs)
+		for i := range repositories {
+			select {
+			case jobs <- i:
+			case <-sc.ctx.Done():
+				return
+			}
+		}
+	}()
+	
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	
+	for index := range done {
+		collected[index] = true
+		if progressCallback != nil {
+			progressCallback(results[index])
+		}
+	}
+	
+	// 被取消时尚未处理的仓库
+	for i, ok := range collected {
+		if !ok {
+			results[i] = RepositoryStatus{Repository: repositories[i], Error: "已取消"}
+		}
 	}
 	
 	sc.logger.Infof("状态收集完成")
 	return results
 }
 
+// worker collects the status of repositories whose indexes arrive on jobs
+func (sc *StatusCollector) worker(id int, repositories []Repository, results []RepositoryStatus, jobs <-chan int, done chan<- int, wg *sync.WaitGroup) {
+	defer wg.Done()
+	
+	for index := range jobs {
+		select {
+		case <-sc.ctx.Done():
+			return
+		default:
+			status := sc.CollectStatus(repositories[index])
+			if sc.ctx.Err() != nil {
+				status.Error = "已取消"
+			}
+			results[index] = status
+			sc.logger.Debugf("工作协程 %d 收集状态完成: %s", id, repositories[index].Name)
+			done <- index
+		}
+	}
+}
+
 // getCurrentBranch gets the current branch name
 func (sc *StatusCollector) getCurrentBranch(ctx context.Context, repoPath string) (string, error) {
-	cmd := exec.CommandContext(ctx, "git", "branch", "--show-current")
-	cmd.Dir = repoPath
-	
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	
-	return strings.TrimSpace(string(output)), nil
+	return git.CurrentBranch(ctx, sc.runner, repoPath)
 }
 
 // getLastCommitInfo gets information about the last commit
 func (sc *StatusCollector) getLastCommitInfo(ctx context.Context, repoPath string, status *RepositoryStatus) error {
-	// 获取提交哈希
-	cmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
-	cmd.Dir = repoPath
-	
-	if output, err := cmd.Output(); err != nil {
-		return err
-	} else {
-		status.LastCommitHash = strings.TrimSpace(string(output))
-	}
-	
-	// 获取提交消息
-	cmd = exec.CommandContext(ctx, "git", "log", "-1", "--pretty=format:%s")
-	cmd.Dir = repoPath
-	
-	if output, err := cmd.Output(); err != nil {
+	info, err := git.LastCommit(ctx, sc.runner, repoPath, "HEAD")
+	if err != nil {
 		return err
-	} else {
-		status.LastCommitMsg = strings.TrimSpace(string(output))
 	}
 	
-	// 获取提交日期
-	cmd = exec.CommandContext(ctx, "git", "log", "-1", "--pretty=format:%ci")
-	cmd.Dir = repoPath
-	
-	if output, err := cmd.Output(); err != nil {
-		return err
-	} else {
-		if date, err := time.Parse("2006-01-02 15:04:05 -0700", strings.TrimSpace(string(output))); err == nil {
-			status.LastCommitDate = date
-		}
-	}
+	status.LastCommitHash = info.Hash
+	status.LastCommitMsg = info.Subject
+	status.LastCommitDate = info.Date
 	
 	return nil
 }
 
-// getWorkingStatus gets the working directory status
-func (sc *StatusCollector) getWorkingStatus(ctx context.Context, repoPath string) (bool, string, error) {
-	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain")
-	cmd.Dir = repoPath
-	
-	output, err := cmd.Output()
-	if err != nil {
-		return false, "", err
-	}
-	
-	statusOutput := strings.TrimSpace(string(output))
-	hasChanges := len(statusOutput) > 0
-	
-	if !hasChanges {
-		return false, "干净", nil
-	}
-	
-	// 简化状态描述
-	lines := strings.Split(statusOutput, "\n")
-	modified := 0
-	added := 0
-	deleted := 0
-	untracked := 0
-	
-	for _, line := range lines {
-		if len(line) < 2 {
-			continue
-		}
-		
-		switch {
-		case strings.HasPrefix(line, "??"):
-			untracked++
-		case strings.HasPrefix(line, "A "):
-			added++
-		case strings.HasPrefix(line, "D "):
-			deleted++
-		case strings.HasPrefix(line, "M ") || strings.HasPrefix(line, " M"):
-			modified++
-		}
+// describeWorkingTree summarizes a working tree status, e.g. "已暂存: 1个修改; 未暂存: 2个修改; 3个未跟踪"
+func describeWorkingTree(wt *git.WorkingTreeStatus) string {
+	if wt.Clean() {
+		return "干净"
 	}
 	
-	var statusParts []string
-	if modified > 0 {
-		statusParts = append(statusParts, fmt.Sprintf("%d个修改", modified))
+	var parts []string
+	if staged := describeChanges(wt.Staged); staged != "" {
+		parts = append(parts, "已暂存: "+staged)
 	}
-	if added > 0 {
-		statusParts = append(statusParts, fmt.Sprintf("%d个新增", added))
+	if unstaged := describeChanges(wt.Unstaged); unstaged != "" {
+		parts = append(parts, "未暂存: "+unstaged)
 	}
-	if deleted > 0 {
-		statusParts = append(statusParts, fmt.Sprintf("%d个删除", deleted))
+	if len(wt.Conflicted) > 0 {
+		parts = append(parts, fmt.Sprintf("%d个冲突", len(wt.Conflicted)))
 	}
-	if untracked > 0 {
-		statusParts = append(statusParts, fmt.Sprintf("%d个未跟踪", untracked))
+	if wt.Untracked > 0 {
+		parts = append(parts, fmt.Sprintf("%d个未跟踪", wt.Untracked))
 	}
 	
-	return true, strings.Join(statusParts, ", "), nil
+	return strings.Join(parts, "; ")
+}
+
+// describeChanges lists the non-zero change counts
+func describeChanges(counts git.ChangeCounts) string {
+	var parts []string
+	for _, item := range []struct {
+		count int
+		label string
+	}{
+		{counts.Modified, "修改"},
+		{counts.Added, "新增"},
+		{counts.Deleted, "删除"},
+		{counts.Renamed, "重命名"},
+		{counts.Copied, "复制"},
+		{counts.TypeChanged, "类型变更"},
+	} {
+		if item.count > 0 {
+			parts = append(parts, fmt.Sprintf("%d个%s", item.count, item.label))
+		}
+	}
+	return strings.Join(parts, ", ")
 }
 
 // getRemoteURL gets the remote repository URL
 func (sc *StatusCollector) getRemoteURL(ctx context.Context, repoPath string) (string, error) {
-	cmd := exec.CommandContext(ctx, "git", "remote", "get-url", "origin")
-	cmd.Dir = repoPath
-	
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	
-	return strings.TrimSpace(string(output)), nil
+	return git.RemoteURL(ctx, sc.runner, repoPath, "origin")
 }
 
-// getRemoteDiff gets the difference with remote branch
-func (sc *StatusCollector) getRemoteDiff(ctx context.Context, repoPath string) (int, int, error) {
-	// 使用更安全的命令检查远程连接（非交互模式）
-	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--exit-code", "origin", "HEAD")
-	cmd.Dir = repoPath
-	
-	// 设置环境变量防止交互提示
-	cmd.Env = append(os.Environ(),
-		"GIT_TERMINAL_PROMPT=0",
-		"GIT_ASKPASS=echo",
-		"SSH_ASKPASS=echo",
-		"GIT_SSH_COMMAND=ssh -o BatchMode=yes -o ConnectTimeout=5 -o StrictHostKeyChecking=no",
-	)
-	
-	if _, err := cmd.Output(); err != nil {
-		// 如果无法连接远程，返回0
-		return 0, 0, nil
-	}
-	
-	// 获取当前分支
-	branch, err := sc.getCurrentBranch(ctx, repoPath)
+// fetchRemote fetches the remote tracked by the current branch (origin by default)
+func (sc *StatusCollector) fetchRemote(ctx context.Context, repoPath string) error {
+	remotes, err := git.Output(ctx, sc.runner, repoPath, "remote")
 	if err != nil {
-		return 0, 0, err
+		return err
 	}
-	
-	remoteBranch := "origin/" + branch
-	
-	// 检查远程分支是否存在
-	cmd = exec.CommandContext(ctx, "git", "rev-parse", "--verify", remoteBranch)
-	cmd.Dir = repoPath
-	
-	if err := cmd.Run(); err != nil {
-		// 远程分支不存在
-		return 0, 0, nil
+	if remotes == "" {
+		// 没有远程仓库，无需获取
+		return nil
 	}
 	
-	// 获取ahead数量
-	cmd = exec.CommandContext(ctx, "git", "rev-list", "--count", remoteBranch+"..HEAD")
-	cmd.Dir = repoPath
+	_, err = sc.runner.Run(ctx, repoPath, "fetch", "--quiet")
+	return err
+}
+
+// getUpstreamDiff compares HEAD with its configured upstream using the local
+// remote-tracking refs, so no network access is needed
+func (sc *StatusCollector) getUpstreamDiff(ctx context.Context, repoPath string, status *RepositoryStatus) error {
+	if lastFetch, err := git.LastFetchTime(ctx, sc.runner, repoPath); err == nil {
+		status.LastFetch = lastFetch
+	}
 	
-	var ahead int
-	if output, err := cmd.Output(); err == nil {
-		if n, err := parseInt(strings.TrimSpace(string(output))); err == nil {
-			ahead = n
+	// 工作区状态中已包含上游信息，无需再调用 git
+	if wt := status.WorkingTree; wt != nil {
+		if wt.Upstream != "" && !wt.UpstreamGone {
+			status.Upstream = wt.Upstream
+			status.Ahead = wt.Ahead
+			status.Behind = wt.Behind
 		}
+		return nil
 	}
 	
-	// 获取behind数量
-	cmd = exec.CommandContext(ctx, "git", "rev-list", "--count", "HEAD.."+remoteBranch)
-	cmd.Dir = repoPath
-	
-	var behind int
-	if output, err := cmd.Output(); err == nil {
-		if n, err := parseInt(strings.TrimSpace(string(output))); err == nil {
-			behind = n
-		}
+	upstream, err := git.Upstream(ctx, sc.runner, repoPath)
+	if err != nil {
+		return err
+	}
+	if upstream == "" {
+		// 没有配置上游分支
+		return nil
 	}
+	status.Upstream = upstream
 	
-	return ahead, behind, nil
-}
-
-// parseInt converts string to int
-func parseInt(s string) (int, error) {
-	var result int
-	for _, ch := range s {
-		if ch < '0' || ch > '9' {
-			return 0, fmt.Errorf("invalid number: %s", s)
-		}
-		result = result*10 + int(ch-'0')
+	ahead, behind, err := git.AheadBehind(ctx, sc.runner, repoPath, "HEAD", "@{upstream}")
+	if err != nil {
+		return err
 	}
-	return result, nil
+	status.Ahead = ahead
+	status.Behind = behind
+	return nil
 }
-
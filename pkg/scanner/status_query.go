@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"reposense/pkg/git"
+)
+
+// StatusQuery selects the repositories that need attention. A repository
+// matches when it meets any of the enabled conditions.
+type StatusQuery struct {
+	Dirty      bool          // 工作区有未提交的变更
+	Ahead      bool          // 领先上游，有未推送的提交
+	Behind     bool          // 落后上游
+	Diverged   bool          // 同时领先和落后上游
+	NoUpstream bool          // 当前分支没有上游
+	Detached   bool          // HEAD 处于分离状态
+	Stale      time.Duration // 最后提交早于该时长之前，0 表示不检查
+}
+
+// Empty reports whether no condition is enabled
+func (q StatusQuery) Empty() bool {
+	return q == StatusQuery{}
+}
+
+// Match reports whether status meets any enabled condition. A repository whose
+// status could not be read always matches, since nothing is known to be safe.
+func (q StatusQuery) Match(status RepositoryStatus, now time.Time) bool {
+	if status.Error != "" {
+		return true
+	}
+
+	hasWorkTree := status.Repository.Kind.HasWorkTree()
+	switch {
+	case q.Dirty && status.HasChanges:
+		return true
+	case q.Ahead && status.Ahead > 0:
+		return true
+	case q.Behind && status.Behind > 0:
+		return true
+	case q.Diverged && status.Ahead > 0 && status.Behind > 0:
+		return true
+	case q.NoUpstream && hasWorkTree && status.Upstream == "" && status.Head.State != git.HeadDetached:
+		return true
+	case q.Detached && status.Head.State == git.HeadDetached:
+		return true
+	case q.Stale > 0 && !status.LastCommitDate.IsZero() && now.Sub(status.LastCommitDate) > q.Stale:
+		return true
+	}
+	return false
+}
+
+// Filter returns the statuses that match the query, or all of them when the query is empty
+func (q StatusQuery) Filter(statuses []RepositoryStatus) []RepositoryStatus {
+	if q.Empty() {
+		return statuses
+	}
+
+	now := time.Now()
+	matched := []RepositoryStatus{}
+	for _, status := range statuses {
+		if q.Match(status, now) {
+			matched = append(matched, status)
+		}
+	}
+	return matched
+}
+
+// ParseAge parses a duration that may also use days and weeks, such as "90d", "2w" or "36h"
+func ParseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	unit := value[len(value)-1]
+	if unit == 'd' || unit == 'w' {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("无效的时长: %s (示例: 90d、2w、36h)", value)
+		}
+		day := 24 * time.Hour
+		if unit == 'w' {
+			return time.Duration(n) * 7 * day, nil
+		}
+		return time.Duration(n) * day, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("无效的时长: %s (示例: 90d、2w、36h)", value)
+	}
+	return d, nil
+}
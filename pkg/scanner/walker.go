@@ -0,0 +1,207 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// DefaultSkipDirs lists directory names that never contain repositories worth scanning
+var DefaultSkipDirs = []string{
+	"node_modules",
+	"bower_components",
+	"__pycache__",
+	".venv",
+	"venv",
+	".tox",
+	".gradle",
+	".terraform",
+	".next",
+	".nuxt",
+	"Pods",
+	".Trash",
+}
+
+// ScanOptions controls how directories are traversed during discovery
+type ScanOptions struct {
+	MaxDepth       int      `json:"max_depth"`        // 最大扫描深度，0 表示不限制
+	FollowSymlinks bool     `json:"follow_symlinks"`  // 是否跟随符号链接目录
+	Workers        int      `json:"workers"`          // 并发读取目录的协程数
+	SkipDirs       []string `json:"skip_dirs"`        // 额外跳过的目录名
+	NoDefaultSkips bool     `json:"no_default_skips"` // 不使用内置跳过列表
+	NoIgnoreFiles  bool     `json:"no_ignore_files"`  // 不读取 .reposenseignore
+	Submodules     bool     `json:"submodules"`       // 同时列出仓库中的子模块
+	Manifest       string   `json:"manifest"`         // 清单文件路径，设置后不再遍历目录
+	Groups         []string `json:"groups"`           // 只使用清单中属于这些分组的仓库
+}
+
+// DefaultScanOptions returns the default traversal settings
+func DefaultScanOptions() ScanOptions {
+	return ScanOptions{
+		Workers: runtime.NumCPU() * 4,
+	}
+}
+
+// walker performs a concurrent directory traversal
+type walker struct {
+	scanner *Scanner
+	options ScanOptions
+	skip    map[string]bool
+	sem     chan struct{}
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	repos   []Repository
+	visited map[string]bool
+}
+
+// newWalker creates a walker for the scanner's current options
+func newWalker(s *Scanner) *walker {
+	options := s.options
+	if options.Workers <= 0 {
+		options.Workers = DefaultScanOptions().Workers
+	}
+
+	skip := make(map[string]bool)
+	if !options.NoDefaultSkips {
+		for _, name := range DefaultSkipDirs {
+			skip[name] = true
+		}
+	}
+	for _, name := range options.SkipDirs {
+		skip[name] = true
+	}
+
+	return &walker{
+		scanner: s,
+		options: options,
+		skip:    skip,
+		sem:     make(chan struct{}, options.Workers),
+		visited: make(map[string]bool),
+	}
+}
+
+// walk traverses root and returns the repositories found, sorted by path
+func (w *walker) walk(root string) []Repository {
+	w.markVisited(root)
+	w.wg.Add(1)
+	go w.visit(root, 0, nil)
+	w.wg.Wait()
+
+	sort.Slice(w.repos, func(i, j int) bool {
+		return w.repos[i].Path < w.repos[j].Path
+	})
+	return w.repos
+}
+
+// visit inspects a single directory and schedules its children
+func (w *walker) visit(dir string, depth int, ignores ignoreStack) {
+	defer w.wg.Done()
+
+	w.sem <- struct{}{}
+	if repo, ok := DetectRepository(dir); ok {
+		<-w.sem
+		w.addRepository(repo)
+		// 仓库内部不再继续扫描
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err == nil && !w.options.NoIgnoreFiles {
+		ignoreFile, loadErr := loadIgnoreFile(dir)
+		if loadErr != nil {
+			w.scanner.logger.Warnf("读取忽略文件失败 %s: %v", filepath.Join(dir, IgnoreFileName), loadErr)
+		}
+		ignores = ignores.push(ignoreFile)
+	}
+	<-w.sem
+
+	if err != nil {
+		w.scanner.logger.Warnf("访问路径失败 %s: %v", dir, err)
+		return
+	}
+
+	if w.options.MaxDepth > 0 && depth >= w.options.MaxDepth {
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == ".git" || w.skip[name] {
+			continue
+		}
+
+		child := filepath.Join(dir, name)
+		if !w.isTraversable(entry, child) {
+			continue
+		}
+
+		if ignores.ignored(child) {
+			w.scanner.logger.Debugf("忽略目录: %s", child)
+			continue
+		}
+
+		w.wg.Add(1)
+		go w.visit(child, depth+1, ignores)
+	}
+}
+
+// isTraversable reports whether entry is a directory the walker should enter
+func (w *walker) isTraversable(entry os.DirEntry, path string) bool {
+	if !w.options.FollowSymlinks {
+		return entry.IsDir()
+	}
+	if entry.IsDir() {
+		return w.markVisited(path)
+	}
+	if entry.Type()&os.ModeSymlink == 0 {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+
+	// 防止符号链接造成循环
+	return w.markVisited(path)
+}
+
+// markVisited records the resolved path and reports whether it was new
+func (w *walker) markVisited(path string) bool {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		resolved = path
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.visited[resolved] {
+		return false
+	}
+	w.visited[resolved] = true
+	return true
+}
+
+// addRepository records a discovered repository and, if enabled, its submodules
+func (w *walker) addRepository(repo Repository) {
+	w.mu.Lock()
+	w.repos = append(w.repos, repo)
+	w.mu.Unlock()
+	w.scanner.logger.Debugf("发现 Git 仓库: %s (%s)", repo.Path, repo.Kind)
+
+	if !w.options.Submodules || !repo.Kind.HasWorkTree() {
+		return
+	}
+
+	for _, rel := range SubmodulePaths(repo.Path) {
+		// 未初始化的子模块没有 .git，会被自然跳过
+		if submodule, ok := DetectRepository(filepath.Join(repo.Path, rel)); ok {
+			submodule.Kind = KindSubmodule
+			submodule.Parent = repo.Path
+			w.addRepository(submodule)
+		}
+	}
+}
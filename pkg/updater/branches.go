@@ -0,0 +1,122 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+// BranchUpdateStatus is the outcome of updating a branch that is not checked out
+type BranchUpdateStatus string
+
+const (
+	BranchFastForwarded BranchUpdateStatus = "fast_forwarded" // 已快进到上游
+	BranchDiverged      BranchUpdateStatus = "diverged"       // 与上游分叉，未修改
+	BranchFailed        BranchUpdateStatus = "failed"
+)
+
+// BranchUpdate describes what --all-branches did with one local branch
+type BranchUpdate struct {
+	Branch   string             `json:"branch"`
+	Upstream string             `json:"upstream"`
+	Status   BranchUpdateStatus `json:"status"`
+	Old      string             `json:"old"`
+	New      string             `json:"new,omitempty"`
+	Ahead    int                `json:"ahead"`
+	Behind   int                `json:"behind"`
+	Message  string             `json:"message,omitempty"`
+}
+
+// ownsBranches reports whether --all-branches should update the local branches
+// through repo. Linked worktrees share branches with their main repository, so
+// they are handled once by the main repository when it is part of the batch.
+func (u *Updater) ownsBranches(repo scanner.Repository) bool {
+	switch repo.Kind {
+	case scanner.KindBare:
+		return false
+	case scanner.KindSubmodule:
+		return repo.Parent == ""
+	case scanner.KindWorktree:
+		return !u.batch[repo.Parent]
+	}
+	return true
+}
+
+// updateOtherBranches fast-forwards the local branches that are not checked
+// out in any worktree and whose upstream is strictly ahead. Only refs are
+// updated, so the working tree is never touched. When journaled is set, each
+// fast-forward is recorded in the run's journal so that undo can revert it.
+func (u *Updater) updateOtherBranches(repo scanner.Repository, result *UpdateResult, journaled bool) {
+	ctx, cancel := context.WithTimeout(u.ctx, u.repoTimeout(repo))
+	defer cancel()
+
+	// git pull 只获取当前分支的远程，其他分支可能跟踪别的远程
+	if res, err := u.runner.Run(ctx, repo.Path, "fetch", "--all", "--quiet"); err != nil {
+		class := git.ClassifyError(err, res.Output())
+		result.Message += fmt.Sprintf("，其他分支未更新 (%s)", describeGitError(class, res.Output()))
+		return
+	}
+
+	branches, err := git.ListBranches(ctx, u.runner, repo.Path, "")
+	if err != nil {
+		result.Message += "，其他分支未更新 (无法读取分支列表)"
+		return
+	}
+
+	forwarded, diverged := 0, 0
+	for _, branch := range branches {
+		// 检出中的分支 (包括当前分支) 只能通过拉取更新
+		if branch.Worktree != "" || branch.Upstream == "" || branch.UpstreamGone || branch.Behind == 0 {
+			continue
+		}
+
+		update := BranchUpdate{
+			Branch:   branch.Name,
+			Upstream: branch.Upstream,
+			Old:      branch.Commit,
+			Ahead:    branch.Ahead,
+			Behind:   branch.Behind,
+		}
+		if branch.Ahead > 0 {
+			update.Status = BranchDiverged
+			update.Message = fmt.Sprintf("与 %s 分叉 (领先 %d, 落后 %d)，未修改", branch.Upstream, branch.Ahead, branch.Behind)
+			diverged++
+			result.Branches = append(result.Branches, update)
+			continue
+		}
+
+		target, err := git.Output(ctx, u.runner, repo.Path, "rev-parse", "--verify", "--quiet", branch.Upstream+"^{commit}")
+		if err == nil {
+			// 带上旧值，分支在此期间被修改时 update-ref 会拒绝
+			_, err = u.runner.Run(ctx, repo.Path, "update-ref", "-m", "reposense: fast-forward to "+branch.Upstream,
+				"refs/heads/"+branch.Name, target, branch.Commit)
+		}
+		if err != nil {
+			update.Status = BranchFailed
+			update.Message = "快进失败: " + err.Error()
+			result.Branches = append(result.Branches, update)
+			continue
+		}
+
+		if journaled {
+			if err := u.journal.RecordRef(repo.Path, "refs/heads/"+branch.Name, branch.Commit, target); err != nil {
+				u.logger.Warnf("记录更新日志失败 %s: %v", repo.Path, err)
+			}
+		}
+
+		update.Status = BranchFastForwarded
+		update.New = target
+		update.Message = fmt.Sprintf("已快进 %d 个提交", branch.Behind)
+		forwarded++
+		result.Branches = append(result.Branches, update)
+	}
+
+	if forwarded > 0 {
+		result.Message += fmt.Sprintf("，另有%d个分支已快进", forwarded)
+	}
+	if diverged > 0 {
+		result.Message += fmt.Sprintf("，%d个分支与上游分叉", diverged)
+	}
+}
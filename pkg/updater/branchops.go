@@ -0,0 +1,488 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+// BranchOperation is a branch command applied to every repository
+type BranchOperation string
+
+const (
+	BranchCreate   BranchOperation = "create"
+	BranchCheckout BranchOperation = "checkout"
+	BranchPush     BranchOperation = "push"
+	BranchDelete   BranchOperation = "delete"
+)
+
+// FailurePolicy decides what happens to the other repositories when a branch operation partly fails
+type FailurePolicy string
+
+const (
+	FailureRollback FailurePolicy = "rollback" // 预检未通过时不做任何修改，执行失败时撤销已成功的仓库
+	FailureContinue FailurePolicy = "continue" // 跳过失败的仓库，保留其他仓库的结果
+)
+
+// ParseFailurePolicy validates a failure policy name; "" means rollback
+func ParseFailurePolicy(value string) (FailurePolicy, error) {
+	switch policy := FailurePolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return FailureRollback, nil
+	case FailureRollback, FailureContinue:
+		return policy, nil
+	}
+	return "", fmt.Errorf("无效的失败处理方式: %s (可选: rollback|continue)", value)
+}
+
+// BranchOptions describes a branch operation to run across repositories
+type BranchOptions struct {
+	Operation    BranchOperation
+	Name         string
+	StartPoint   string // create: 新分支的起点，默认为 HEAD
+	Remote       string // push 和删除远程分支时使用的远程，默认为 origin
+	DeleteRemote bool   // delete: 同时删除远程分支
+	Force        bool   // delete: 允许删除未合并或有未推送提交的分支
+	OnFailure    FailurePolicy
+}
+
+// BranchCheck is the outcome of checking whether a repository is ready for a branch operation
+type BranchCheck string
+
+const (
+	CheckReady     BranchCheck = "ready"
+	CheckNotNeeded BranchCheck = "not_needed" // 无需操作，例如分支已存在或已检出
+	CheckBlocked   BranchCheck = "blocked"
+)
+
+// BranchOpSummary records what a branch operation did in one repository
+type BranchOpSummary struct {
+	Operation     BranchOperation `json:"operation"`
+	Branch        string          `json:"branch"`
+	Check         BranchCheck     `json:"check"`
+	CheckMessage  string          `json:"check_message,omitempty"`
+	Applied       bool            `json:"applied"`
+	RolledBack    bool            `json:"rolled_back,omitempty"`
+	RollbackError string          `json:"rollback_error,omitempty"`
+
+	// 撤销操作所需的原始状态
+	PreviousBranch   string    `json:"previous_branch,omitempty"`   // checkout 前检出的分支，分离 HEAD 时为空
+	PreviousCommit   string    `json:"previous_commit,omitempty"`   // checkout 前的 HEAD，或 create/delete 涉及的分支提交
+	PreviousUpstream string    `json:"previous_upstream,omitempty"` // delete 前分支跟踪的上游
+	RemoteCommit     string    `json:"remote_commit,omitempty"`     // push/delete 前远程分支的提交，为空表示不存在
+	createdLocal     bool      // checkout 时从远程跟踪分支新建了本地分支
+	hadUpstream      bool      // push 前分支已设置上游
+	upstreamConfig   [2]string // delete 前的 branch.<name>.remote 和 branch.<name>.merge
+}
+
+// BranchRepositories applies a branch operation to every repository in three
+// phases on the worker pool: all repositories are checked first, then changed,
+// and with FailureRollback the changed ones are restored if any repository failed.
+func (u *Updater) BranchRepositories(repositories []scanner.Repository, options BranchOptions) ([]UpdateResult, error) {
+	if len(repositories) == 0 {
+		return []UpdateResult{}, nil
+	}
+	if options.Remote == "" {
+		options.Remote = "origin"
+	}
+	if options.OnFailure == "" {
+		options.OnFailure = FailureRollback
+	}
+	if _, err := u.runner.Run(u.ctx, repositories[0].Path, "check-ref-format", "--branch", options.Name); err != nil {
+		return nil, fmt.Errorf("无效的分支名: %s", options.Name)
+	}
+
+	u.logger.Infof("开始检查 %d 个仓库，分支操作 %s %s", len(repositories), options.Operation, options.Name)
+	checked := u.runBatch(repositories, func(repo scanner.Repository) UpdateResult {
+		return u.checkBranchOperation(repo, options)
+	}, nil)
+
+	byPath := make(map[string]*UpdateResult, len(checked))
+	var ready []scanner.Repository
+	blocked := false
+	for i := range checked {
+		result := &checked[i]
+		byPath[result.Repository.Path] = result
+		switch result.BranchOp.Check {
+		case CheckReady:
+			ready = append(ready, result.Repository)
+		case CheckBlocked:
+			blocked = true
+		}
+	}
+
+	// 预检未通过时，rollback 策略下不修改任何仓库
+	if blocked && options.OnFailure == FailureRollback {
+		for _, repo := range ready {
+			byPath[repo.Path].skip("未执行: 其他仓库预检未通过")
+		}
+		return finishBranchResults(checked), nil
+	}
+	if u.config.DryRun {
+		for _, repo := range ready {
+			result := byPath[repo.Path]
+			result.Success = true
+			result.Message = fmt.Sprintf("DRY RUN: 将%s分支 %s", branchOperationLabel(options.Operation), options.Name)
+		}
+		return finishBranchResults(checked), nil
+	}
+
+	u.logger.Infof("在 %d 个仓库中执行分支操作", len(ready))
+	applied := u.runBatch(ready, func(repo scanner.Repository) UpdateResult {
+		result := byPath[repo.Path]
+		u.applyBranchOperation(repo, options, result)
+		return *result
+	}, nil)
+
+	failed := false
+	for _, result := range applied {
+		*byPath[result.Repository.Path] = result
+		if !result.Success {
+			failed = true
+		}
+	}
+	for _, repo := range ready {
+		// 中断后未执行的仓库
+		if result := byPath[repo.Path]; result.Message == "" {
+			result.skip("未执行: 操作已中断")
+		}
+	}
+
+	if failed && options.OnFailure == FailureRollback {
+		var done []scanner.Repository
+		for _, result := range applied {
+			if result.BranchOp.Applied {
+				done = append(done, result.Repository)
+			}
+		}
+		u.logger.Infof("有仓库执行失败，回滚 %d 个仓库", len(done))
+		rolledBack := u.runBatch(done, func(repo scanner.Repository) UpdateResult {
+			result := byPath[repo.Path]
+			u.rollbackBranchOperation(repo, options, result)
+			return *result
+		}, nil)
+		for _, result := range rolledBack {
+			*byPath[result.Repository.Path] = result
+		}
+	}
+	return finishBranchResults(checked), nil
+}
+
+// finishBranchResults fills in the status of every result
+func finishBranchResults(results []UpdateResult) []UpdateResult {
+	for i := range results {
+		results[i].finishStatus()
+		results[i].EndTime = time.Now()
+		results[i].Duration = results[i].EndTime.Sub(results[i].StartTime)
+	}
+	return results
+}
+
+// branchOperationLabel names an operation in messages
+func branchOperationLabel(operation BranchOperation) string {
+	switch operation {
+	case BranchCreate:
+		return "创建"
+	case BranchCheckout:
+		return "检出"
+	case BranchPush:
+		return "推送"
+	case BranchDelete:
+		return "删除"
+	}
+	return string(operation)
+}
+
+// checkBranchOperation decides whether repo is ready for the operation and records
+// the state needed to roll it back. It does not change the repository.
+func (u *Updater) checkBranchOperation(repo scanner.Repository, options BranchOptions) UpdateResult {
+	result := UpdateResult{
+		Repository: repo,
+		StartTime:  time.Now(),
+		BranchOp:   &BranchOpSummary{Operation: options.Operation, Branch: options.Name},
+	}
+	summary := result.BranchOp
+	notNeeded := func(message string) UpdateResult {
+		summary.Check = CheckNotNeeded
+		summary.CheckMessage = message
+		result.skip("已跳过: " + message)
+		return result
+	}
+	block := func(message string) UpdateResult {
+		summary.Check = CheckBlocked
+		summary.CheckMessage = message
+		result.Success = false
+		if result.ErrorCode == "" {
+			result.ErrorCode = git.ErrorConflict
+		}
+		result.Message = "预检未通过: " + message
+		return result
+	}
+
+	// 子模块由父仓库决定检出的提交；工作树与主仓库共享分支，只在主仓库中操作一次
+	if repo.Kind == scanner.KindSubmodule {
+		return notNeeded("子模块由父仓库管理")
+	}
+	if repo.Kind == scanner.KindWorktree && options.Operation != BranchCheckout && u.batch[repo.Parent] {
+		return notNeeded("与主仓库共享分支")
+	}
+	if repo.Kind == scanner.KindBare && options.Operation == BranchCheckout {
+		return notNeeded("裸仓库没有工作区")
+	}
+
+	ctx, cancel := context.WithTimeout(u.ctx, u.repoTimeout(repo))
+	defer cancel()
+
+	branches, err := git.ListBranches(ctx, u.runner, repo.Path, "")
+	if err != nil {
+		return block("无法读取分支列表: " + err.Error())
+	}
+	var branch *git.BranchInfo
+	for i := range branches {
+		if branches[i].Name == options.Name {
+			branch = &branches[i]
+		}
+	}
+
+	switch options.Operation {
+	case BranchCreate:
+		if branch != nil {
+			return notNeeded("分支已存在")
+		}
+		startPoint := options.StartPoint
+		if startPoint == "" {
+			startPoint = "HEAD"
+		}
+		commit, err := git.Output(ctx, u.runner, repo.Path, "rev-parse", "--verify", "--quiet", startPoint+"^{commit}")
+		if err != nil {
+			return block(fmt.Sprintf("起点 %s 不存在", startPoint))
+		}
+		summary.PreviousCommit = commit
+
+	case BranchCheckout:
+		if branch != nil && branch.Current {
+			return notNeeded("已检出该分支")
+		}
+		if _, operation, err := repo.State(); err == nil && operation != git.OpNone {
+			return block(fmt.Sprintf("仓库中有未完成的%s，请先完成或中止", operation.Description()))
+		}
+		wt, err := git.ReadWorkingTreeStatus(ctx, u.runner, repo.Path)
+		if err != nil {
+			return block("无法读取工作区状态")
+		}
+		if wt.Staged.Total() > 0 || wt.Unstaged.Total() > 0 || len(wt.Conflicted) > 0 {
+			return block("工作区有未提交的变更")
+		}
+		if branch == nil {
+			if !git.RefExists(ctx, u.runner, repo.Path, "refs/remotes/"+options.Remote+"/"+options.Name) {
+				return block(fmt.Sprintf("本地和 %s 上都没有该分支", options.Remote))
+			}
+			summary.createdLocal = true
+		} else if branch.Worktree != "" {
+			return block("分支已在工作树 " + branch.Worktree + " 中检出")
+		}
+		summary.PreviousBranch, _ = git.CurrentBranch(ctx, u.runner, repo.Path)
+		if summary.PreviousCommit, err = git.HeadCommit(ctx, u.runner, repo.Path); err != nil {
+			return block("无法读取 HEAD")
+		}
+
+	case BranchPush:
+		if branch == nil {
+			return notNeeded("没有该分支")
+		}
+		if _, err := git.RemoteURL(ctx, u.runner, repo.Path, options.Remote); err != nil {
+			return block(fmt.Sprintf("没有远程 %s", options.Remote))
+		}
+		remote, err := u.remoteBranch(repo, options, &result)
+		if err != nil {
+			return block(err.Error())
+		}
+		if remote == branch.Commit {
+			return notNeeded("远程分支已是最新")
+		}
+		if remote != "" {
+			// 远程分支有本地没有的提交时，推送会被拒绝
+			if _, err := u.runner.Run(ctx, repo.Path, "merge-base", "--is-ancestor", remote, branch.Commit); err != nil {
+				return block(fmt.Sprintf("%s 上的分支包含本地没有的提交，请先获取并合并", options.Remote))
+			}
+		}
+		summary.PreviousCommit = branch.Commit
+		summary.RemoteCommit = remote
+		summary.hadUpstream = branch.Upstream != ""
+
+	case BranchDelete:
+		if options.DeleteRemote {
+			if _, err := git.RemoteURL(ctx, u.runner, repo.Path, options.Remote); err != nil {
+				return block(fmt.Sprintf("没有远程 %s", options.Remote))
+			}
+			remote, err := u.remoteBranch(repo, options, &result)
+			if err != nil {
+				return block(err.Error())
+			}
+			summary.RemoteCommit = remote
+		}
+		if branch == nil && summary.RemoteCommit == "" {
+			return notNeeded("没有该分支")
+		}
+		if branch != nil {
+			if branch.Worktree != "" {
+				return block("分支正在工作树 " + branch.Worktree + " 中检出")
+			}
+			if !options.Force && branch.Upstream != "" && !branch.UpstreamGone && branch.Ahead > 0 {
+				return block(fmt.Sprintf("有 %d 个未推送的提交", branch.Ahead))
+			}
+			if !options.Force && (branch.Upstream == "" || branch.UpstreamGone) {
+				if _, err := u.runner.Run(ctx, repo.Path, "merge-base", "--is-ancestor", branch.Commit, "HEAD"); err != nil {
+					return block("分支尚未合并")
+				}
+			}
+			summary.PreviousCommit = branch.Commit
+			summary.PreviousUpstream = branch.Upstream
+			// 上游可能随远程分支一起删除，回滚时直接恢复配置
+			summary.upstreamConfig[0], _ = git.Output(ctx, u.runner, repo.Path, "config", "--get", "branch."+options.Name+".remote")
+			summary.upstreamConfig[1], _ = git.Output(ctx, u.runner, repo.Path, "config", "--get", "branch."+options.Name+".merge")
+		}
+	}
+
+	summary.Check = CheckReady
+	return result
+}
+
+// remoteBranch looks up the branch on the remote, retrying network errors.
+// When the remote cannot be reached the error class is recorded on result.
+func (u *Updater) remoteBranch(repo scanner.Repository, options BranchOptions, result *UpdateResult) (string, error) {
+	var commit string
+	var err error
+	result.Attempts = u.withRetry(repo.Name, u.repoTimeout(repo), func(ctx context.Context) bool {
+		commit, err = git.RemoteBranch(ctx, u.runner, repo.Path, options.Remote, options.Name)
+		return err != nil && git.ClassifyError(err, err.Error()).Transient()
+	})
+	if err != nil {
+		result.Error = err.Error()
+		result.ErrorCode = git.ClassifyError(err, err.Error())
+		message := strings.TrimPrefix(describeGitError(result.ErrorCode, err.Error()), "更新失败: ")
+		return "", fmt.Errorf("无法查询 %s: %s", options.Remote, message)
+	}
+	return commit, nil
+}
+
+// applyBranchOperation performs the operation in a repository that passed the check
+func (u *Updater) applyBranchOperation(repo scanner.Repository, options BranchOptions, result *UpdateResult) {
+	ctx, cancel := context.WithTimeout(u.ctx, u.repoTimeout(repo))
+	defer cancel()
+	summary := result.BranchOp
+	name := options.Name
+
+	var steps [][]string
+	switch options.Operation {
+	case BranchCreate:
+		steps = [][]string{{"branch", name, summary.PreviousCommit}}
+		result.Message = fmt.Sprintf("已创建分支 %s (%s)", name, git.ShortHash(summary.PreviousCommit))
+	case BranchCheckout:
+		if summary.createdLocal {
+			steps = [][]string{{"switch", "--create", name, "--track", options.Remote + "/" + name}}
+			result.Message = fmt.Sprintf("已从 %s/%s 创建并检出分支", options.Remote, name)
+		} else {
+			steps = [][]string{{"switch", name}}
+			result.Message = "已检出分支 " + name
+		}
+	case BranchPush:
+		steps = [][]string{{"push", "--set-upstream", options.Remote, "refs/heads/" + name + ":refs/heads/" + name}}
+		result.Message = fmt.Sprintf("已推送到 %s", options.Remote)
+	case BranchDelete:
+		if summary.PreviousCommit != "" {
+			flag := "-d"
+			if options.Force {
+				flag = "-D"
+			}
+			steps = append(steps, []string{"branch", flag, name})
+		}
+		if summary.RemoteCommit != "" {
+			steps = append(steps, []string{"push", options.Remote, "--delete", name})
+		}
+		result.Message = "已删除本地分支"
+		if summary.PreviousCommit == "" {
+			result.Message = "已删除远程分支"
+		} else if summary.RemoteCommit != "" {
+			result.Message = "已删除本地和远程分支"
+		}
+	}
+
+	for i, args := range steps {
+		res, err := u.runner.Run(ctx, repo.Path, args...)
+		if err != nil {
+			u.failGit(result, err, res.Output())
+			result.Message = strings.Replace(result.Message, "更新失败", branchOperationLabel(options.Operation)+"失败", 1)
+			// 删除本地分支后远程删除失败，仍需回滚本地分支
+			summary.Applied = i > 0
+			return
+		}
+	}
+	summary.Applied = true
+	result.Success = true
+}
+
+// rollbackBranchOperation restores a repository changed by applyBranchOperation
+func (u *Updater) rollbackBranchOperation(repo scanner.Repository, options BranchOptions, result *UpdateResult) {
+	// 回滚不受中断影响，避免仓库之间停留在不一致的状态
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(u.ctx), u.repoTimeout(repo))
+	defer cancel()
+	summary := result.BranchOp
+	name := options.Name
+	ref := "refs/heads/" + name
+
+	var steps [][]string
+	switch options.Operation {
+	case BranchCreate:
+		// 带上旧值，分支在此期间被修改时 update-ref 会拒绝
+		steps = [][]string{{"update-ref", "-d", ref, summary.PreviousCommit}}
+	case BranchCheckout:
+		if summary.PreviousBranch != "" {
+			steps = append(steps, []string{"switch", summary.PreviousBranch})
+		} else {
+			steps = append(steps, []string{"switch", "--detach", summary.PreviousCommit})
+		}
+		if summary.createdLocal {
+			steps = append(steps, []string{"branch", "-D", name})
+		}
+	case BranchPush:
+		if summary.RemoteCommit == "" {
+			steps = append(steps, []string{"push", options.Remote, "--delete", name})
+		} else {
+			steps = append(steps, []string{"push", "--force-with-lease=" + ref + ":" + summary.PreviousCommit,
+				options.Remote, summary.RemoteCommit + ":" + ref})
+		}
+		if !summary.hadUpstream {
+			steps = append(steps, []string{"branch", "--unset-upstream", name})
+		}
+	case BranchDelete:
+		if summary.RemoteCommit != "" && result.Success {
+			steps = append(steps, []string{"push", options.Remote, summary.RemoteCommit + ":" + ref})
+		}
+		if summary.PreviousCommit != "" && !git.RefExists(ctx, u.runner, repo.Path, ref) {
+			steps = append(steps, []string{"branch", "--no-track", name, summary.PreviousCommit})
+			if remote, merge := summary.upstreamConfig[0], summary.upstreamConfig[1]; remote != "" && merge != "" {
+				steps = append(steps,
+					[]string{"config", "branch." + name + ".remote", remote},
+					[]string{"config", "branch." + name + ".merge", merge})
+			}
+		}
+	}
+
+	for _, args := range steps {
+		if res, err := u.runner.Run(ctx, repo.Path, args...); err != nil {
+			summary.RollbackError, _, _ = strings.Cut(strings.TrimSpace(res.Output()), "\n")
+			if summary.RollbackError == "" {
+				summary.RollbackError = err.Error()
+			}
+			result.Message += "，回滚失败"
+			return
+		}
+	}
+	summary.RolledBack = true
+	result.Message += "，已回滚"
+}
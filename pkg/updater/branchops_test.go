@@ -0,0 +1,109 @@
+package updater
+
+import (
+	"strings"
+	"testing"
+
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+func TestBranchCreateRollback(t *testing.T) {
+	a, b := newFakeRepo(t, "a"), newFakeRepo(t, "b")
+	// 两个仓库共用 a 的 FakeRunner
+	runner := a.runner
+	runner.Handler = nil
+	for _, f := range []*fakeRepo{a, b} {
+		runner.SetOutput(f.repo.Path, "", "for-each-ref",
+			"--format=%(refname:short)%00%(objectname)%00%(HEAD)%00%(upstream:short)%00%(upstream:track,nobracket)%00%(committerdate:iso-strict)%00%(worktreepath)",
+			"refs/heads")
+		runner.SetOutput(f.repo.Path, oldHead, "rev-parse", "--verify", "--quiet", "HEAD^{commit}")
+		runner.SetOutput(f.repo.Path, "", "update-ref", "-d", "refs/heads/feature", oldHead)
+	}
+	runner.SetOutput("", "", "check-ref-format", "--branch", "feature")
+	runner.SetOutput(a.repo.Path, "", "branch", "feature", oldHead)
+	runner.Set(b.repo.Path, git.FakeResponse{Stderr: "fatal: cannot lock ref 'refs/heads/feature'", ExitCode: 128}, "branch", "feature", oldHead)
+
+	tests := []struct {
+		policy     FailurePolicy
+		rolledBack bool
+	}{
+		{FailureRollback, true},
+		{FailureContinue, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			u := a.updater(UpdaterConfig{})
+			results, err := u.BranchRepositories([]scanner.Repository{a.repo, b.repo},
+				BranchOptions{Operation: BranchCreate, Name: "feature", OnFailure: tt.policy})
+			if err != nil {
+				t.Fatalf("BranchRepositories() error: %v", err)
+			}
+
+			byName := map[string]UpdateResult{}
+			for _, result := range results {
+				byName[result.Repository.Name] = result
+			}
+			if got := byName["b"]; got.Status != StatusFailed || got.BranchOp.Applied {
+				t.Errorf("b = %q, applied %v, want a failure that changed nothing", got.Status, got.BranchOp.Applied)
+			}
+			got := byName["a"]
+			if !got.BranchOp.Applied || got.BranchOp.RolledBack != tt.rolledBack {
+				t.Errorf("a: applied %v, rolled back %v, want applied, rolled back %v", got.BranchOp.Applied, got.BranchOp.RolledBack, tt.rolledBack)
+			}
+			if tt.rolledBack && !strings.HasSuffix(got.Message, "，已回滚") {
+				t.Errorf("a: Message = %q, want it to report the rollback", got.Message)
+			}
+		})
+	}
+
+	// 只有 rollback 策略删除了 a 中创建的分支
+	deletes := 0
+	for _, call := range runner.Calls() {
+		if strings.Join(call.Args, " ") == "update-ref -d refs/heads/feature "+oldHead {
+			if call.Dir != a.repo.Path {
+				t.Errorf("rolled back %s, want only %s", call.Dir, a.repo.Path)
+			}
+			deletes++
+		}
+	}
+	if deletes != 1 {
+		t.Errorf("update-ref -d ran %d times, want 1", deletes)
+	}
+}
+
+func TestBranchCheckBlocksAll(t *testing.T) {
+	a, b := newFakeRepo(t, "a"), newFakeRepo(t, "b")
+	runner := a.runner
+	runner.Handler = nil
+	runner.SetOutput("", "", "check-ref-format", "--branch", "feature")
+	for _, f := range []*fakeRepo{a, b} {
+		runner.SetOutput(f.repo.Path, "", "for-each-ref",
+			"--format=%(refname:short)%00%(objectname)%00%(HEAD)%00%(upstream:short)%00%(upstream:track,nobracket)%00%(committerdate:iso-strict)%00%(worktreepath)",
+			"refs/heads")
+	}
+	// b 的起点不存在，rollback 策略下 a 也不应被修改
+	runner.SetOutput(a.repo.Path, oldHead, "rev-parse", "--verify", "--quiet", "HEAD^{commit}")
+
+	u := a.updater(UpdaterConfig{})
+	results, err := u.BranchRepositories([]scanner.Repository{a.repo, b.repo},
+		BranchOptions{Operation: BranchCreate, Name: "feature"})
+	if err != nil {
+		t.Fatalf("BranchRepositories() error: %v", err)
+	}
+	for _, result := range results {
+		want := StatusSkipped
+		if result.Repository.Name == "b" {
+			want = StatusFailed
+		}
+		if result.Status != want {
+			t.Errorf("%s: Status = %q (%s), want %q", result.Repository.Name, result.Status, result.Message, want)
+		}
+	}
+	for _, call := range runner.Calls() {
+		if call.Args[0] == "branch" {
+			t.Errorf("ran git %s, want no changes", strings.Join(call.Args, " "))
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"reposense/pkg/git"
+)
+
+// maxIncomingCommits bounds how many incoming commits a result lists
+const maxIncomingCommits = 20
+
+// dependencyManifests are the file names whose changes usually require reinstalling dependencies
+var dependencyManifests = map[string]bool{
+	"go.mod": true, "go.sum": true,
+	"package.json": true, "package-lock.json": true, "yarn.lock": true, "pnpm-lock.yaml": true,
+	"requirements.txt": true, "pyproject.toml": true, "setup.py": true, "Pipfile": true, "Pipfile.lock": true, "poetry.lock": true,
+	"Cargo.toml": true, "Cargo.lock": true,
+	"pom.xml": true, "build.gradle": true, "build.gradle.kts": true,
+	"composer.json": true, "composer.lock": true,
+	"Gemfile": true, "Gemfile.lock": true,
+	"Podfile": true, "Podfile.lock": true,
+	"pubspec.yaml": true, "pubspec.lock": true,
+}
+
+// PullChanges describes what a pull brought into a repository
+type PullChanges struct {
+	OldHead     string       `json:"old_head"`
+	NewHead     string       `json:"new_head"`
+	Commits     []git.Commit `json:"commits,omitempty"` // 最多 maxIncomingCommits 个，最新的在前
+	CommitCount int          `json:"commit_count"`
+	git.DiffStat
+	NewTags   []string `json:"new_tags,omitempty"`
+	Manifests []string `json:"manifests,omitempty"` // 发生变化的依赖清单文件
+}
+
+// pullSnapshot is the state of a repository captured before a pull
+type pullSnapshot struct {
+	head string
+	tags map[string]string
+}
+
+// snapshotBeforePull records HEAD and tags so that the pull can be described afterwards
+func (u *Updater) snapshotBeforePull(ctx context.Context, repoPath string) *pullSnapshot {
+	head, err := git.HeadCommit(ctx, u.runner, repoPath)
+	if err != nil {
+		// 空仓库没有 HEAD
+		return nil
+	}
+	tags, err := git.ListRefs(ctx, u.runner, repoPath, "refs/tags")
+	if err != nil {
+		return nil
+	}
+	return &pullSnapshot{head: head, tags: tags}
+}
+
+// pullChanges compares a repository with the snapshot taken before the pull.
+// It returns nil when HEAD did not move and no tags arrived.
+func (u *Updater) pullChanges(ctx context.Context, repoPath string, before *pullSnapshot) *PullChanges {
+	if before == nil {
+		return nil
+	}
+
+	changes := &PullChanges{OldHead: before.head}
+	head, err := git.HeadCommit(ctx, u.runner, repoPath)
+	if err != nil {
+		u.logger.Debugf("读取拉取后的HEAD失败 %s: %v", repoPath, err)
+		return nil
+	}
+	changes.NewHead = head
+
+	if tags, err := git.ListRefs(ctx, u.runner, repoPath, "refs/tags"); err == nil {
+		for ref := range tags {
+			if _, existed := before.tags[ref]; !existed {
+				changes.NewTags = append(changes.NewTags, strings.TrimPrefix(ref, "refs/tags/"))
+			}
+		}
+		sort.Strings(changes.NewTags)
+	}
+
+	if changes.OldHead == changes.NewHead {
+		if len(changes.NewTags) == 0 {
+			return nil
+		}
+		return changes
+	}
+
+	if commits, total, err := git.IncomingCommits(ctx, u.runner, repoPath, changes.OldHead, changes.NewHead, maxIncomingCommits); err == nil {
+		changes.Commits = commits
+		changes.CommitCount = total
+	} else {
+		u.logger.Debugf("读取新提交失败 %s: %v", repoPath, err)
+	}
+
+	if stat, err := git.Diff(ctx, u.runner, repoPath, changes.OldHead, changes.NewHead); err == nil {
+		changes.DiffStat = stat
+		for _, file := range stat.Paths {
+			if dependencyManifests[path.Base(file)] {
+				changes.Manifests = append(changes.Manifests, file)
+			}
+		}
+	} else {
+		u.logger.Debugf("统计文件变更失败 %s: %v", repoPath, err)
+	}
+	return changes
+}
+
+// summary describes the changes in a few words for the result message
+func (c *PullChanges) summary() string {
+	var parts []string
+	if c.OldHead != c.NewHead {
+		parts = append(parts, fmt.Sprintf("%d个新提交", c.CommitCount))
+		parts = append(parts, fmt.Sprintf("%d个文件 (+%d -%d)", c.FilesChanged, c.Insertions, c.Deletions))
+	}
+	if len(c.NewTags) > 0 {
+		parts = append(parts, fmt.Sprintf("%d个新标签", len(c.NewTags)))
+	}
+	if len(c.Manifests) > 0 {
+		parts = append(parts, "依赖清单有变化")
+	}
+	return strings.Join(parts, ", ")
+}
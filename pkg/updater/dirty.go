@@ -0,0 +1,151 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+// DirtyPolicy decides what update does with a working tree that has uncommitted changes
+type DirtyPolicy string
+
+const (
+	DirtySkip  DirtyPolicy = "skip"  // 跳过有未提交变更的仓库
+	DirtyStash DirtyPolicy = "stash" // 先贮藏变更，拉取后恢复
+	DirtyFail  DirtyPolicy = "fail"  // 不拉取，直接报告失败
+)
+
+// autoStashMessage marks stashes created by update
+const autoStashMessage = "reposense auto-stash"
+
+// ParseDirtyPolicy validates a dirty policy name; "" means skip
+func ParseDirtyPolicy(value string) (DirtyPolicy, error) {
+	switch policy := DirtyPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return DirtySkip, nil
+	case DirtySkip, DirtyStash, DirtyFail:
+		return policy, nil
+	}
+	return "", fmt.Errorf("无效的脏工作区策略: %s (可选: skip|stash|fail)", value)
+}
+
+// ResultStatus is the outcome of updating a single repository
+type ResultStatus string
+
+const (
+	StatusSuccess ResultStatus = "success"
+	StatusSkipped ResultStatus = "skipped"
+	StatusFailed  ResultStatus = "failed"
+)
+
+// skip marks the result as skipped, which is not a failure
+func (r *UpdateResult) skip(message string) {
+	r.Success = true
+	r.Status = StatusSkipped
+	r.Message = message
+}
+
+// finishStatus derives Status from Success for results that did not set it
+func (r *UpdateResult) finishStatus() {
+	if r.Status != "" {
+		return
+	}
+	if r.Success {
+		r.Status = StatusSuccess
+	} else {
+		r.Status = StatusFailed
+		if r.ErrorCode == "" {
+			r.ErrorCode = git.ErrorUnknown
+		}
+	}
+}
+
+// pullDirtyRepository applies the dirty policy before pulling a repository with a working tree
+func (u *Updater) pullDirtyRepository(ctx context.Context, repo scanner.Repository, result *UpdateResult) {
+	wt, err := git.ReadWorkingTreeStatus(ctx, u.runner, repo.Path)
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+		result.Message = "更新失败: 无法读取工作区状态"
+		return
+	}
+
+	// 未跟踪文件不影响拉取，只有已跟踪文件的变更算作脏工作区
+	dirty := wt.Staged.Total() > 0 || wt.Unstaged.Total() > 0 || len(wt.Conflicted) > 0
+	if !dirty {
+		u.pullRepository(ctx, repo, result)
+		return
+	}
+
+	policy := u.config.DirtyPolicy
+	if policy == "" {
+		policy = DirtySkip
+	}
+
+	switch policy {
+	case DirtyFail:
+		result.Success = false
+		result.Error = "工作区有未提交的变更"
+		result.ErrorCode = git.ErrorConflict
+		result.Message = "更新失败: 工作区有未提交的变更"
+	case DirtyStash:
+		if len(wt.Conflicted) > 0 {
+			// 有冲突的工作区无法贮藏
+			result.Success = false
+			result.Error = "存在未解决的冲突"
+			result.ErrorCode = git.ErrorConflict
+			result.Message = "更新失败: 存在未解决的冲突，无法贮藏"
+			return
+		}
+		u.pullWithStash(ctx, repo, result)
+	default:
+		result.skip("已跳过: 工作区有未提交的变更")
+	}
+}
+
+// pullWithStash stashes local changes, pulls and restores them
+func (u *Updater) pullWithStash(ctx context.Context, repo scanner.Repository, result *UpdateResult) {
+	res, err := u.runner.Run(ctx, repo.Path, "stash", "push", "-m", autoStashMessage)
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+		result.Message = "更新失败: 贮藏本地变更失败"
+		return
+	}
+	if strings.Contains(res.Output(), "No local changes to save") {
+		u.pullRepository(ctx, repo, result)
+		return
+	}
+
+	u.pullRepository(ctx, repo, result)
+	pulled := result.Success
+
+	// 无论拉取是否成功都要恢复变更；恢复不受拉取超时或取消的影响
+	popCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), u.repoTimeout(repo))
+	defer cancel()
+	res, err = u.runner.Run(popCtx, repo.Path, "stash", "pop")
+	if err != nil {
+		// 变更仍在 stash 中，不能重试，否则会再次贮藏
+		reason := "恢复贮藏失败"
+		result.ErrorCode = git.ErrorUnknown
+		if strings.Contains(res.Output(), "CONFLICT") {
+			reason = "恢复贮藏时发生冲突，请手动解决"
+			result.ErrorCode = git.ErrorConflict
+		}
+		if pulled {
+			result.Message = fmt.Sprintf("已拉取，但%s，变更仍保存在 stash 中", reason)
+		} else {
+			result.Message = fmt.Sprintf("%s；%s，变更仍保存在 stash 中", result.Message, reason)
+		}
+		result.Success = false
+		result.Error = err.Error()
+		return
+	}
+
+	if pulled {
+		result.Message += "，已恢复本地变更"
+	}
+}
@@ -0,0 +1,103 @@
+package updater
+
+import (
+	"strings"
+	"testing"
+
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+func TestUpdateDirtyPolicies(t *testing.T) {
+	tests := []struct {
+		policy DirtyPolicy
+		status ResultStatus
+		pulls  int
+	}{
+		{"", StatusSkipped, 0},
+		{DirtySkip, StatusSkipped, 0},
+		{DirtyFail, StatusFailed, 0},
+		{DirtyStash, StatusSuccess, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			f := newFakeRepo(t, "r")
+			f.dirty = true
+			u := f.updater(UpdaterConfig{DirtyPolicy: tt.policy})
+
+			results, _ := u.UpdateRepositories([]scanner.Repository{f.repo}, nil)
+			if results[0].Status != tt.status {
+				t.Errorf("Status = %q (%s), want %q", results[0].Status, results[0].Message, tt.status)
+			}
+			if got := f.count("pull"); got != tt.pulls {
+				t.Errorf("pulls = %d, want %d", got, tt.pulls)
+			}
+		})
+	}
+}
+
+func TestPullWithStash(t *testing.T) {
+	tests := []struct {
+		name    string
+		pull    git.FakeResponse
+		pop     git.FakeResponse
+		status  ResultStatus
+		code    git.ErrorClass
+		message string
+	}{
+		{
+			name:    "restored",
+			pull:    git.FakeResponse{Stdout: "Fast-forward\n"},
+			status:  StatusSuccess,
+			message: "已恢复本地变更",
+		},
+		{
+			name:    "pop conflict",
+			pull:    git.FakeResponse{Stdout: "Fast-forward\n"},
+			pop:     git.FakeResponse{Stdout: "CONFLICT (content): Merge conflict in file.txt\n", ExitCode: 1},
+			status:  StatusFailed,
+			code:    git.ErrorConflict,
+			message: "已拉取，但恢复贮藏时发生冲突",
+		},
+		{
+			// 拉取失败时仍要恢复变更
+			name:    "pull failed",
+			pull:    git.FakeResponse{Stderr: "fatal: Not possible to fast-forward, aborting.", ExitCode: 128},
+			status:  StatusFailed,
+			code:    git.ErrorNonFastForward,
+			message: "非快进更新",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRepo(t, "r")
+			f.dirty = true
+			f.pulls = []git.FakeResponse{tt.pull}
+			f.pop = tt.pop
+			u := f.updater(UpdaterConfig{DirtyPolicy: DirtyStash})
+
+			results, _ := u.UpdateRepositories([]scanner.Repository{f.repo}, nil)
+			result := results[0]
+			if result.Status != tt.status || result.ErrorCode != tt.code {
+				t.Errorf("Status, ErrorCode = %q, %q, want %q, %q", result.Status, result.ErrorCode, tt.status, tt.code)
+			}
+			if !strings.Contains(result.Message, tt.message) {
+				t.Errorf("Message = %q, want it to contain %q", result.Message, tt.message)
+			}
+
+			// 贮藏、拉取、恢复依次执行，且各只执行一次
+			var order []string
+			for _, call := range f.calls() {
+				if strings.HasPrefix(call, "stash") || strings.HasPrefix(call, "pull") {
+					order = append(order, strings.Fields(call)[0]+" "+strings.Fields(call)[1])
+				}
+			}
+			want := []string{"stash push", "pull --no-edit", "stash pop"}
+			if strings.Join(order, ",") != strings.Join(want, ",") {
+				t.Errorf("commands = %q, want %q", order, want)
+			}
+		})
+	}
+}
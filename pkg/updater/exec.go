@@ -0,0 +1,176 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+// maxExecOutput bounds how much output of an exec command is kept per repository
+const maxExecOutput = 64 << 10
+
+// ExecOptions controls how ExecRepositories runs a command
+type ExecOptions struct {
+	FailFast bool      // 有仓库失败后不再启动新的命令
+	Stream   io.Writer // 不为空时实时输出，每行带仓库名前缀；为空时只记录在结果中
+}
+
+// ExecSummary is the outcome of running a command in one repository
+type ExecSummary struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"` // 未执行或被终止时为 -1
+	Output   string `json:"output,omitempty"`
+}
+
+// ExecRepositories runs command in every repository on the worker pool.
+// With FailFast, repositories not yet started after the first failure are
+// reported as skipped.
+func (u *Updater) ExecRepositories(repositories []scanner.Repository, command []string, options ExecOptions, progressCallback func(UpdateResult)) ([]UpdateResult, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("没有指定要执行的命令")
+	}
+	if len(repositories) == 0 {
+		return []UpdateResult{}, nil
+	}
+
+	var failed atomic.Bool
+	var streamMu sync.Mutex
+	operation := func(repo scanner.Repository) UpdateResult {
+		if options.FailFast && failed.Load() {
+			result := UpdateResult{Repository: repo, StartTime: time.Now()}
+			result.skip("已跳过: 其他仓库执行失败 (--fail-fast)")
+			result.Exec = &ExecSummary{Command: strings.Join(command, " "), ExitCode: -1}
+			result.EndTime = result.StartTime
+			return result
+		}
+
+		var stream *prefixWriter
+		if options.Stream != nil {
+			stream = &prefixWriter{mu: &streamMu, out: options.Stream, prefix: "[" + repo.Name + "] "}
+		}
+		result := u.execRepository(repo, command, stream)
+		if !result.Success {
+			failed.Store(true)
+		}
+		return result
+	}
+
+	u.logger.Infof("开始在 %d 个仓库中执行命令，使用 %d 个工作协程", len(repositories), u.config.WorkerCount)
+	results := u.runBatch(repositories, operation, progressCallback)
+	u.logger.Infof("执行完成，共处理 %d 个仓库", len(results))
+	return results, nil
+}
+
+// execRepository runs command in the repository directory with the per-repository timeout
+func (u *Updater) execRepository(repo scanner.Repository, command []string, stream *prefixWriter) (result UpdateResult) {
+	result = UpdateResult{
+		Repository: repo,
+		StartTime:  time.Now(),
+		Exec:       &ExecSummary{Command: strings.Join(command, " "), ExitCode: -1},
+	}
+	defer func() {
+		result.finishStatus()
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+	}()
+
+	timeout := u.repoTimeout(repo)
+
+	if u.config.DryRun {
+		result.Success = true
+		result.Message = "DRY RUN: 将执行 " + result.Exec.Command
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(u.ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = repo.Path
+	cmd.Env = append(os.Environ(),
+		"REPOSENSE_REPO="+repo.Path,
+		"REPOSENSE_NAME="+repo.Name,
+	)
+	cmd.WaitDelay = time.Second
+	var output bytes.Buffer
+	var out io.Writer = &output
+	if stream != nil {
+		out = io.MultiWriter(&output, stream)
+		defer stream.flush()
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	result.Exec.Output = tailOutput(output.String(), maxExecOutput)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.Success = true
+		result.Exec.ExitCode = 0
+		result.Message = "执行成功"
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.ErrorCode = git.ErrorTimeout
+		result.Message = fmt.Sprintf("执行超时 (%v)", timeout)
+		result.Error = err.Error()
+	case errors.As(err, &exitErr):
+		result.Exec.ExitCode = exitErr.ExitCode()
+		result.Message = fmt.Sprintf("退出码 %d", result.Exec.ExitCode)
+		result.Error = err.Error()
+	default:
+		result.Message = "无法执行命令: " + err.Error()
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// prefixWriter writes complete lines to a shared writer, each prefixed with
+// the repository name, so that concurrent output does not interleave mid-line
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+// Write buffers p and writes out every complete line
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf[:i+1])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush writes a trailing line that did not end with a newline
+func (w *prefixWriter) flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+// writeLine writes one prefixed line while holding the shared lock
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	io.WriteString(w.out, w.prefix)
+	w.out.Write(line)
+}
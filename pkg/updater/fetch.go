@@ -0,0 +1,201 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+// RefUpdate describes a remote-tracking ref or tag that moved during a fetch
+type RefUpdate struct {
+	Ref     string `json:"ref"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Commits int    `json:"commits"` // 新位置上多出的提交数
+}
+
+// FetchSummary describes what a fetch changed
+type FetchSummary struct {
+	Updated     []RefUpdate `json:"updated,omitempty"`
+	Added       []string    `json:"added,omitempty"`
+	Deleted     []string    `json:"deleted,omitempty"`      // 已在远程删除并被清理的分支
+	DeletedTags []string    `json:"deleted_tags,omitempty"` // 远程已不存在并被清理的标签
+	NewCommits  int         `json:"new_commits"`
+}
+
+// Empty reports whether the fetch changed nothing
+func (s *FetchSummary) Empty() bool {
+	return len(s.Updated) == 0 && len(s.Added) == 0 && len(s.Deleted) == 0 && len(s.DeletedTags) == 0
+}
+
+// FetchRepositories refreshes the remote-tracking refs of repositories in parallel
+// without touching their working trees
+func (u *Updater) FetchRepositories(repositories []scanner.Repository, progressCallback func(UpdateResult)) ([]UpdateResult, error) {
+	if len(repositories) == 0 {
+		return []UpdateResult{}, nil
+	}
+
+	u.logger.Infof("开始获取 %d 个仓库的远程更新，使用 %d 个工作协程", len(repositories), u.config.WorkerCount)
+	results := u.runBatch(repositories, u.fetchRepository, progressCallback)
+	u.logger.Infof("获取完成，共处理 %d 个仓库", len(results))
+	return results, nil
+}
+
+// fetchRepository runs git fetch --all --prune --tags and records which refs moved
+func (u *Updater) fetchRepository(repo scanner.Repository) (result UpdateResult) {
+	result = UpdateResult{
+		Repository: repo,
+		StartTime:  time.Now(),
+	}
+	defer func() {
+		result.finishStatus()
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+	}()
+
+	options := u.repoOptions[repo.Path]
+	timeout := u.repoTimeout(repo)
+
+	if options.SkipUpdate {
+		result.skip("已跳过: 清单中设置了 skip_update")
+		return result
+	}
+	if u.config.DryRun {
+		result.Success = true
+		result.Message = "DRY RUN: 模拟获取成功"
+		return result
+	}
+
+	// 链接工作树共享远程跟踪分支，依次执行
+	unlock := u.lockObjectStore(repo)
+	defer unlock()
+
+	// 裸克隆没有 refs/remotes，分支直接保存在 refs/heads
+	prefixes := []string{"refs/remotes", "refs/tags"}
+	if repo.Kind == scanner.KindBare {
+		prefixes = append(prefixes, "refs/heads")
+	}
+
+	ctx, cancel := context.WithTimeout(u.ctx, timeout)
+	defer cancel()
+	before, err := git.ListRefs(ctx, u.runner, repo.Path, prefixes...)
+	if err != nil {
+		result.Error = err.Error()
+		result.Message = "获取失败: 无法读取引用"
+		return result
+	}
+
+	// 只重试 fetch 本身，获取前的引用快照保持不变
+	var fetchErr error
+	var output string
+	result.Attempts = u.withRetry(repo.Name, timeout, func(ctx context.Context) bool {
+		res, err := u.runner.Run(ctx, repo.Path, "fetch", "--all", "--prune", "--tags")
+		fetchErr, output = err, res.Output()
+		return err != nil && git.ClassifyError(err, output).Transient()
+	})
+	if fetchErr != nil {
+		u.failGit(&result, fetchErr, output)
+		return result
+	}
+
+	// 重试可能已用尽上面的超时，比较引用使用新的超时
+	ctx, cancel = context.WithTimeout(u.ctx, timeout)
+	defer cancel()
+	after, err := git.ListRefs(ctx, u.runner, repo.Path, prefixes...)
+	if err != nil {
+		result.Error = err.Error()
+		result.Message = "获取成功，但无法读取更新后的引用"
+		result.Success = true
+		return result
+	}
+
+	summary := u.compareRefs(ctx, repo.Path, before, after)
+	result.Success = true
+	result.Fetch = summary
+	result.Message = describeFetch(summary)
+	return result
+}
+
+// compareRefs works out which refs were added, moved or deleted by a fetch
+func (u *Updater) compareRefs(ctx context.Context, repoPath string, before, after map[string]string) *FetchSummary {
+	summary := &FetchSummary{}
+	var newTips []string
+
+	for ref, hash := range after {
+		// origin/HEAD 只是指向默认分支的符号引用
+		if strings.HasSuffix(ref, "/HEAD") {
+			continue
+		}
+		old, existed := before[ref]
+		switch {
+		case !existed:
+			summary.Added = append(summary.Added, ref)
+			newTips = append(newTips, hash)
+		case old != hash:
+			update := RefUpdate{Ref: ref, Old: old, New: hash}
+			if count, err := git.CountCommits(ctx, u.runner, repoPath, old+".."+hash); err == nil {
+				update.Commits = count
+			}
+			summary.Updated = append(summary.Updated, update)
+			newTips = append(newTips, hash)
+		}
+	}
+	for ref := range before {
+		if _, ok := after[ref]; ok || strings.HasSuffix(ref, "/HEAD") {
+			continue
+		}
+		if strings.HasPrefix(ref, "refs/tags/") {
+			summary.DeletedTags = append(summary.DeletedTags, ref)
+		} else {
+			summary.Deleted = append(summary.Deleted, ref)
+		}
+	}
+
+	sort.Strings(summary.Added)
+	sort.Strings(summary.Deleted)
+	sort.Strings(summary.DeletedTags)
+	sort.Slice(summary.Updated, func(i, j int) bool {
+		return summary.Updated[i].Ref < summary.Updated[j].Ref
+	})
+
+	// 新到达的提交: 从新位置可达、但获取前从任何引用都不可达
+	if len(newTips) > 0 {
+		args := append([]string{"rev-list", "--count"}, newTips...)
+		args = append(args, "--not", "--branches")
+		for _, hash := range before {
+			args = append(args, hash)
+		}
+		if out, err := git.Output(ctx, u.runner, repoPath, args...); err == nil {
+			summary.NewCommits, _ = git.ParseCount(out)
+		}
+	}
+	return summary
+}
+
+// describeFetch summarizes a fetch for the result message
+func describeFetch(summary *FetchSummary) string {
+	if summary.Empty() {
+		return "已是最新"
+	}
+
+	var parts []string
+	if n := len(summary.Updated); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d个引用更新", n))
+	}
+	if n := len(summary.Added); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d个新引用", n))
+	}
+	if n := len(summary.Deleted); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d个分支已在远程删除", n))
+	}
+	if n := len(summary.DeletedTags); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d个标签已在远程删除", n))
+	}
+	parts = append(parts, fmt.Sprintf("%d个新提交", summary.NewCommits))
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,186 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"reposense/pkg/filter"
+	"reposense/pkg/scanner"
+)
+
+// maxHookOutput bounds how much hook output is kept in a result
+const maxHookOutput = 4000
+
+// PostUpdateHook is a command that runs in a repository after a pull changed matching paths
+type PostUpdateHook struct {
+	Name          string        `json:"name"`
+	Command       string        `json:"command"`        // 通过 sh -c 在仓库目录中执行
+	Paths         []string      `json:"paths"`          // 触发钩子的路径 glob，不含 "/" 时匹配任意目录下的文件名
+	Timeout       time.Duration `json:"timeout"`        // 0 表示使用更新超时
+	MaxConcurrent int           `json:"max_concurrent"` // 同时运行的实例数，0 表示 1
+}
+
+// HookResult is the outcome of running a post-update hook in one repository
+type HookResult struct {
+	Name     string        `json:"name"`
+	Command  string        `json:"command"`
+	Trigger  string        `json:"trigger"` // 触发钩子的第一个变更路径
+	Success  bool          `json:"success"`
+	Output   string        `json:"output,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// compiledHook is a hook with its path globs compiled and its concurrency slots
+type compiledHook struct {
+	PostUpdateHook
+	patterns []*regexp.Regexp
+	slots    chan struct{}
+}
+
+// compileHooks validates hooks and compiles their path globs
+func compileHooks(hooks []PostUpdateHook) ([]*compiledHook, error) {
+	compiled := make([]*compiledHook, 0, len(hooks))
+	for i, hook := range hooks {
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("hook-%d", i+1)
+		}
+		if strings.TrimSpace(hook.Command) == "" {
+			return nil, fmt.Errorf("钩子 %s 没有设置命令", hook.Name)
+		}
+		if len(hook.Paths) == 0 {
+			return nil, fmt.Errorf("钩子 %s 没有设置触发路径", hook.Name)
+		}
+
+		c := &compiledHook{PostUpdateHook: hook, slots: make(chan struct{}, max(hook.MaxConcurrent, 1))}
+		for _, glob := range hook.Paths {
+			// 与 .gitignore 相同，开头或中间含 "/" 的模式相对于仓库根目录，其他模式匹配任意目录
+			anchored := strings.Contains(glob, "/")
+			glob = strings.TrimPrefix(glob, "/")
+			if !anchored {
+				glob = "**/" + glob
+			}
+			re, err := regexp.Compile("^" + filter.GlobRegex(glob) + "$")
+			if err != nil {
+				return nil, fmt.Errorf("钩子 %s 的路径模式无效 %q: %w", hook.Name, glob, err)
+			}
+			c.patterns = append(c.patterns, re)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// trigger returns the first changed path matching the hook, or ""
+func (h *compiledHook) trigger(paths []string) string {
+	for _, path := range paths {
+		for _, re := range h.patterns {
+			if re.MatchString(path) {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// runHooks runs the hooks whose paths were touched by the pull recorded in result
+func (u *Updater) runHooks(repo scanner.Repository, result *UpdateResult) {
+	if result.Changes == nil || len(result.Changes.Paths) == 0 {
+		return
+	}
+
+	failed := 0
+	for _, hook := range u.hooks {
+		trigger := hook.trigger(result.Changes.Paths)
+		if trigger == "" {
+			continue
+		}
+
+		// 等待空位时仍响应中断
+		select {
+		case hook.slots <- struct{}{}:
+		case <-u.ctx.Done():
+			return
+		}
+		hookResult := u.runHook(repo, hook, result.Changes)
+		<-hook.slots
+
+		hookResult.Trigger = trigger
+		if !hookResult.Success {
+			failed++
+		}
+		result.Hooks = append(result.Hooks, hookResult)
+	}
+
+	if len(result.Hooks) > 0 {
+		if failed > 0 {
+			result.Message += fmt.Sprintf("，%d个钩子失败", failed)
+		} else {
+			result.Message += fmt.Sprintf("，已运行%d个钩子", len(result.Hooks))
+		}
+	}
+}
+
+// runHook executes a single hook in the repository directory
+func (u *Updater) runHook(repo scanner.Repository, hook *compiledHook, changes *PullChanges) HookResult {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = u.config.Timeout
+	}
+	ctx, cancel := context.WithTimeout(u.ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	cmd.Dir = repo.Path
+	cmd.Env = append(os.Environ(),
+		"REPOSENSE_REPO="+repo.Path,
+		"REPOSENSE_OLD_HEAD="+changes.OldHead,
+		"REPOSENSE_NEW_HEAD="+changes.NewHead,
+	)
+	cmd.WaitDelay = time.Second
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	start := time.Now()
+	err := cmd.Run()
+	result := HookResult{
+		Name:     hook.Name,
+		Command:  hook.Command,
+		Success:  err == nil,
+		Output:   tailOutput(output.String(), maxHookOutput),
+		Duration: time.Since(start),
+	}
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Error = fmt.Sprintf("钩子执行超时 (%v)", timeout)
+	default:
+		result.Error = err.Error()
+	}
+	u.logger.Debugf("钩子 %s 在 %s 中执行完成: %v", hook.Name, repo.Path, err)
+	return result
+}
+
+// tailOutput keeps the last limit bytes of long command output, where errors usually are
+func tailOutput(output string, limit int) string {
+	output = strings.TrimSpace(output)
+	if len(output) <= limit {
+		return output
+	}
+	return "..." + output[len(output)-limit:]
+}
+
+// ValidateHooks reports the first invalid hook, so that configuration errors
+// surface before any repository is touched
+func ValidateHooks(hooks []PostUpdateHook) error {
+	_, err := compileHooks(hooks)
+	return err
+}
@@ -0,0 +1,66 @@
+package updater
+
+import (
+	"testing"
+)
+
+func TestHookPathPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		miss    []string
+	}{
+		// 不含 "/" 的模式匹配任意目录
+		{"go.mod", []string{"go.mod", "tools/go.mod", "a/b/go.mod"}, []string{"go.sum"}},
+		// 以 "/" 开头的模式只匹配仓库根目录
+		{"/go.mod", []string{"go.mod"}, []string{"tools/go.mod", "a/b/go.mod"}},
+		// 中间含 "/" 的模式同样相对于根目录
+		{"web/package.json", []string{"web/package.json"}, []string{"apps/web/package.json", "package.json"}},
+		{"**/package.json", []string{"package.json", "apps/web/package.json"}, []string{"package-lock.json"}},
+		{"migrations/**", []string{"migrations/001.sql", "migrations/a/b.sql"}, []string{"db/migrations/001.sql"}},
+		{"*.proto", []string{"api.proto", "api/v1/api.proto"}, []string{"api.proto.bak"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			hooks, err := compileHooks([]PostUpdateHook{{Command: "true", Paths: []string{tt.pattern}}})
+			if err != nil {
+				t.Fatalf("compileHooks() error: %v", err)
+			}
+			for _, path := range tt.match {
+				if hooks[0].trigger([]string{path}) != path {
+					t.Errorf("%q should match %q", tt.pattern, path)
+				}
+			}
+			for _, path := range tt.miss {
+				if got := hooks[0].trigger([]string{path}); got != "" {
+					t.Errorf("%q should not match %q", tt.pattern, path)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileHooksErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hook PostUpdateHook
+	}{
+		{"no command", PostUpdateHook{Name: "x", Paths: []string{"go.mod"}}},
+		{"blank command", PostUpdateHook{Name: "x", Command: "  ", Paths: []string{"go.mod"}}},
+		{"no paths", PostUpdateHook{Name: "x", Command: "true"}},
+	}
+	for _, tt := range tests {
+		if err := ValidateHooks([]PostUpdateHook{tt.hook}); err == nil {
+			t.Errorf("%s: ValidateHooks() succeeded, want error", tt.name)
+		}
+	}
+
+	hooks, err := compileHooks([]PostUpdateHook{{Command: "true", Paths: []string{"a"}}})
+	if err != nil {
+		t.Fatalf("compileHooks() error: %v", err)
+	}
+	if hooks[0].Name != "hook-1" {
+		t.Errorf("unnamed hook name = %q, want hook-1", hooks[0].Name)
+	}
+}
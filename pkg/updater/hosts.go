@@ -0,0 +1,167 @@
+package updater
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"reposense/pkg/filter"
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+// DefaultHostKey selects the limit for hosts without their own entry
+const DefaultHostKey = "*"
+
+// HostLimit throttles network operations against one Git host
+type HostLimit struct {
+	MaxConcurrent int           `json:"max_concurrent"` // 同时进行的操作数，0 表示不限制
+	MinInterval   time.Duration `json:"min_interval"`   // 相邻两次操作开始的最小间隔
+}
+
+// hostJob is a repository waiting for its host to have capacity
+type hostJob struct {
+	repo scanner.Repository
+	host string
+}
+
+// hostScheduler hands out jobs to workers in order, skipping jobs whose
+// host is at its concurrency cap or was contacted too recently
+type hostScheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	ctx     context.Context
+	stop    func() bool // 取消对 ctx 的监听
+	pending []hostJob
+	limits  map[string]HostLimit
+	active  map[string]int
+	last    map[string]time.Time
+}
+
+// newHostScheduler creates a scheduler for jobs; it stops handing out jobs once ctx is done
+func newHostScheduler(ctx context.Context, jobs []hostJob, limits map[string]HostLimit) *hostScheduler {
+	s := &hostScheduler{
+		ctx:     ctx,
+		pending: jobs,
+		limits:  limits,
+		active:  make(map[string]int),
+		last:    make(map[string]time.Time),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	s.stop = context.AfterFunc(ctx, s.wake)
+	return s
+}
+
+// limit returns the limit that applies to host
+func (s *hostScheduler) limit(host string) HostLimit {
+	if host == "" {
+		// 本地路径等没有主机的远程不限制
+		return HostLimit{}
+	}
+	if limit, ok := s.limits[host]; ok {
+		return limit
+	}
+	return s.limits[DefaultHostKey]
+}
+
+// next blocks until a job can start and returns it, or returns false when
+// no jobs are left or the scheduler was canceled
+func (s *hostScheduler) next() (hostJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if s.ctx.Err() != nil || len(s.pending) == 0 {
+			return hostJob{}, false
+		}
+
+		now := time.Now()
+		wait := time.Duration(-1)
+		for i, job := range s.pending {
+			limit := s.limit(job.host)
+			if limit.MaxConcurrent > 0 && s.active[job.host] >= limit.MaxConcurrent {
+				continue
+			}
+			if delay := s.last[job.host].Add(limit.MinInterval).Sub(now); delay > 0 {
+				if wait < 0 || delay < wait {
+					wait = delay
+				}
+				continue
+			}
+
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			s.active[job.host]++
+			s.last[job.host] = now
+			return job, true
+		}
+
+		// 没有可开始的任务: 等待其他任务完成，或等到最近的间隔结束
+		if wait > 0 {
+			timer := time.AfterFunc(wait, s.wake)
+			s.cond.Wait()
+			timer.Stop()
+		} else {
+			s.cond.Wait()
+		}
+	}
+}
+
+// done releases the slot held by a finished job
+func (s *hostScheduler) done(job hostJob) {
+	s.mu.Lock()
+	s.active[job.host]--
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// wake lets waiting workers re-check the pending jobs
+func (s *hostScheduler) wake() {
+	s.mu.Lock()
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// hostJobs pairs repositories with the host of their remote. Hosts are only
+// resolved when host limits are configured, since resolving runs git per repository.
+func (u *Updater) hostJobs(repositories []scanner.Repository) []hostJob {
+	jobs := make([]hostJob, len(repositories))
+	for i, repo := range repositories {
+		jobs[i].repo = repo
+	}
+	if len(u.config.HostLimits) == 0 || u.config.DryRun {
+		return jobs
+	}
+
+	sem := make(chan struct{}, max(u.config.WorkerCount, 1))
+	var wg sync.WaitGroup
+	for i := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(job *hostJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			job.host = u.remoteHost(job.repo)
+		}(&jobs[i])
+	}
+	wg.Wait()
+	return jobs
+}
+
+// remoteHost returns the host of the origin remote, or of the first remote
+// when there is no origin, or "" for repositories without a network remote
+func (u *Updater) remoteHost(repo scanner.Repository) string {
+	ctx, cancel := context.WithTimeout(u.ctx, u.config.Timeout)
+	defer cancel()
+
+	url, err := git.RemoteURL(ctx, u.runner, repo.Path, "origin")
+	if err != nil {
+		remotes, err := git.Remotes(ctx, u.runner, repo.Path)
+		if err != nil || len(remotes) == 0 {
+			return ""
+		}
+		if url, err = git.RemoteURL(ctx, u.runner, repo.Path, remotes[0]); err != nil {
+			return ""
+		}
+	}
+	return filter.RemoteHost(url)
+}
@@ -0,0 +1,278 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"reposense/pkg/cache"
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+// Journal records the state of repositories around a pull so that the run can be undone
+type Journal interface {
+	RecordBefore(repoPath, name, branch, head string) error
+	RecordResult(repoPath, head, operation, status, message string) error
+	RecordRef(repoPath, ref, old, new string) error
+}
+
+// SetJournal makes UpdateRepositories record each repository's branch and HEAD before pulling
+func (u *Updater) SetJournal(journal Journal) {
+	u.journal = journal
+}
+
+// journalBefore records the state of a repository that is about to be pulled.
+// It returns false when nothing was recorded, e.g. for an unborn branch.
+func (u *Updater) journalBefore(repo scanner.Repository) bool {
+	// 裸仓库只获取，父仓库中的子模块由父仓库更新，都不会移动 HEAD
+	if u.journal == nil || repo.Kind == scanner.KindBare || (repo.Kind == scanner.KindSubmodule && repo.Parent != "") {
+		return false
+	}
+
+	head, _, err := repo.State()
+	if err != nil || head.Commit == "" {
+		return false
+	}
+	if err := u.journal.RecordBefore(repo.Path, repo.Name, head.Branch, head.Commit); err != nil {
+		u.logger.Warnf("记录更新日志失败 %s: %v", repo.Path, err)
+		return false
+	}
+	return true
+}
+
+// journalResult records the HEAD and outcome of a repository after the pull.
+// Repositories with an unfinished operation are skipped before pulling, so an
+// operation found here was left behind by this pull.
+func (u *Updater) journalResult(repo scanner.Repository, result UpdateResult) {
+	head, operation, err := repo.State()
+	if err != nil {
+		u.logger.Warnf("读取更新后的HEAD失败 %s: %v", repo.Path, err)
+		return
+	}
+	if err := u.journal.RecordResult(repo.Path, head.Commit, string(operation), string(result.Status), result.Message); err != nil {
+		u.logger.Warnf("记录更新日志失败 %s: %v", repo.Path, err)
+	}
+}
+
+// UndoResult is the outcome of restoring one repository of an update run
+type UndoResult struct {
+	Entry    cache.JournalEntry `json:"entry"`
+	Status   ResultStatus       `json:"status"`
+	Message  string             `json:"message"`
+	Restored bool               `json:"restored"` // 仓库已处于更新前的状态
+}
+
+// Undo restores the repositories of an update run to the HEAD recorded before
+// the pull and moves the branches fast-forwarded by --all-branches back. A
+// rebase or merge the run left unfinished is aborted; repositories whose
+// branch, HEAD or working tree changed since the run are refused rather than
+// reset.
+func (u *Updater) Undo(run *cache.UpdateRun) []UndoResult {
+	results := make([]UndoResult, 0, len(run.Entries))
+	for _, entry := range run.Entries {
+		if u.ctx.Err() != nil {
+			break
+		}
+		results = append(results, u.undoEntry(entry))
+	}
+	return results
+}
+
+// undoEntry restores a single repository: the checked out branch first, then
+// the other branches the run fast-forwarded
+func (u *Updater) undoEntry(entry cache.JournalEntry) UndoResult {
+	result := UndoResult{Entry: entry, Status: StatusSkipped}
+	switch {
+	case entry.Undone():
+		result.Message = "已撤销过"
+		return result
+	case !entry.Changed():
+		result.Message = "本次更新没有改变该仓库"
+		return result
+	}
+
+	// 清单中的超时按仓库路径查找
+	ctx, cancel := context.WithTimeout(u.ctx, u.repoTimeout(scanner.Repository{Path: entry.Path}))
+	defer cancel()
+
+	if entry.HeadChanged() {
+		result = u.undoHead(ctx, entry)
+		// 当前分支拒绝撤销时保留其他分支，修复后重新执行即可一并恢复
+		if result.Status == StatusFailed {
+			return result
+		}
+	} else {
+		result.Restored = true
+	}
+	if len(entry.Refs) > 0 {
+		u.undoRefs(ctx, entry, &result)
+	}
+	return result
+}
+
+// undoHead restores the checked out branch with git reset --keep
+func (u *Updater) undoHead(ctx context.Context, entry cache.JournalEntry) UndoResult {
+	result := UndoResult{Entry: entry, Status: StatusSkipped}
+	fail := func(message string) UndoResult {
+		result.Status = StatusFailed
+		result.Message = message
+		return result
+	}
+
+	gitDir, err := git.Output(ctx, u.runner, entry.Path, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return fail("无法定位git目录: " + err.Error())
+	}
+	operation := git.InProgressOperation(gitDir)
+	if entry.Operation != "" && operation == git.Operation(entry.Operation) {
+		return u.abortOperation(ctx, entry, gitDir, operation)
+	}
+	if operation != git.OpNone {
+		return fail(fmt.Sprintf("仓库中有未完成的%s，拒绝撤销", operation.Description()))
+	}
+
+	head, err := git.HeadCommit(ctx, u.runner, entry.Path)
+	if err != nil {
+		return fail("无法读取当前HEAD: " + err.Error())
+	}
+	if head == entry.HeadBefore {
+		result.Restored = true
+		result.Message = "已处于更新前的状态"
+		return result
+	}
+	if head != entry.HeadAfter {
+		return fail(fmt.Sprintf("更新后HEAD已变化 (%s -> %s)，拒绝撤销", git.ShortHash(entry.HeadAfter), git.ShortHash(head)))
+	}
+
+	branch, err := git.CurrentBranch(ctx, u.runner, entry.Path)
+	if err != nil {
+		return fail("无法读取当前分支: " + err.Error())
+	}
+	if branch != entry.Branch {
+		return fail(fmt.Sprintf("当前分支已从 %s 切换到 %s，拒绝撤销", entry.Branch, branch))
+	}
+
+	wt, err := git.ReadWorkingTreeStatus(ctx, u.runner, entry.Path)
+	if err != nil {
+		return fail("无法读取工作区状态: " + err.Error())
+	}
+	if wt.Staged.Total() > 0 || wt.Unstaged.Total() > 0 || len(wt.Conflicted) > 0 {
+		return fail("工作区有未提交的变更，拒绝撤销")
+	}
+
+	if u.config.DryRun {
+		result.Status = StatusSuccess
+		result.Message = fmt.Sprintf("DRY RUN: 将重置到 %s", git.ShortHash(entry.HeadBefore))
+		return result
+	}
+
+	// --keep 在会覆盖本地文件时拒绝执行，不会丢失未跟踪文件
+	res, err := u.runner.Run(ctx, entry.Path, "reset", "--keep", entry.HeadBefore)
+	if err != nil {
+		return fail("重置失败: " + res.Output())
+	}
+
+	result.Status = StatusSuccess
+	result.Restored = true
+	result.Message = fmt.Sprintf("已恢复到 %s (%s -> %s)", entry.Branch, git.ShortHash(entry.HeadAfter), git.ShortHash(entry.HeadBefore))
+	return result
+}
+
+// undoRefs moves the branches fast-forwarded by the run back to their old
+// commits. A branch that already points at its old commit counts as restored,
+// one that moved again since the run is left alone and reported.
+func (u *Updater) undoRefs(ctx context.Context, entry cache.JournalEntry, result *UndoResult) {
+	var reverted, moved []string
+	for _, ref := range entry.Refs {
+		name := strings.TrimPrefix(ref.Ref, "refs/heads/")
+		current, _ := git.Output(ctx, u.runner, entry.Path, "rev-parse", "--verify", "--quiet", ref.Ref)
+		switch {
+		case current == ref.Old:
+			continue
+		case current != ref.New:
+			moved = append(moved, name)
+			continue
+		case u.config.DryRun:
+			reverted = append(reverted, name)
+			continue
+		}
+
+		// 带上快进后的值，分支在此期间被修改时 update-ref 会拒绝
+		if _, err := u.runner.Run(ctx, entry.Path, "update-ref", "-m", "reposense: undo fast-forward",
+			ref.Ref, ref.Old, ref.New); err != nil {
+			moved = append(moved, name)
+			continue
+		}
+		reverted = append(reverted, name)
+	}
+
+	var notes []string
+	if len(reverted) > 0 {
+		verb := "已回退"
+		if u.config.DryRun {
+			verb = "将回退"
+		}
+		notes = append(notes, fmt.Sprintf("%s%d个其他分支 (%s)", verb, len(reverted), strings.Join(reverted, ", ")))
+	}
+	if len(moved) > 0 {
+		notes = append(notes, fmt.Sprintf("%d个分支在更新后已变化，拒绝回退 (%s)", len(moved), strings.Join(moved, ", ")))
+		result.Status = StatusFailed
+		result.Restored = false
+	} else if len(reverted) > 0 && result.Status == StatusSkipped {
+		result.Status = StatusSuccess
+	}
+	if len(notes) == 0 {
+		notes = append(notes, "其他分支已处于更新前的状态")
+	}
+
+	if result.Message == "" {
+		result.Message = strings.Join(notes, "，")
+	} else {
+		result.Message += "，" + strings.Join(notes, "，")
+	}
+}
+
+// abortOperation aborts the rebase or merge an update run left unfinished,
+// which returns the branch to the HEAD recorded before the pull
+func (u *Updater) abortOperation(ctx context.Context, entry cache.JournalEntry, gitDir string, operation git.Operation) UndoResult {
+	result := UndoResult{Entry: entry, Status: StatusFailed}
+
+	// 只中止本次更新开始的操作：分支和起点必须与拉取前一致
+	var branch, origin string
+	switch operation {
+	case git.OpRebase:
+		branch, origin = git.RebaseOrigin(gitDir)
+	case git.OpMerge:
+		branch, _ = git.CurrentBranch(ctx, u.runner, entry.Path)
+		origin, _ = git.HeadCommit(ctx, u.runner, entry.Path)
+	default:
+		result.Message = fmt.Sprintf("无法自动中止%s，拒绝撤销", operation.Description())
+		return result
+	}
+	if branch != entry.Branch || origin != entry.HeadBefore {
+		result.Message = fmt.Sprintf("仓库中的%s不是本次更新开始的，拒绝撤销", operation.Description())
+		return result
+	}
+
+	if u.config.DryRun {
+		result.Status = StatusSuccess
+		result.Message = fmt.Sprintf("DRY RUN: 将中止未完成的%s，恢复到 %s", operation.Description(), git.ShortHash(entry.HeadBefore))
+		return result
+	}
+
+	res, err := u.runner.Run(ctx, entry.Path, string(operation), "--abort")
+	if err != nil {
+		result.Message = fmt.Sprintf("中止%s失败: %s", operation.Description(), res.Output())
+		return result
+	}
+	if head, err := git.HeadCommit(ctx, u.runner, entry.Path); err != nil || head != entry.HeadBefore {
+		result.Message = fmt.Sprintf("已中止%s，但 HEAD 未回到 %s", operation.Description(), git.ShortHash(entry.HeadBefore))
+		return result
+	}
+
+	result.Status = StatusSuccess
+	result.Restored = true
+	result.Message = fmt.Sprintf("已中止未完成的%s，恢复到 %s (%s)", operation.Description(), entry.Branch, git.ShortHash(entry.HeadBefore))
+	return result
+}
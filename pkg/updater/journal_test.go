@@ -0,0 +1,150 @@
+package updater
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"reposense/pkg/cache"
+	"reposense/pkg/scanner"
+)
+
+// memJournal records journal entries in memory
+type memJournal struct {
+	mu      sync.Mutex
+	entries map[string]*cache.JournalEntry
+}
+
+func (j *memJournal) RecordBefore(repoPath, name, branch, head string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.entries == nil {
+		j.entries = make(map[string]*cache.JournalEntry)
+	}
+	j.entries[repoPath] = &cache.JournalEntry{Path: repoPath, Name: name, Branch: branch, HeadBefore: head}
+	return nil
+}
+
+func (j *memJournal) RecordResult(repoPath, head, operation, status, message string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry := j.entries[repoPath]
+	entry.HeadAfter, entry.Operation, entry.Status, entry.Message = head, operation, status, message
+	return nil
+}
+
+func (j *memJournal) RecordRef(repoPath, ref, old, new string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry := j.entries[repoPath]
+	entry.Refs = append(entry.Refs, cache.JournalRef{Ref: ref, Old: old, New: new})
+	return nil
+}
+
+// run returns the recorded entries as an update run
+func (j *memJournal) run() *cache.UpdateRun {
+	run := &cache.UpdateRun{}
+	for _, entry := range j.entries {
+		run.Entries = append(run.Entries, *entry)
+	}
+	return run
+}
+
+// pullAndRecord updates f with a journal and returns the recorded run
+func pullAndRecord(t *testing.T, f *fakeRepo) *cache.UpdateRun {
+	t.Helper()
+	journal := &memJournal{}
+	u := f.updater(UpdaterConfig{})
+	u.SetJournal(journal)
+	results, _ := u.UpdateRepositories([]scanner.Repository{f.repo}, nil)
+	if results[0].Status != StatusSuccess {
+		t.Fatalf("Status = %q (%s), want %q", results[0].Status, results[0].Message, StatusSuccess)
+	}
+
+	run := journal.run()
+	if len(run.Entries) != 1 {
+		t.Fatalf("journal has %d entries, want 1", len(run.Entries))
+	}
+	entry := run.Entries[0]
+	if entry.Branch != "main" || entry.HeadBefore != oldHead || entry.HeadAfter != newHead || entry.Status != string(StatusSuccess) {
+		t.Fatalf("entry = %+v, want main %s -> %s", entry, oldHead, newHead)
+	}
+	return run
+}
+
+func TestUndoRestoresHead(t *testing.T) {
+	f := newFakeRepo(t, "r")
+	run := pullAndRecord(t, f)
+
+	results := f.updater(UpdaterConfig{}).Undo(run)
+	if len(results) != 1 || results[0].Status != StatusSuccess || !results[0].Restored {
+		t.Fatalf("Undo() = %+v, want a restored success", results)
+	}
+	if f.count("reset --keep "+oldHead) != 1 {
+		t.Errorf("calls = %q, want reset --keep %s", f.calls(), oldHead)
+	}
+	if f.head() != oldHead {
+		t.Errorf("HEAD = %s, want %s", f.head(), oldHead)
+	}
+
+	// 再次撤销时已处于更新前的状态
+	results = f.updater(UpdaterConfig{}).Undo(run)
+	if results[0].Status != StatusSkipped || !results[0].Restored {
+		t.Errorf("second Undo() = %+v, want a restored skip", results[0])
+	}
+}
+
+func TestUndoRefuses(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(f *fakeRepo)
+		message string
+	}{
+		{"head moved", func(f *fakeRepo) { f.setHead(strings.Repeat("c", 40)) }, "HEAD已变化"},
+		{"dirty", func(f *fakeRepo) { f.dirty = true }, "未提交的变更"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeRepo(t, "r")
+			run := pullAndRecord(t, f)
+			tt.change(f)
+
+			results := f.updater(UpdaterConfig{}).Undo(run)
+			if results[0].Status != StatusFailed || !strings.Contains(results[0].Message, tt.message) {
+				t.Errorf("Undo() = %q (%s), want a failure mentioning %q", results[0].Status, results[0].Message, tt.message)
+			}
+			if f.count("reset") != 0 {
+				t.Errorf("calls = %q, want no reset", f.calls())
+			}
+		})
+	}
+}
+
+func TestUndoRefs(t *testing.T) {
+	f := newFakeRepo(t, "r")
+	dir := f.repo.Path
+	// dev 仍指向快进后的提交，release 在更新后又被移动
+	f.runner.SetOutput(dir, newHead, "rev-parse", "--verify", "--quiet", "refs/heads/dev")
+	f.runner.SetOutput(dir, strings.Repeat("c", 40), "rev-parse", "--verify", "--quiet", "refs/heads/release")
+	f.runner.SetOutput(dir, "", "update-ref", "-m", "reposense: undo fast-forward", "refs/heads/dev", oldHead, newHead)
+
+	run := &cache.UpdateRun{Entries: []cache.JournalEntry{{
+		Path: dir, Name: "r", Branch: "main", HeadBefore: oldHead, HeadAfter: oldHead,
+		Refs: []cache.JournalRef{
+			{Ref: "refs/heads/dev", Old: oldHead, New: newHead},
+			{Ref: "refs/heads/release", Old: oldHead, New: newHead},
+		},
+	}}}
+
+	result := f.updater(UpdaterConfig{}).Undo(run)[0]
+	if f.count("update-ref") != 1 {
+		t.Errorf("calls = %q, want only dev moved back", f.calls())
+	}
+	if result.Status != StatusFailed || result.Restored {
+		t.Errorf("Undo() = %q, restored %v, want a failure for the moved branch", result.Status, result.Restored)
+	}
+	if !strings.Contains(result.Message, "已回退1个其他分支 (dev)") || !strings.Contains(result.Message, "(release)") {
+		t.Errorf("Message = %q, want dev reverted and release refused", result.Message)
+	}
+}
@@ -0,0 +1,50 @@
+package updater
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// maxRetryBackoff caps the delay between two attempts
+const maxRetryBackoff = 30 * time.Second
+
+// withRetry runs attempt with a fresh timeout until it reports that a retry
+// would not help or the configured retries are used up, and returns the number of attempts
+func (u *Updater) withRetry(name string, timeout time.Duration, attempt func(ctx context.Context) (retryable bool)) int {
+	for n := 1; ; n++ {
+		ctx, cancel := context.WithTimeout(u.ctx, timeout)
+		retryable := attempt(ctx)
+		cancel()
+
+		if !retryable || n > u.config.Retries || u.ctx.Err() != nil {
+			return n
+		}
+
+		delay := u.retryDelay(n)
+		u.logger.Debugf("仓库 %s 第 %d 次尝试失败，%v 后重试", name, n, delay)
+		select {
+		case <-time.After(delay):
+		case <-u.ctx.Done():
+			return n
+		}
+	}
+}
+
+// retryDelay returns the exponential backoff with jitter before retry n
+func (u *Updater) retryDelay(n int) time.Duration {
+	base := u.config.RetryBackoff
+	if base <= 0 {
+		return 0
+	}
+
+	delay := base
+	for i := 1; i < n && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryBackoff)
+
+	// 一半固定、一半随机，避免同一主机上的仓库同时重试
+	half := delay / 2
+	return half + rand.N(half+1)
+}
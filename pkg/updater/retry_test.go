@@ -0,0 +1,97 @@
+package updater
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+func TestUpdateRetries(t *testing.T) {
+	const (
+		network = "fatal: unable to access 'https://example.com/r.git/': Could not resolve host: example.com"
+		timeout = "ssh: connect to host example.com port 22: Connection timed out"
+		auth    = "remote: Permission denied\nfatal: Authentication failed for 'https://example.com/r.git/'"
+		nonFF   = "fatal: Not possible to fast-forward, aborting."
+	)
+
+	tests := []struct {
+		name     string
+		stderr   string
+		class    git.ErrorClass
+		failures int // 成功之前失败的次数，-1 表示一直失败
+		retries  int
+		attempts int
+		status   ResultStatus
+	}{
+		{"network then success", network, git.ErrorNetwork, 2, 2, 3, StatusSuccess},
+		{"network exhausts retries", network, git.ErrorNetwork, -1, 2, 3, StatusFailed},
+		{"timeout exhausts retries", timeout, git.ErrorTimeout, -1, 1, 2, StatusFailed},
+		{"network without retries", network, git.ErrorNetwork, -1, 0, 1, StatusFailed},
+		{"auth is not retried", auth, git.ErrorAuth, -1, 3, 1, StatusFailed},
+		{"non-fast-forward is not retried", nonFF, git.ErrorNonFastForward, -1, 3, 1, StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 输出经过与 failGit 相同的分类
+			failure := git.FakeResponse{Stderr: tt.stderr, ExitCode: 128}
+			if class := git.ClassifyError(errors.New("exit status 128"), tt.stderr); class != tt.class {
+				t.Fatalf("ClassifyError() = %q, want %q", class, tt.class)
+			}
+
+			f := newFakeRepo(t, "r")
+			f.pulls = []git.FakeResponse{failure}
+			if tt.failures >= 0 {
+				f.pulls = make([]git.FakeResponse, tt.failures, tt.failures+1)
+				for i := range f.pulls {
+					f.pulls[i] = failure
+				}
+				f.pulls = append(f.pulls, git.FakeResponse{Stdout: "Fast-forward\n"})
+			}
+			u := f.updater(UpdaterConfig{Retries: tt.retries, RetryBackoff: 0, Timeout: time.Second})
+
+			results, _ := u.UpdateRepositories([]scanner.Repository{f.repo}, nil)
+			result := results[0]
+			if result.Attempts != tt.attempts {
+				t.Errorf("Attempts = %d, want %d", result.Attempts, tt.attempts)
+			}
+			if got := f.count("pull"); got != tt.attempts {
+				t.Errorf("pulls = %d, want %d", got, tt.attempts)
+			}
+			if result.Status != tt.status {
+				t.Errorf("Status = %q (%s), want %q", result.Status, result.Message, tt.status)
+			}
+			if tt.status == StatusFailed && result.ErrorCode != tt.class {
+				t.Errorf("ErrorCode = %q, want %q", result.ErrorCode, tt.class)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	u := NewUpdaterWithRunner(UpdaterConfig{RetryBackoff: time.Second}, git.NewFakeRunner())
+	tests := []struct {
+		n        int
+		min, max time.Duration
+	}{
+		{1, 500 * time.Millisecond, time.Second},
+		{2, time.Second, 2 * time.Second},
+		{3, 2 * time.Second, 4 * time.Second},
+		{10, maxRetryBackoff / 2, maxRetryBackoff},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if delay := u.retryDelay(tt.n); delay < tt.min || delay > tt.max {
+				t.Errorf("retryDelay(%d) = %v, want between %v and %v", tt.n, delay, tt.min, tt.max)
+			}
+		}
+	}
+
+	u.config.RetryBackoff = 0
+	if delay := u.retryDelay(3); delay != 0 {
+		t.Errorf("retryDelay() without backoff = %v, want 0", delay)
+	}
+}
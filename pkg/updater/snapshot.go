@@ -0,0 +1,305 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"reposense/pkg/cache"
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+)
+
+// RestoreSummary describes how a repository compared with a snapshot and what restoring it did
+type RestoreSummary struct {
+	Branch         string   `json:"branch,omitempty"` // 快照中的分支
+	Commit         string   `json:"commit,omitempty"` // 快照中的提交
+	PreviousBranch string   `json:"previous_branch,omitempty"`
+	PreviousCommit string   `json:"previous_commit,omitempty"`
+	Detached       bool     `json:"detached,omitempty"` // 以分离 HEAD 检出了快照中的提交
+	Drift          []string `json:"drift,omitempty"`    // 当前状态与快照的差异
+}
+
+// snapshotRelPath returns the path of a repository relative to the snapshot root,
+// so that a snapshot can be restored in a workspace checked out elsewhere
+func snapshotRelPath(root, repoPath string) string {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		absRoot = root
+	}
+	absRepo, err := filepath.Abs(repoPath)
+	if err != nil {
+		absRepo = repoPath
+	}
+	rel, err := filepath.Rel(absRoot, absRepo)
+	if err != nil {
+		return filepath.ToSlash(repoPath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// snapshotCandidate reports whether a repository has its own checkout to record.
+// Bare repositories have no HEAD to restore and submodules follow their parent.
+func snapshotCandidate(repo scanner.Repository) bool {
+	return repo.Kind != scanner.KindBare && repo.Kind != scanner.KindSubmodule
+}
+
+// CaptureSnapshot records the branch and HEAD of every repository under root.
+// The results describe each repository, including those that could not be recorded.
+func (u *Updater) CaptureSnapshot(root string, repositories []scanner.Repository) ([]cache.SnapshotRepo, []UpdateResult) {
+	var mu sync.Mutex
+	var entries []cache.SnapshotRepo
+
+	results := u.runBatch(repositories, func(repo scanner.Repository) (result UpdateResult) {
+		result = UpdateResult{Repository: repo, StartTime: time.Now()}
+		defer func() {
+			result.finishStatus()
+			result.EndTime = time.Now()
+			result.Duration = result.EndTime.Sub(result.StartTime)
+		}()
+
+		if !snapshotCandidate(repo) {
+			result.skip("已跳过: 裸仓库和子模块不记录在快照中")
+			return result
+		}
+
+		ctx, cancel := context.WithTimeout(u.ctx, u.repoTimeout(repo))
+		defer cancel()
+
+		head, err := git.HeadCommit(ctx, u.runner, repo.Path)
+		if err != nil {
+			result.Error = err.Error()
+			result.Message = "无法读取 HEAD (仓库可能还没有提交)"
+			return result
+		}
+		entry := cache.SnapshotRepo{
+			Path:   snapshotRelPath(root, repo.Path),
+			Name:   repo.Name,
+			Commit: head,
+		}
+		entry.Branch, _ = git.CurrentBranch(ctx, u.runner, repo.Path)
+		entry.RemoteURL, _ = git.RemoteURL(ctx, u.runner, repo.Path, "origin")
+		if wt, err := git.ReadWorkingTreeStatus(ctx, u.runner, repo.Path); err == nil {
+			entry.Dirty = wt.Staged.Total() > 0 || wt.Unstaged.Total() > 0 || len(wt.Conflicted) > 0
+		}
+
+		result.Success = true
+		result.Message = describeCheckout(entry.Branch, head)
+		if entry.Dirty {
+			result.Message += "，工作区有未提交的变更 (不会记录在快照中)"
+		}
+
+		mu.Lock()
+		entries = append(entries, entry)
+		mu.Unlock()
+		return result
+	}, nil)
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, results
+}
+
+// describeCheckout names a branch and commit, e.g. "main @ 1a2b3c4"
+func describeCheckout(branch, commit string) string {
+	if branch == "" {
+		return git.ShortHash(commit) + " (分离 HEAD)"
+	}
+	return branch + " @ " + git.ShortHash(commit)
+}
+
+// RestoreSnapshot checks out the recorded commit in every repository of the
+// snapshot found under root. Repositories with uncommitted changes or unfinished
+// operations are refused; recorded repositories that are missing and repositories
+// that are not in the snapshot are reported without being touched.
+func (u *Updater) RestoreSnapshot(root string, snapshot *cache.Snapshot, repositories []scanner.Repository) []UpdateResult {
+	entries := make(map[string]cache.SnapshotRepo, len(snapshot.Repos))
+	for _, entry := range snapshot.Repos {
+		entries[entry.Path] = entry
+	}
+
+	var matched []scanner.Repository
+	var results []UpdateResult
+	found := make(map[string]bool)
+	for _, repo := range repositories {
+		if !snapshotCandidate(repo) {
+			continue
+		}
+		rel := snapshotRelPath(root, repo.Path)
+		if _, ok := entries[rel]; ok {
+			matched = append(matched, repo)
+			found[rel] = true
+			continue
+		}
+		result := UpdateResult{Repository: repo, StartTime: time.Now(), Restore: &RestoreSummary{
+			Drift: []string{"快照中没有该仓库"},
+		}}
+		result.skip("已跳过: 快照中没有该仓库，未修改")
+		results = append(results, result)
+	}
+
+	for _, entry := range snapshot.Repos {
+		if found[entry.Path] {
+			continue
+		}
+		result := UpdateResult{
+			Repository: scanner.Repository{Path: filepath.Join(root, filepath.FromSlash(entry.Path)), Name: entry.Name},
+			StartTime:  time.Now(),
+			Message:    "仓库不存在",
+			Restore: &RestoreSummary{
+				Branch: entry.Branch,
+				Commit: entry.Commit,
+				Drift:  []string{"仓库不存在，需要先克隆 " + entry.RemoteURL},
+			},
+		}
+		results = append(results, result)
+	}
+
+	results = append(results, u.runBatch(matched, func(repo scanner.Repository) UpdateResult {
+		return u.restoreRepository(repo, entries[snapshotRelPath(root, repo.Path)])
+	}, nil)...)
+
+	for i := range results {
+		results[i].finishStatus()
+	}
+	return results
+}
+
+// restoreRepository checks out the snapshot entry in one repository
+func (u *Updater) restoreRepository(repo scanner.Repository, entry cache.SnapshotRepo) (result UpdateResult) {
+	summary := &RestoreSummary{Branch: entry.Branch, Commit: entry.Commit}
+	result = UpdateResult{Repository: repo, StartTime: time.Now(), Restore: summary}
+	defer func() {
+		result.finishStatus()
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+	}()
+
+	ctx, cancel := context.WithTimeout(u.ctx, u.repoTimeout(repo))
+	defer cancel()
+
+	// 记录与快照的差异
+	if entry.Dirty {
+		summary.Drift = append(summary.Drift, "保存快照时工作区有未提交的变更，这些变更无法恢复")
+	}
+	if url, _ := git.RemoteURL(ctx, u.runner, repo.Path, "origin"); url != entry.RemoteURL {
+		summary.Drift = append(summary.Drift, fmt.Sprintf("origin 地址不同: 快照为 %q，当前为 %q", entry.RemoteURL, url))
+	}
+	summary.PreviousBranch, _ = git.CurrentBranch(ctx, u.runner, repo.Path)
+	summary.PreviousCommit, _ = git.HeadCommit(ctx, u.runner, repo.Path)
+	if summary.PreviousBranch != entry.Branch {
+		summary.Drift = append(summary.Drift, fmt.Sprintf("分支不同: 快照为 %s，当前为 %s",
+			describeCheckout(entry.Branch, entry.Commit), describeCheckout(summary.PreviousBranch, summary.PreviousCommit)))
+	} else if summary.PreviousCommit != entry.Commit {
+		summary.Drift = append(summary.Drift, fmt.Sprintf("提交不同: 快照为 %s，当前为 %s",
+			git.ShortHash(entry.Commit), git.ShortHash(summary.PreviousCommit)))
+	}
+
+	wt, err := git.ReadWorkingTreeStatus(ctx, u.runner, repo.Path)
+	if err != nil {
+		result.Error = err.Error()
+		result.Message = "拒绝恢复: 无法读取工作区状态"
+		return result
+	}
+	dirty := wt.Staged.Total() > 0 || wt.Unstaged.Total() > 0 || len(wt.Conflicted) > 0
+	if dirty && !entry.Dirty {
+		summary.Drift = append(summary.Drift, "工作区有未提交的变更")
+	}
+
+	if summary.PreviousBranch == entry.Branch && summary.PreviousCommit == entry.Commit {
+		if dirty {
+			result.skip("已在快照的提交上，未修改工作区")
+		} else {
+			result.skip("已与快照一致")
+		}
+		return result
+	}
+
+	if _, operation, err := repo.State(); err == nil && operation != git.OpNone {
+		result.ErrorCode = git.ErrorConflict
+		result.Message = fmt.Sprintf("拒绝恢复: 仓库中有未完成的%s", operation.Description())
+		return result
+	}
+	if dirty {
+		result.ErrorCode = git.ErrorConflict
+		result.Message = "拒绝恢复: 工作区有未提交的变更"
+		return result
+	}
+
+	// 快照中的提交可能只存在于远程，先获取一次
+	if !u.hasCommit(ctx, repo.Path, entry.Commit) {
+		if u.config.DryRun {
+			result.Success = true
+			result.Message = fmt.Sprintf("DRY RUN: 将获取远程更新并检出 %s", describeCheckout(entry.Branch, entry.Commit))
+			return result
+		}
+		u.withRetry(repo.Name, u.repoTimeout(repo), func(ctx context.Context) bool {
+			res, err := u.runner.Run(ctx, repo.Path, "fetch", "--all", "--quiet")
+			return err != nil && git.ClassifyError(err, res.Output()).Transient()
+		})
+		if !u.hasCommit(ctx, repo.Path, entry.Commit) {
+			result.Message = fmt.Sprintf("无法恢复: 本地和远程都没有提交 %s", git.ShortHash(entry.Commit))
+			return result
+		}
+	}
+
+	// 分支仍指向快照中的提交时检出分支，否则以分离 HEAD 检出提交，不移动任何分支
+	args := []string{"switch", "--detach", entry.Commit}
+	if entry.Branch != "" {
+		tip, err := git.Output(ctx, u.runner, repo.Path, "rev-parse", "--verify", "--quiet", "refs/heads/"+entry.Branch)
+		switch {
+		case err != nil:
+			summary.Drift = append(summary.Drift, fmt.Sprintf("分支 %s 已不存在", entry.Branch))
+		case tip != entry.Commit:
+			// 当前就在该分支上时，上面已报告提交不同
+			if entry.Branch != summary.PreviousBranch {
+				summary.Drift = append(summary.Drift, fmt.Sprintf("分支 %s 已移动到 %s", entry.Branch, git.ShortHash(tip)))
+			}
+		default:
+			args = []string{"switch", entry.Branch}
+		}
+	}
+	summary.Detached = args[1] == "--detach"
+
+	if u.config.DryRun {
+		result.Success = true
+		if summary.Detached {
+			result.Message = fmt.Sprintf("DRY RUN: 将以分离 HEAD 检出 %s", git.ShortHash(entry.Commit))
+		} else {
+			result.Message = "DRY RUN: 将恢复到 " + describeCheckout(entry.Branch, entry.Commit)
+		}
+		return result
+	}
+
+	res, err := u.runner.Run(ctx, repo.Path, args...)
+	if err != nil {
+		u.failGit(&result, err, res.Output())
+		result.Message = "恢复失败: " + firstLine(res.Output())
+		return result
+	}
+	result.Success = true
+	if summary.Detached {
+		result.Message = fmt.Sprintf("已检出 %s (分离 HEAD)", git.ShortHash(entry.Commit))
+	} else {
+		result.Message = "已恢复到 " + describeCheckout(entry.Branch, entry.Commit)
+	}
+	return result
+}
+
+// hasCommit reports whether commit exists in the repository
+func (u *Updater) hasCommit(ctx context.Context, repoPath, commit string) bool {
+	return git.RefExists(ctx, u.runner, repoPath, commit+"^{commit}")
+}
+
+// firstLine returns the first non-empty line of output
+func firstLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
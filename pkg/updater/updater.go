@@ -3,12 +3,12 @@ package updater
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"reposense/pkg/git"
 	"reposense/pkg/scanner"
 
 	"github.com/sirupsen/logrus"
@@ -18,8 +18,18 @@ import (
 type UpdateResult struct {
 	Repository scanner.Repository `json:"repository"`
 	Success    bool               `json:"success"`
+	Status     ResultStatus       `json:"status"`
 	Message    string             `json:"message"`
 	Error      string             `json:"error,omitempty"`
+	ErrorCode  git.ErrorClass     `json:"error_code,omitempty"` // 失败原因分类
+	Attempts   int                `json:"attempts,omitempty"`
+	Changes    *PullChanges       `json:"changes,omitempty"` // 拉取带来的提交、文件和标签
+	Branches   []BranchUpdate     `json:"branches,omitempty"` // --all-branches 时其他本地分支的处理结果
+	Hooks      []HookResult       `json:"hooks,omitempty"`    // 被变更路径触发的更新后钩子
+	Fetch      *FetchSummary      `json:"fetch,omitempty"`   // 仅 fetch 命令填写
+	Exec       *ExecSummary       `json:"exec,omitempty"`    // 仅 exec 命令填写
+	BranchOp   *BranchOpSummary   `json:"branch_op,omitempty"` // 仅 branch 命令填写
+	Restore    *RestoreSummary    `json:"restore,omitempty"`   // 仅 snapshot restore 填写
 	Duration   time.Duration      `json:"duration"`
 	StartTime  time.Time          `json:"start_time"`
 	EndTime    time.Time          `json:"end_time"`
@@ -32,18 +42,40 @@ type UpdaterConfig struct {
 	DryRun            bool          `json:"dry_run"`
 	GitPullStrategy   string        `json:"git_pull_strategy"`   // "ff-only", "merge", "rebase"
 	GitNonInteractive bool          `json:"git_non_interactive"` // 禁用交互提示
+	DirtyPolicy       DirtyPolicy   `json:"dirty_policy"`        // 工作区有未提交变更时的处理方式
+	Retries           int           `json:"retries"`             // 网络错误或超时后的重试次数
+	RetryBackoff      time.Duration `json:"retry_backoff"`       // 首次重试前的等待时间，之后逐次翻倍
+	HostLimits        map[string]HostLimit `json:"host_limits"` // 按远程主机限制并发和请求间隔，"*" 适用于其他主机
+	AllBranches       bool          `json:"all_branches"`        // 同时快进未检出的本地分支
+	Hooks             []PostUpdateHook `json:"hooks"`            // 拉取改动了指定路径时在仓库中执行的命令
 }
 
 // Updater handles batch Git operations
 type Updater struct {
 	config UpdaterConfig
 	logger *logrus.Logger
+	runner git.Runner
 	ctx    context.Context
 	cancel context.CancelFunc
+	
+	batch      map[string]bool // 当前批次中的仓库路径
+	storeLocks sync.Map        // 按对象库加锁，避免共享对象库的工作树并发执行git
+	
+	repoOptions map[string]scanner.ManifestOptions // 清单中按仓库路径设置的选项
+	journal     Journal                            // 记录拉取前后的 HEAD，可为空
+	hooks       []*compiledHook                    // 每次批量更新开始时编译
 }
 
 // NewUpdater creates a new Updater instance
 func NewUpdater(config UpdaterConfig) *Updater {
+	runner := git.NewExecRunner()
+	// 如果启用非交互模式，设置环境变量防止交互提示
+	runner.NonInteractive = config.GitNonInteractive
+	return NewUpdaterWithRunner(config, runner)
+}
+
+// NewUpdaterWithRunner creates a new Updater instance that executes git through runner
+func NewUpdaterWithRunner(config UpdaterConfig, runner git.Runner) *Updater {
 	logger := logrus.New()
 	logger.SetLevel(logrus.InfoLevel)
 	
@@ -52,6 +84,7 @@ func NewUpdater(config UpdaterConfig) *Updater {
 	return &Updater{
 		config: config,
 		logger: logger,
+		runner: runner,
 		ctx:    ctx,
 		cancel: cancel,
 	}
@@ -62,37 +95,56 @@ func (u *Updater) SetLogLevel(level logrus.Level) {
 	u.logger.SetLevel(level)
 }
 
+// SetRepositoryOptions sets per-repository overrides keyed by repository path
+func (u *Updater) SetRepositoryOptions(options map[string]scanner.ManifestOptions) {
+	u.repoOptions = options
+}
+
+// repoTimeout returns the timeout for an operation in repo; the manifest's timeout takes precedence
+func (u *Updater) repoTimeout(repo scanner.Repository) time.Duration {
+	if options := u.repoOptions[repo.Path]; options.Timeout > 0 {
+		return options.Timeout
+	}
+	return u.config.Timeout
+}
+
 // UpdateRepositories performs batch git pull operations
 func (u *Updater) UpdateRepositories(repositories []scanner.Repository, progressCallback func(UpdateResult)) ([]UpdateResult, error) {
 	if len(repositories) == 0 {
 		return []UpdateResult{}, nil
 	}
 	
+	hooks, err := compileHooks(u.config.Hooks)
+	if err != nil {
+		return nil, err
+	}
+	u.hooks = hooks
+	
 	u.logger.Infof("开始更新 %d 个仓库，使用 %d 个工作协程", len(repositories), u.config.WorkerCount)
+	results := u.runBatch(repositories, u.updateRepository, progressCallback)
+	u.logger.Infof("更新完成，共处理 %d 个仓库", len(results))
+	return results, nil
+}
+
+// runBatch applies operation to repositories with the worker pool
+func (u *Updater) runBatch(repositories []scanner.Repository, operation func(scanner.Repository) UpdateResult, progressCallback func(UpdateResult)) []UpdateResult {
+	u.batch = make(map[string]bool, len(repositories))
+	for _, repo := range repositories {
+		u.batch[repo.Path] = true
+	}
 	
-	// 创建任务通道和结果通道
-	jobs := make(chan scanner.Repository, len(repositories))
+	// 按远程主机调度任务，总并发仍由工作协程数决定
+	scheduler := newHostScheduler(u.ctx, u.hostJobs(repositories), u.config.HostLimits)
+	defer scheduler.stop()
 	results := make(chan UpdateResult, len(repositories))
 	
 	// 启动工作协程
 	var wg sync.WaitGroup
 	for i := 0; i < u.config.WorkerCount; i++ {
 		wg.Add(1)
-		go u.worker(i, jobs, results, &wg)
+		go u.worker(i, operation, scheduler, results, &wg)
 	}
 	
-	// 发送任务
-	go func() {
-		defer close(jobs)
-		for _, repo := range repositories {
-			select {
-			case jobs <- repo:
-			case <-u.ctx.Done():
-				return
-			}
-		}
-	}()
-	
 	// 收集结果
 	var updateResults []UpdateResult
 	go func() {
@@ -107,23 +159,22 @@ func (u *Updater) UpdateRepositories(repositories []scanner.Repository, progress
 		}
 	}
 	
-	u.logger.Infof("更新完成，共处理 %d 个仓库", len(updateResults))
-	return updateResults, nil
+	return updateResults
 }
 
-// worker is a worker goroutine that processes repository updates
-func (u *Updater) worker(id int, jobs <-chan scanner.Repository, results chan<- UpdateResult, wg *sync.WaitGroup) {
+// worker is a worker goroutine that applies operation to the repositories the scheduler hands out
+func (u *Updater) worker(id int, operation func(scanner.Repository) UpdateResult, scheduler *hostScheduler, results chan<- UpdateResult, wg *sync.WaitGroup) {
 	defer wg.Done()
 	
-	for repo := range jobs {
-		select {
-		case <-u.ctx.Done():
+	for {
+		job, ok := scheduler.next()
+		if !ok {
 			return
-		default:
-			result := u.updateRepository(repo)
-			u.logger.Debugf("工作协程 %d 完成仓库 %s: %s", id, repo.Name, result.Message)
-			results <- result
 		}
+		result := operation(job.repo)
+		scheduler.done(job)
+		u.logger.Debugf("工作协程 %d 完成仓库 %s: %s", id, job.repo.Name, result.Message)
+		results <- result
 	}
 }
 
@@ -136,73 +187,53 @@ func (u *Updater) updateRepository(repo scanner.Repository) UpdateResult {
 		StartTime:  startTime,
 	}
 	
-	if u.config.DryRun {
+	options := u.repoOptions[repo.Path]
+	timeout := u.repoTimeout(repo)
+	
+	if options.SkipUpdate {
+		result.skip("已跳过: 清单中设置了 skip_update")
+	} else if reason := u.unsafeStateReason(repo); reason != "" {
+		result.skip("已跳过: " + reason)
+	} else if u.config.DryRun {
 		result.Success = true
 		result.Message = "DRY RUN: 模拟更新成功"
 	} else {
-		// 创建带超时的上下文
-		ctx, cancel := context.WithTimeout(u.ctx, u.config.Timeout)
-		defer cancel()
+		// 共享对象库的仓库依次执行，等待时间不计入超时
+		unlock := u.lockObjectStore(repo)
+		defer unlock()
 		
-		// 构建git pull命令参数
-		args := []string{"pull"}
+		journaled := u.journalBefore(repo)
 		
-		// 根据策略添加参数
-		switch u.config.GitPullStrategy {
-		case "rebase":
-			args = append(args, "--rebase", "--no-edit")
-		case "merge":
-			args = append(args, "--no-edit")
-		default: // "ff-only" 或未设置
-			args = append(args, "--no-edit", "--ff-only")
-		}
-		
-		// 执行 git pull
-		cmd := exec.CommandContext(ctx, "git", args...)
-		cmd.Dir = repo.Path
+		// 每次尝试使用新的超时，只有网络错误和超时会重试
+		result.Attempts = u.withRetry(repo.Name, timeout, func(ctx context.Context) bool {
+			result = UpdateResult{Repository: repo, StartTime: startTime}
+			switch repo.Kind {
+			case scanner.KindBare:
+				u.fetchBareRepository(ctx, repo, &result)
+			case scanner.KindSubmodule:
+				u.updateSubmodule(ctx, repo, &result)
+			default:
+				u.pullDirtyRepository(ctx, repo, &result)
+			}
+			return !result.Success && result.ErrorCode.Transient()
+		})
 		
-		// 如果启用非交互模式，设置环境变量防止交互提示
-		if u.config.GitNonInteractive {
-			cmd.Env = append(os.Environ(),
-				"GIT_TERMINAL_PROMPT=0",                           // 禁用终端提示
-				"GIT_ASKPASS=echo",                               // 禁用密码提示
-				"SSH_ASKPASS=echo",                               // 禁用SSH密码提示
-				"GIT_SSH_COMMAND=ssh -o BatchMode=yes -o ConnectTimeout=10 -o StrictHostKeyChecking=no", // 非交互SSH
-			)
+		// 网络不可用时其他分支同样无法获取
+		if u.config.AllBranches && u.ownsBranches(repo) && !result.ErrorCode.Transient() {
+			u.updateOtherBranches(repo, &result, journaled)
 		}
 		
-		output, err := cmd.CombinedOutput()
+		if journaled {
+			result.finishStatus()
+			u.journalResult(repo, result)
+		}
 		
-		if err != nil {
-			result.Success = false
-			result.Error = err.Error()
-			
-			// 提供更友好的错误消息
-			errorMsg := string(output)
-			if strings.Contains(errorMsg, "Permission denied") || strings.Contains(errorMsg, "could not read from remote repository") {
-				result.Message = "更新失败: SSH认证失败或无权限访问远程仓库"
-			} else if strings.Contains(errorMsg, "refusing to merge unrelated histories") {
-				result.Message = "更新失败: 拒绝合并不相关的历史记录"
-			} else if strings.Contains(errorMsg, "non-fast-forward") {
-				result.Message = "更新失败: 非快进更新，本地有未推送的提交"
-			} else if strings.Contains(errorMsg, "Authentication failed") {
-				result.Message = "更新失败: 认证失败，请检查访问凭据"
-			} else if strings.Contains(errorMsg, "There is no tracking information") {
-				result.Message = "更新失败: 当前分支没有设置远程跟踪分支"
-			} else if strings.Contains(errorMsg, "timeout") || strings.Contains(errorMsg, "Timeout") {
-				result.Message = "更新失败: 连接超时，请检查网络或远程仓库状态"
-			} else {
-				// 截断长错误消息
-				if len(errorMsg) > 100 {
-					errorMsg = errorMsg[:97] + "..."
-				}
-				result.Message = fmt.Sprintf("更新失败: %s", errorMsg)
-			}
-		} else {
-			result.Success = true
-			result.Message = u.parseGitPullOutput(string(output))
+		// 钩子失败不影响更新结果，只记录在结果中
+		if result.Success && len(u.hooks) > 0 {
+			u.runHooks(repo, &result)
 		}
 	}
+	result.finishStatus()
 	
 	// 计算时间（在函数结束前）
 	result.EndTime = time.Now()
@@ -211,6 +242,166 @@ func (u *Updater) updateRepository(repo scanner.Repository) UpdateResult {
 	return result
 }
 
+// unsafeStateReason explains why a repository cannot be pulled in its current
+// state, such as an unfinished rebase or a detached HEAD, or returns ""
+func (u *Updater) unsafeStateReason(repo scanner.Repository) string {
+	// 裸仓库只执行 fetch，子模块本来就处于分离 HEAD，由父仓库更新
+	if repo.Kind == scanner.KindBare || repo.Kind == scanner.KindSubmodule {
+		return ""
+	}
+	
+	head, operation, err := repo.State()
+	if err != nil {
+		// 交给 git pull 报告具体错误
+		u.logger.Debugf("读取仓库状态失败 %s: %v", repo.Path, err)
+		return ""
+	}
+	
+	if operation != git.OpNone {
+		return fmt.Sprintf("仓库中有未完成的%s，请先完成或中止", operation.Description())
+	}
+	if head.State == git.HeadDetached {
+		return fmt.Sprintf("HEAD 处于分离状态 (%s)，没有可拉取的分支", head.ShortCommit())
+	}
+	return ""
+}
+
+// pullRepository runs git pull in a repository with a working tree
+func (u *Updater) pullRepository(ctx context.Context, repo scanner.Repository, result *UpdateResult) {
+	// 构建git pull命令参数
+	args := []string{"pull"}
+	
+	// 根据策略添加参数，清单中的设置优先
+	strategy := u.config.GitPullStrategy
+	if options := u.repoOptions[repo.Path]; options.PullStrategy != "" {
+		strategy = options.PullStrategy
+	}
+	switch strategy {
+	case "rebase":
+		args = append(args, "--rebase", "--no-edit")
+	case "merge":
+		// 显式指定合并，否则未配置 pull.rebase 时 git 会拒绝拉取分叉的分支
+		args = append(args, "--no-rebase", "--no-edit")
+	default: // "ff-only" 或未设置
+		args = append(args, "--no-edit", "--ff-only")
+	}
+	
+	before := u.snapshotBeforePull(ctx, repo.Path)
+	
+	// 执行 git pull
+	res, err := u.runner.Run(ctx, repo.Path, args...)
+	output := res.Output()
+	
+	if err != nil {
+		u.failGit(result, err, output)
+		return
+	}
+	
+	result.Success = true
+	result.Message = u.parseGitPullOutput(output)
+	if result.Changes = u.pullChanges(ctx, repo.Path, before); result.Changes != nil {
+		result.Message += ": " + result.Changes.summary()
+	}
+	
+	// 子模块通过父仓库更新
+	if scanner.HasSubmodules(repo.Path) {
+		if _, err := u.runner.Run(ctx, repo.Path, "submodule", "update", "--init", "--recursive"); err != nil {
+			result.Message += "，子模块更新失败"
+			result.Error = err.Error()
+		} else {
+			result.Message += "，子模块已同步"
+		}
+	}
+}
+
+// failGit records a failed git command on result together with its error class
+func (u *Updater) failGit(result *UpdateResult, err error, output string) {
+	result.Success = false
+	result.Error = err.Error()
+	result.ErrorCode = git.ClassifyError(err, output)
+	result.Message = describeGitError(result.ErrorCode, output)
+}
+
+// describeGitError provides a friendlier message for a failed git command
+func describeGitError(class git.ErrorClass, errorMsg string) string {
+	switch class {
+	case git.ErrorAuth:
+		return "更新失败: 认证失败或无权限访问远程仓库，请检查访问凭据"
+	case git.ErrorHostKey:
+		return "更新失败: SSH主机密钥验证失败"
+	case git.ErrorNetwork:
+		return "更新失败: 网络错误，无法连接远程仓库"
+	case git.ErrorTimeout:
+		return "更新失败: 连接超时，请检查网络或远程仓库状态"
+	case git.ErrorNonFastForward:
+		if strings.Contains(errorMsg, "refusing to merge unrelated histories") {
+			return "更新失败: 拒绝合并不相关的历史记录"
+		}
+		return "更新失败: 非快进更新，本地有未推送的提交"
+	case git.ErrorNoUpstream:
+		return "更新失败: 当前分支没有设置远程跟踪分支"
+	case git.ErrorConflict:
+		return "更新失败: 与本地变更冲突"
+	}
+	
+	// 截断长错误消息
+	if len(errorMsg) > 100 {
+		errorMsg = errorMsg[:97] + "..."
+	}
+	return fmt.Sprintf("更新失败: %s", errorMsg)
+}
+
+// fetchBareRepository refreshes a bare repository, which has no working tree to pull into
+func (u *Updater) fetchBareRepository(ctx context.Context, repo scanner.Repository, result *UpdateResult) {
+	res, err := u.runner.Run(ctx, repo.Path, "fetch", "--all", "--prune")
+	if err != nil {
+		u.failGit(result, err, res.Output())
+		return
+	}
+	
+	result.Success = true
+	result.Message = "裸仓库: 已获取远程更新"
+}
+
+// updateSubmodule updates a submodule through its parent repository
+func (u *Updater) updateSubmodule(ctx context.Context, repo scanner.Repository, result *UpdateResult) {
+	if repo.Parent == "" {
+		u.pullDirtyRepository(ctx, repo, result)
+		return
+	}
+	
+	// 父仓库在本批次中时，由父仓库统一更新子模块
+	if u.batch[repo.Parent] {
+		result.skip("跳过: 子模块随父仓库更新")
+		return
+	}
+	
+	rel, err := filepath.Rel(repo.Parent, repo.Path)
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+		result.Message = "更新失败: 无法确定子模块路径"
+		return
+	}
+	
+	res, err := u.runner.Run(ctx, repo.Parent, "submodule", "update", "--init", "--recursive", "--", filepath.ToSlash(rel))
+	if err != nil {
+		u.failGit(result, err, res.Output())
+		return
+	}
+	
+	result.Success = true
+	result.Message = "子模块已同步到父仓库记录的提交"
+}
+
+// lockObjectStore serializes git operations on repositories sharing an object store
+func (u *Updater) lockObjectStore(repo scanner.Repository) func() {
+	value, _ := u.storeLocks.LoadOrStore(repo.ObjectStore(), &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
 // parseGitPullOutput parses git pull output to provide meaningful messages
 func (u *Updater) parseGitPullOutput(output string) string {
 	if output == "" {
@@ -223,10 +414,14 @@ func (u *Updater) parseGitPullOutput(output string) string {
 		return "已是最新版本"
 	case contains(output, "Already up-to-date"):
 		return "已是最新版本"
+	case contains(output, "is up to date"):
+		return "已是最新版本"
 	case contains(output, "Fast-forward"):
 		return "快进更新成功"
 	case contains(output, "Merge made by"):
 		return "合并更新成功"
+	case contains(output, "Successfully rebased"):
+		return "变基更新成功"
 	case contains(output, "files changed"):
 		return "更新成功"
 	default:
@@ -266,14 +461,18 @@ func (u *Updater) Stop() {
 func (u *Updater) GetStatistics(results []UpdateResult) map[string]interface{} {
 	total := len(results)
 	successful := 0
+	skipped := 0
 	failed := 0
 	var totalDuration time.Duration
 	
 	for _, result := range results {
-		if result.Success {
-			successful++
-		} else {
+		switch result.Status {
+		case StatusSkipped:
+			skipped++
+		case StatusFailed:
 			failed++
+		default:
+			successful++
 		}
 		totalDuration += result.Duration
 	}
@@ -286,6 +485,7 @@ func (u *Updater) GetStatistics(results []UpdateResult) map[string]interface{} {
 	return map[string]interface{}{
 		"total":              total,
 		"successful":         successful,
+		"skipped":            skipped,
 		"failed":             failed,
 		"success_rate":       float64(successful) / float64(total) * 100,
 		"total_duration":     totalDuration,
@@ -0,0 +1,235 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"reposense/pkg/git"
+	"reposense/pkg/scanner"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	oldHead = strings.Repeat("a", 40)
+	newHead = strings.Repeat("b", 40)
+)
+
+// fakeRepo is a repository on branch main whose HEAD lives on disk, as
+// repo.State() reads it, while git commands are answered by a FakeRunner
+type fakeRepo struct {
+	t      *testing.T
+	repo   scanner.Repository
+	runner *git.FakeRunner
+
+	mu     sync.Mutex
+	pulls  []git.FakeResponse // 依次返回给每次 pull，最后一个重复使用
+	pulled int
+	dirty  bool             // status 报告已修改的文件
+	pop    git.FakeResponse // stash pop 的输出
+}
+
+// newFakeRepo creates a repository whose main branch points at oldHead. A
+// successful pull moves it to newHead.
+func newFakeRepo(t *testing.T, name string) *fakeRepo {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), name)
+	if err := os.MkdirAll(filepath.Join(dir, ".git", "refs", "heads"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeRepo{
+		t:      t,
+		repo:   scanner.Repository{Path: dir, Name: name, IsGitRepo: true, Kind: scanner.KindNormal},
+		runner: git.NewFakeRunner(),
+		pulls:  []git.FakeResponse{{Stdout: "Fast-forward\n"}},
+	}
+	f.setHead(oldHead)
+	f.runner.Handler = f.handle
+	return f
+}
+
+// updater builds an updater with a single worker that runs git through the fake
+func (f *fakeRepo) updater(config UpdaterConfig) *Updater {
+	config.WorkerCount = 1
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	u := NewUpdaterWithRunner(config, f.runner)
+	u.SetLogLevel(logrus.ErrorLevel)
+	return u
+}
+
+// head reads the commit main points at
+func (f *fakeRepo) head() string {
+	data, err := os.ReadFile(filepath.Join(f.repo.Path, ".git", "refs", "heads", "main"))
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// setHead moves main to commit
+func (f *fakeRepo) setHead(commit string) {
+	if err := os.WriteFile(filepath.Join(f.repo.Path, ".git", "refs", "heads", "main"), []byte(commit+"\n"), 0o644); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// calls returns the commands run so far, each joined into one string
+func (f *fakeRepo) calls() []string {
+	var calls []string
+	for _, call := range f.runner.Calls() {
+		calls = append(calls, strings.Join(call.Args, " "))
+	}
+	return calls
+}
+
+// count returns how many commands started with prefix
+func (f *fakeRepo) count(prefix string) int {
+	n := 0
+	for _, call := range f.calls() {
+		if strings.HasPrefix(call, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// handle answers the git commands used by update, undo and the branch operations
+func (f *fakeRepo) handle(dir string, args []string) (git.FakeResponse, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.Join(args, " ") {
+	case "rev-parse HEAD":
+		return git.FakeResponse{Stdout: f.head() + "\n"}, true
+	case "rev-parse --absolute-git-dir":
+		return git.FakeResponse{Stdout: filepath.Join(dir, ".git") + "\n"}, true
+	case "branch --show-current":
+		return git.FakeResponse{Stdout: "main\n"}, true
+	case "status --porcelain=v2 --branch --show-stash -z":
+		out := "# branch.oid " + f.head() + "\x00# branch.head main\x00"
+		if f.dirty {
+			out += "1 .M N... 100644 100644 100644 aaa aaa file.txt\x00"
+		}
+		return git.FakeResponse{Stdout: out}, true
+	case "stash push -m " + autoStashMessage:
+		return git.FakeResponse{Stdout: "Saved working directory and index state On main: " + autoStashMessage + "\n"}, true
+	case "stash pop":
+		return f.pop, true
+	}
+
+	switch args[0] {
+	case "pull":
+		response := f.pulls[min(f.pulled, len(f.pulls)-1)]
+		f.pulled++
+		if response.ExitCode == 0 && response.Err == nil {
+			f.setHead(newHead)
+		}
+		return response, true
+	case "for-each-ref":
+		return git.FakeResponse{}, true
+	case "rev-list":
+		return git.FakeResponse{Stdout: "1\n"}, true
+	case "log":
+		return git.FakeResponse{Stdout: newHead + "\x00t\x00incoming\n"}, true
+	case "diff":
+		return git.FakeResponse{Stdout: "1\t0\tfile.txt\x00"}, true
+	case "reset":
+		// reset --keep <commit>
+		f.setHead(args[len(args)-1])
+		return git.FakeResponse{}, true
+	}
+	return git.FakeResponse{}, false
+}
+
+func TestUpdatePullStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		args     string
+	}{
+		{"", "pull --no-edit --ff-only"},
+		{"ff-only", "pull --no-edit --ff-only"},
+		{"rebase", "pull --rebase --no-edit"},
+		{"merge", "pull --no-rebase --no-edit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			f := newFakeRepo(t, "r")
+			u := f.updater(UpdaterConfig{GitPullStrategy: tt.strategy})
+
+			results, err := u.UpdateRepositories([]scanner.Repository{f.repo}, nil)
+			if err != nil {
+				t.Fatalf("UpdateRepositories() error: %v", err)
+			}
+			result := results[0]
+			if result.Status != StatusSuccess {
+				t.Fatalf("Status = %q (%s), want %q", result.Status, result.Message, StatusSuccess)
+			}
+			if f.count(tt.args) != 1 {
+				t.Errorf("calls = %q, want one %q", f.calls(), tt.args)
+			}
+			if result.Changes == nil {
+				t.Fatal("Changes = nil, want the pulled commit")
+			}
+			if result.Changes.OldHead != oldHead || result.Changes.NewHead != newHead || result.Changes.CommitCount != 1 {
+				t.Errorf("Changes = %s..%s (%d commits), want %s..%s (1 commit)",
+					result.Changes.OldHead, result.Changes.NewHead, result.Changes.CommitCount, oldHead, newHead)
+			}
+		})
+	}
+}
+
+func TestUpdatePullFailure(t *testing.T) {
+	tests := []struct {
+		stderr string
+		code   git.ErrorClass
+	}{
+		{"fatal: Not possible to fast-forward, aborting.", git.ErrorNonFastForward},
+		{"There is no tracking information for the current branch.", git.ErrorNoUpstream},
+		{"fatal: Authentication failed for 'https://example.com/r.git/'", git.ErrorAuth},
+		{"error: Your local changes to the following files would be overwritten by merge", git.ErrorConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.code), func(t *testing.T) {
+			f := newFakeRepo(t, "r")
+			f.pulls = []git.FakeResponse{{Stderr: tt.stderr, ExitCode: 1}}
+			u := f.updater(UpdaterConfig{})
+
+			results, _ := u.UpdateRepositories([]scanner.Repository{f.repo}, nil)
+			result := results[0]
+			if result.Status != StatusFailed || result.ErrorCode != tt.code {
+				t.Errorf("Status, ErrorCode = %q, %q, want %q, %q", result.Status, result.ErrorCode, StatusFailed, tt.code)
+			}
+			if f.head() != oldHead {
+				t.Errorf("HEAD = %s, want it unchanged", f.head())
+			}
+		})
+	}
+}
+
+func TestUpdateSkipsUnsafeState(t *testing.T) {
+	f := newFakeRepo(t, "r")
+	if err := os.WriteFile(filepath.Join(f.repo.Path, ".git", "MERGE_HEAD"), []byte(newHead+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	u := f.updater(UpdaterConfig{})
+
+	results, _ := u.UpdateRepositories([]scanner.Repository{f.repo}, nil)
+	if results[0].Status != StatusSkipped {
+		t.Errorf("Status = %q, want %q", results[0].Status, StatusSkipped)
+	}
+	if f.count("pull") != 0 {
+		t.Errorf("calls = %q, want no pull", f.calls())
+	}
+}